@@ -3,22 +3,27 @@ package behavioral
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"gorka/internal/embedded"
 	"gorka/internal/openrouter"
+	"gorka/internal/session"
 	"gorka/internal/tools"
 	"gorka/internal/types"
+	"gorka/internal/usage"
 	"gorka/internal/utils"
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/sashabaranov/go-openai"
 )
 
+// DefaultResumeMessage is sent to an interrupted session resumed without a message of its own
+const DefaultResumeMessage = "Your previous run was interrupted. Continue the task from where you left off, repeating any tool call whose result is missing."
+
 type Engine struct {
 	matrices         map[string]*types.BehavioralMatrix
 	qualityValidator *QualityValidator
@@ -95,8 +100,8 @@ func (e *Engine) logError(format string, args ...interface{}) {
 }
 
 // Configuration-driven context management
-func (e *Engine) createTimeoutContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), e.defaultTimeout)
+func (e *Engine) createTimeoutContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, e.defaultTimeout)
 }
 
 // Configuration-driven content truncation
@@ -146,6 +151,13 @@ func (e *Engine) LoadBehavioralMatrices() error {
 }
 
 func (e *Engine) ExecuteBehavioralMatrix(req *types.BehavioralRequest) (*types.BehavioralResult, error) {
+	return e.ExecuteBehavioralMatrixWithContext(context.Background(), req)
+}
+
+// ExecuteBehavioralMatrixWithContext executes a behavioral matrix, passing ctx through to the agent
+// so callers can attach a stream handler for progress reporting. Cancelling ctx stops the agent's
+// model calls and any tools it is running.
+func (e *Engine) ExecuteBehavioralMatrixWithContext(ctx context.Context, req *types.BehavioralRequest) (*types.BehavioralResult, error) {
 	matrix, exists := e.matrices[req.AgentID]
 	if !exists {
 		return nil, fmt.Errorf("behavioral matrix not found: %s", req.AgentID)
@@ -163,41 +175,131 @@ func (e *Engine) ExecuteBehavioralMatrix(req *types.BehavioralRequest) (*types.B
 		return nil, fmt.Errorf("input validation failed: %w", err)
 	}
 
+	if req.SessionID != "" {
+		if err := e.checkSessionAgent(req.SessionID, req.AgentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.runAgent(ctx, req, matrix, e.truncateContent(formatUserInputFromRequest(req)))
+}
+
+// ContinueSessionWithContext sends a follow-up message to an earlier agent session, active or
+// completed, and runs that agent again with its previous conversation still in context
+func (e *Engine) ContinueSessionWithContext(ctx context.Context, sessionID, message string) (*types.BehavioralResult, error) {
+	agentSession, exists := e.agentSpawner.GetSession(sessionID)
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	matrix, exists := e.matrices[agentSession.AgentID]
+	if !exists {
+		return nil, fmt.Errorf("behavioral matrix not found: %s", agentSession.AgentID)
+	}
+
+	req := &types.BehavioralRequest{
+		AgentID:          agentSession.AgentID,
+		InputParameters:  map[string]interface{}{},
+		ExecutionContext: map[string]interface{}{},
+		SessionID:        sessionID,
+	}
+	return e.runAgent(ctx, req, matrix, e.truncateContent(message))
+}
+
+// ResumeSessionWithContext resumes an interrupted agent session from its last complete tool
+// exchange, dropping any tool calls left without results, and runs the agent again with message
+func (e *Engine) ResumeSessionWithContext(ctx context.Context, sessionID, message string) (*types.BehavioralResult, error) {
+	agentSession, exists := e.agentSpawner.GetSession(sessionID)
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if status := agentSession.Status(); status != session.StatusInterrupted {
+		return nil, fmt.Errorf("session %s is %s, not interrupted", sessionID, status)
+	}
+	if message == "" {
+		message = DefaultResumeMessage
+	}
+	return e.ContinueSessionWithContext(ctx, sessionID, message)
+}
+
+// checkSessionAgent makes sure a session being continued belongs to the requested agent
+func (e *Engine) checkSessionAgent(sessionID, agentID string) error {
+	agentSession, exists := e.agentSpawner.GetSession(sessionID)
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	if agentSession.AgentID != agentID {
+		return fmt.Errorf("session %s belongs to agent %s, not %s", sessionID, agentSession.AgentID, agentID)
+	}
+	return nil
+}
+
+// runAgent executes an agent inside a usage run and reports the run's usage on the result
+func (e *Engine) runAgent(ctx context.Context, req *types.BehavioralRequest, matrix *types.BehavioralMatrix, userInput string) (*types.BehavioralResult, error) {
+	// Top-level calls open a usage run; sub-agents spawned during it inherit the run via ctx
+	run := usage.RunFromContext(ctx)
+	rootRun := run == nil
+	if rootRun {
+		run = e.agentSpawner.Ledger().NewRun(req.AgentID)
+		ctx = usage.WithRun(ctx, run)
+	}
+
 	// All agents are handled the same way - execute based on their behavioral spec
-	return e.executeAgent(req, matrix)
+	result, err := e.executeAgent(ctx, req, matrix, userInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if rootRun {
+		result.ExecutionMeta["usage"] = e.agentSpawner.Ledger().Summarize(run)
+	}
+	return result, nil
 }
 
 // executeAgent handles execution for any agent type based on its behavioral spec
-func (e *Engine) executeAgent(req *types.BehavioralRequest, matrix *types.BehavioralMatrix) (*types.BehavioralResult, error) {
+func (e *Engine) executeAgent(parent context.Context, req *types.BehavioralRequest, matrix *types.BehavioralMatrix, userInput string) (*types.BehavioralResult, error) {
 	// Create timeout context for agent execution
-	ctx, cancel := e.createTimeoutContext()
+	ctx, cancel := e.createTimeoutContext(parent)
 	defer cancel()
 
 	// Phase 1: Get execution plan from LLM with timeout
-	userInput := e.truncateContent(formatUserInputFromRequest(req))
-	
+
 	// Use a channel to handle timeout for LLM request
 	type llmResult struct {
-		response *openai.ChatCompletionResponse
-		err      error
+		run *openrouter.AgentRunResult
+		err error
 	}
 	
 	llmChan := make(chan llmResult, 1)
 	go func() {
-		response, err := e.agentSpawner.SpawnAgent(matrix, userInput)
-		llmChan <- llmResult{response: response, err: err}
+		var run *openrouter.AgentRunResult
+		var err error
+		if req.SessionID != "" {
+			run, err = e.agentSpawner.ContinueAgentWithContext(ctx, req.SessionID, userInput)
+		} else {
+			run, err = e.agentSpawner.SpawnAgentWithContext(ctx, matrix, userInput)
+		}
+		llmChan <- llmResult{run: run, err: err}
 	}()
 	
-	var llmResponse *openai.ChatCompletionResponse
+	var agentRun *openrouter.AgentRunResult
 	select {
 	case result := <-llmChan:
+		var budgetErr *usage.BudgetError
+		if errors.As(result.err, &budgetErr) {
+			e.logWarn("Agent %s stopped early: %v", req.AgentID, budgetErr)
+			return e.budgetExceededResult(req, result.run, budgetErr), nil
+		}
 		if result.err != nil {
 			return nil, fmt.Errorf("OpenRouter agent execution failed: %w", result.err)
 		}
-		llmResponse = result.response
+		agentRun = result.run
 	case <-ctx.Done():
+		if parent.Err() != nil {
+			return nil, fmt.Errorf("agent execution cancelled: %w", parent.Err())
+		}
 		return nil, fmt.Errorf("agent execution timed out after %v", e.defaultTimeout)
 	}
+	llmResponse := agentRun.Response
 
 	if len(llmResponse.Choices) == 0 {
 		return nil, fmt.Errorf("OpenRouter returned response with no choices - ID: %s, Model: %s, Usage: %+v", 
@@ -211,7 +313,7 @@ func (e *Engine) executeAgent(req *types.BehavioralRequest, matrix *types.Behavi
 		llmResponse.ID, llmResponse.Model, len(llmResponse.Choices), len(llmContent), llmResponse.Usage)
 
 	// Phase 2: Execute actual work based on agent type  
-	workResults, err := e.executeAgentWork(req.AgentID, llmResponse, req.InputParameters)
+	workResults, err := e.executeAgentWork(ctx, req.AgentID, agentRun, req.InputParameters)
 	if err != nil {
 		return nil, fmt.Errorf("agent work execution failed: %w", err)
 	}
@@ -220,7 +322,7 @@ func (e *Engine) executeAgent(req *types.BehavioralRequest, matrix *types.Behavi
 	e.logDebug("Checking if coordination needed - AgentID: '%s', Is project_orchestrator: %v", req.AgentID, req.AgentID == "project_orchestrator")
 	if req.AgentID == "project_orchestrator" {
 		e.logInfo("Project orchestrator detected - executing multi-agent coordination")
-		coordinationResults, err := e.executeProjectOrchestration(req, workResults, llmContent)
+		coordinationResults, err := e.executeProjectOrchestration(parent, req, workResults, llmContent)
 		if err != nil {
 			e.logWarn("Project orchestration failed: %v", err)
 			// Continue with original results if coordination fails
@@ -235,12 +337,13 @@ func (e *Engine) executeAgent(req *types.BehavioralRequest, matrix *types.Behavi
 	result := &types.BehavioralResult{
 		AgentID: req.AgentID,
 		OutputData: map[string]interface{}{
-			"llm_plan":          e.truncateContent(llmContent),
 			"work_results":      workResults,
 			"model_used":        llmResponse.Model,
-			"tokens_used":       llmResponse.Usage.TotalTokens,
-			"completion_tokens": llmResponse.Usage.CompletionTokens,
-			"prompt_tokens":     llmResponse.Usage.PromptTokens,
+			"tokens_used":       agentRun.Usage.Tokens.Total,
+			"completion_tokens": agentRun.Usage.Tokens.Completion,
+			"prompt_tokens":     agentRun.Usage.Tokens.Prompt,
+			"reasoning_tokens":  agentRun.Usage.Tokens.Reasoning,
+			"cost_usd":          agentRun.Usage.CostUSD,
 		},
 		ExecutionMeta: map[string]interface{}{
 			"execution_mode":    "hybrid_llm_plus_tools",
@@ -250,7 +353,29 @@ func (e *Engine) executeAgent(req *types.BehavioralRequest, matrix *types.Behavi
 			"work_executed":    len(workResults) > 0,
 			"timeout_used":     e.defaultTimeout.String(),
 			"max_context_size": e.config.MaxContextSize,
+			"model_attempts":   agentRun.ModelAttempts,
+			"fallback_used":    agentRun.FallbackUsed(),
+			"agent_usage":      agentRun.Usage,
+			"session_id":       agentRun.SessionID,
+			"stop_reason":      agentRun.StopReason,
 		},
+		ToolCalls: agentRun.ToolCalls,
+	}
+
+	// Typed output validated against the matrix output schema replaces the raw plan text
+	if agentRun.StructuredOutput != nil {
+		for field, value := range agentRun.StructuredOutput {
+			if _, reserved := result.OutputData[field]; !reserved {
+				result.OutputData[field] = value
+			}
+		}
+		result.ExecutionMeta["structured_output"] = true
+	} else {
+		result.OutputData["llm_plan"] = e.truncateContent(llmContent)
+		result.ExecutionMeta["structured_output"] = false
+		if agentRun.OutputError != "" {
+			result.ExecutionMeta["output_error"] = agentRun.OutputError
+		}
 	}
 
 	// Add execution summary to output if tools were executed
@@ -311,42 +436,67 @@ func (e *Engine) executeAgent(req *types.BehavioralRequest, matrix *types.Behavi
 	return result, nil
 }
 
-// executeAgentWork performs actual work based on the OpenAI response with tool calls
-func (e *Engine) executeAgentWork(agentID string, openaiResponse *openai.ChatCompletionResponse, inputParams map[string]interface{}) (map[string]interface{}, error) {
+// budgetExceededResult reports whatever the agent produced before a budget cap stopped it
+func (e *Engine) budgetExceededResult(req *types.BehavioralRequest, agentRun *openrouter.AgentRunResult, budgetErr *usage.BudgetError) *types.BehavioralResult {
+	result := &types.BehavioralResult{
+		AgentID: req.AgentID,
+		OutputData: map[string]interface{}{
+			"status": "budget_exceeded",
+			"error":  budgetErr.Error(),
+		},
+		ExecutionMeta: map[string]interface{}{
+			"execution_mode":  "budget_exceeded",
+			"agent_id":        req.AgentID,
+			"budget_exceeded": true,
+			"budget_scope":    budgetErr.Scope,
+			"budget_limit":    budgetErr.Limit,
+			"budget_spent":    budgetErr.Spent,
+		},
+	}
+
+	if agentRun == nil {
+		return result
+	}
+
+	result.OutputData["tokens_used"] = agentRun.Usage.Tokens.Total
+	result.OutputData["completion_tokens"] = agentRun.Usage.Tokens.Completion
+	result.OutputData["prompt_tokens"] = agentRun.Usage.Tokens.Prompt
+	result.OutputData["reasoning_tokens"] = agentRun.Usage.Tokens.Reasoning
+	result.OutputData["cost_usd"] = agentRun.Usage.CostUSD
+	result.ExecutionMeta["model_attempts"] = agentRun.ModelAttempts
+	result.ExecutionMeta["agent_usage"] = agentRun.Usage
+	result.ExecutionMeta["session_id"] = agentRun.SessionID
+	result.ExecutionMeta["stop_reason"] = agentRun.StopReason
+	result.ToolCalls = agentRun.ToolCalls
+
+	if agentRun.Response != nil && len(agentRun.Response.Choices) > 0 {
+		result.OutputData["model_used"] = agentRun.Response.Model
+		result.OutputData["partial_response"] = e.truncateContent(agentRun.Response.Choices[0].Message.Content)
+	}
+
+	return result
+}
+
+// executeAgentWork performs actual work based on the agent run and the tool calls it made
+func (e *Engine) executeAgentWork(ctx context.Context, agentID string, agentRun *openrouter.AgentRunResult, inputParams map[string]interface{}) (map[string]interface{}, error) {
+	openaiResponse := agentRun.Response
 	if len(openaiResponse.Choices) == 0 {
 		return nil, fmt.Errorf("no response choices available for agent %s", agentID)
 	}
 
 	choice := openaiResponse.Choices[0]
 	
-	e.logDebug("executeAgentWork - Agent: %s, Content length: %d, Tool calls: %d", 
-		agentID, len(choice.Message.Content), len(choice.Message.ToolCalls))
+	e.logDebug("executeAgentWork - Agent: %s, Content length: %d, Tool calls: %d, Tools executed: %d", 
+		agentID, len(choice.Message.Content), len(choice.Message.ToolCalls), len(agentRun.ToolCalls))
 	
-	// Check if there's tool execution metadata in the response (from OpenRouter client tool execution)
-	toolExecutionMeta := e.extractToolExecutionMetadata(choice.Message.Content)
-	if toolExecutionMeta != nil && toolExecutionMeta.ToolsExecuted > 0 {
-		e.logDebug("Found tool execution metadata - %d tools executed in %s mode", 
-			toolExecutionMeta.ToolsExecuted, toolExecutionMeta.ExecutionMode)
-		
-		// Clean the content by removing metadata
-		cleanContent := e.cleanContentFromMetadata(choice.Message.Content)
-		
-		return map[string]interface{}{
-			"tool_results":         toolExecutionMeta,
-			"actions_taken":        toolExecutionMeta.ExecutionResults,
-			"response_content":     e.truncateContent(cleanContent),
-			"execution_mode":       toolExecutionMeta.ExecutionMode,
-			"tools_executed":       toolExecutionMeta.ToolsExecuted,
-			"tool_types":          toolExecutionMeta.ToolTypes,
-			"errors_encountered":   toolExecutionMeta.ErrorsEncountered,
-			"summary":             fmt.Sprintf("Successfully executed %d tools in %s mode: %v", 
-				toolExecutionMeta.ToolsExecuted, toolExecutionMeta.ExecutionMode, toolExecutionMeta.ToolTypes),
-		}, nil
+	// Tools the agent already ran during its conversation
+	if len(agentRun.ToolCalls) > 0 {
+		return e.summarizeToolCalls(agentRun.ToolCalls, choice.Message.Content), nil
 	}
 	
 	// Try OpenAI SDK tool calling
 	if len(choice.Message.ToolCalls) > 0 {
-		return e.executeOpenAIToolCalls(choice.Message.ToolCalls, choice.Message.Content)
+		return e.executeOpenAIToolCalls(ctx, choice.Message.ToolCalls, choice.Message.Content)
 	}
 
 	// No tool calls found and no metadata - check if there's substantial content
@@ -375,13 +525,48 @@ func (e *Engine) executeAgentWork(agentID string, openaiResponse *openai.ChatCom
 	return nil, fmt.Errorf("agent %s provided insufficient response (no tool calls and minimal content: %d chars)", agentID, contentLength)
 }
 
+// summarizeToolCalls builds the work results from the tool calls an agent already ran
+func (e *Engine) summarizeToolCalls(records []types.ToolCallRecord, responseContent string) map[string]interface{} {
+	actionsTaken := make([]string, 0, len(records))
+	toolTypes := make([]string, 0)
+	errorsEncountered := make([]string, 0)
+	seen := make(map[string]bool)
+	executed := 0
+
+	for _, record := range records {
+		if !seen[record.Name] {
+			seen[record.Name] = true
+			toolTypes = append(toolTypes, record.Name)
+		}
+		if record.Error != "" {
+			errorsEncountered = append(errorsEncountered, fmt.Sprintf("Tool %s: %s", record.Name, record.Error))
+			continue
+		}
+		executed++
+		actionsTaken = append(actionsTaken, fmt.Sprintf("%s executed successfully", record.Name))
+	}
+
+	mode := records[0].Mode
+	e.logDebug("Agent ran %d tool calls in %s mode (%d failed)", len(records), mode, len(errorsEncountered))
+
+	return map[string]interface{}{
+		"actions_taken":      actionsTaken,
+		"response_content":   e.truncateContent(responseContent),
+		"execution_mode":     mode,
+		"tools_executed":     executed,
+		"tool_types":         toolTypes,
+		"errors_encountered": errorsEncountered,
+		"summary":            fmt.Sprintf("Successfully executed %d tools in %s mode: %v", executed, mode, toolTypes),
+	}
+}
+
 // executeOpenAIToolCalls executes OpenAI SDK tool calls and returns results
-func (e *Engine) executeOpenAIToolCalls(toolCalls []openai.ToolCall, responseContent string) (map[string]interface{}, error) {
+func (e *Engine) executeOpenAIToolCalls(ctx context.Context, toolCalls []openai.ToolCall, responseContent string) (map[string]interface{}, error) {
 	toolResults := make(map[string]interface{})
 	actionsSummary := make([]string, 0)
 	
 	for i, toolCall := range toolCalls {
-		result, err := e.executeOpenAIToolCall(toolCall)
+		result, err := e.executeOpenAIToolCall(ctx, toolCall)
 		if err != nil {
 			return nil, fmt.Errorf("tool execution failed for %s (call %d): %w", toolCall.Function.Name, i, err)
 		}
@@ -401,51 +586,14 @@ func (e *Engine) executeOpenAIToolCalls(toolCalls []openai.ToolCall, responseCon
 }
 
 // executeOpenAIToolCall executes a single OpenAI tool call using the ToolsManager
-type ToolExecutionMetadata struct {
-	ToolsExecuted     int      `json:"tools_executed"`
-	ToolTypes         []string `json:"tool_types"`
-	ExecutionResults  []string `json:"execution_results"`
-	ExecutionMode     string   `json:"execution_mode"`
-	ToolCallsDetected int      `json:"tool_calls_detected"`
-	XMLToolsUsed      int      `json:"xml_tools_used"`
-	ErrorsEncountered []string `json:"errors_encountered"`
-}
-
-// extractToolExecutionMetadata extracts tool execution metadata from response content
-func (e *Engine) extractToolExecutionMetadata(content string) *ToolExecutionMetadata {
-	// Look for the metadata comment in the content
-	metadataPattern := regexp.MustCompile(`<!-- TOOL_EXECUTION_METADATA: (.*?) -->`)
-	matches := metadataPattern.FindStringSubmatch(content)
-	
-	if len(matches) < 2 {
-		return nil
-	}
-	
-	var metadata ToolExecutionMetadata
-	if err := json.Unmarshal([]byte(matches[1]), &metadata); err != nil {
-		e.logDebug("Failed to parse tool execution metadata: %v", err)
-		return nil
-	}
-	
-	return &metadata
-}
-
-// cleanContentFromMetadata removes tool execution metadata from content
-func (e *Engine) cleanContentFromMetadata(content string) string {
-	// Remove the metadata comment
-	metadataPattern := regexp.MustCompile(`\n\n<!-- TOOL_EXECUTION_METADATA: .*? -->`)
-	return metadataPattern.ReplaceAllString(content, "")
-}
-
-// executeOpenAIToolCall executes a single OpenAI tool call using the ToolsManager
-func (e *Engine) executeOpenAIToolCall(toolCall openai.ToolCall) (string, error) {
+func (e *Engine) executeOpenAIToolCall(ctx context.Context, toolCall openai.ToolCall) (string, error) {
 	var params map[string]interface{}
 	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
 		return "", fmt.Errorf("failed to parse tool arguments: %w", err)
 	}
 
 	// Use the centralized OpenAI tool execution system
-	return e.toolsManager.ExecuteOpenAITool(toolCall.Function.Name, params)
+	return e.toolsManager.ExecuteOpenAITool(ctx, toolCall.Function.Name, params)
 }
 
 
@@ -498,6 +646,26 @@ func (e *Engine) GetToolsManager() *tools.ToolsManager {
 	return e.toolsManager
 }
 
+// StartSessionSweeper applies the session retention policy in the background until ctx is done
+func (e *Engine) StartSessionSweeper(ctx context.Context) {
+	e.agentSpawner.StartSessionSweeper(ctx)
+}
+
+// CloseSessions flushes the session writes not yet synced to disk
+func (e *Engine) CloseSessions() error {
+	return e.agentSpawner.CloseSessions()
+}
+
+// RecoverSessions marks sessions left active by a server process that is gone as interrupted
+func (e *Engine) RecoverSessions() ([]*session.AgentSession, error) {
+	return e.agentSpawner.RecoverSessions()
+}
+
+// InterruptedSessions returns the agent sessions waiting to be resumed
+func (e *Engine) InterruptedSessions() []*session.AgentSession {
+	return e.agentSpawner.InterruptedSessions()
+}
+
 // validateInputParameters validates request parameters against behavioral matrix schema
 func (e *Engine) validateInputParameters(req *types.BehavioralRequest, matrix *types.BehavioralMatrix) error {
 	// Extract expected input schema using the centralized function from types package
@@ -576,7 +744,7 @@ func (e *Engine) validateFieldValue(fieldName string, value interface{}, schema
 }
 
 // executeProjectOrchestration handles multi-agent coordination for project orchestrator
-func (e *Engine) executeProjectOrchestration(req *types.BehavioralRequest, workResults map[string]interface{}, llmContent string) (map[string]interface{}, error) {
+func (e *Engine) executeProjectOrchestration(ctx context.Context, req *types.BehavioralRequest, workResults map[string]interface{}, llmContent string) (map[string]interface{}, error) {
 	e.logDebug("Starting project orchestration for task: %v", req.InputParameters)
 	
 	// Step 1: Parse thinking results to determine required agents
@@ -588,7 +756,7 @@ func (e *Engine) executeProjectOrchestration(req *types.BehavioralRequest, workR
 	e.logInfo("Determined required agents: %v", requiredAgents)
 	
 	// Step 2: Spawn required agents with parallel control and collect results
-	agentResults, err := e.spawnRequiredAgentsParallel(requiredAgents, req)
+	agentResults, err := e.spawnRequiredAgentsParallel(ctx, requiredAgents, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to spawn required agents: %w", err)
 	}
@@ -766,8 +934,9 @@ func (e *Engine) getToolExecutionPatternsFromSpecs() map[string]string {
 	return toolExecutionPatterns
 }
 
-// spawnRequiredAgentsParallel spawns the determined agents in parallel with configuration-driven control
-func (e *Engine) spawnRequiredAgentsParallel(requiredAgents []string, originalReq *types.BehavioralRequest) ([]map[string]interface{}, error) {
+// spawnRequiredAgentsParallel spawns the determined agents in parallel with configuration-driven control;
+// ctx carries the orchestration's usage run so sub-agent calls count against the same budget
+func (e *Engine) spawnRequiredAgentsParallel(ctx context.Context, requiredAgents []string, originalReq *types.BehavioralRequest) ([]map[string]interface{}, error) {
 	// Use a channel to collect results and wait group for synchronization
 	resultChan := make(chan map[string]interface{}, len(requiredAgents))
 	var wg sync.WaitGroup
@@ -793,7 +962,7 @@ func (e *Engine) spawnRequiredAgentsParallel(requiredAgents []string, originalRe
 			}
 			
 			// Execute the agent with timeout (sessions are managed internally)
-			result, err := e.ExecuteBehavioralMatrix(agentReq)
+			result, err := e.ExecuteBehavioralMatrixWithContext(ctx, agentReq)
 			if err != nil {
 				e.logWarn("Failed to execute agent %s: %v", agentID, err)
 				// Continue with other agents even if one fails
@@ -805,10 +974,15 @@ func (e *Engine) spawnRequiredAgentsParallel(requiredAgents []string, originalRe
 				return
 			}
 			
+			status := "success"
+			if exceeded, _ := result.ExecutionMeta["budget_exceeded"].(bool); exceeded {
+				status = "budget_exceeded"
+			}
+
 			// Add successful result
 			resultChan <- map[string]interface{}{
 				"agent_id":    agentID,
-				"status":      "success",
+				"status":      status,
 				"output_data": result.OutputData,
 				"metadata":    result.ExecutionMeta,
 			}
@@ -834,9 +1008,9 @@ func (e *Engine) spawnRequiredAgentsParallel(requiredAgents []string, originalRe
 }
 
 // spawnRequiredAgents spawns the determined agents sequentially (fallback method)
-func (e *Engine) spawnRequiredAgents(requiredAgents []string, originalReq *types.BehavioralRequest) ([]map[string]interface{}, error) {
+func (e *Engine) spawnRequiredAgents(ctx context.Context, requiredAgents []string, originalReq *types.BehavioralRequest) ([]map[string]interface{}, error) {
 	// For backward compatibility, delegate to parallel version with same semaphore control
-	return e.spawnRequiredAgentsParallel(requiredAgents, originalReq)
+	return e.spawnRequiredAgentsParallel(ctx, requiredAgents, originalReq)
 }
 
 // synthesizeAgentResults combines results from multiple agents
@@ -1151,7 +1325,7 @@ func (e *Engine) registerBehavioralTools() {
 }
 
 // executeSpawnBehavioralAgents executes the spawn behavioral agents tool
-func (e *Engine) executeSpawnBehavioralAgents(params map[string]interface{}) (string, error) {
+func (e *Engine) executeSpawnBehavioralAgents(ctx context.Context, params map[string]interface{}) (string, error) {
 	// Extract parameters
 	taskSpec, ok := params["task_specification"].(string)
 	if !ok {
@@ -1183,8 +1357,8 @@ func (e *Engine) executeSpawnBehavioralAgents(params map[string]interface{}) (st
 		},
 	}
 
-	// Execute the behavioral matrix
-	result, err := e.ExecuteBehavioralMatrix(request)
+	// Execute the behavioral matrix within the calling agent's request
+	result, err := e.ExecuteBehavioralMatrixWithContext(ctx, request)
 	if err != nil {
 		return "", fmt.Errorf("failed to execute project orchestrator: %w", err)
 	}
@@ -1199,6 +1373,6 @@ func (e *Engine) executeSpawnBehavioralAgents(params map[string]interface{}) (st
 }
 
 // ExecuteSpawnBehavioralAgents is a public wrapper for testing the spawn_behavioral_agents tool
-func (e *Engine) ExecuteSpawnBehavioralAgents(params map[string]interface{}) (string, error) {
-	return e.executeSpawnBehavioralAgents(params)
+func (e *Engine) ExecuteSpawnBehavioralAgents(ctx context.Context, params map[string]interface{}) (string, error) {
+	return e.executeSpawnBehavioralAgents(ctx, params)
 }
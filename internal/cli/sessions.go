@@ -0,0 +1,518 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gorka/internal/behavioral"
+	"gorka/internal/mcp"
+	"gorka/internal/session"
+	"gorka/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionFlags holds the flags shared by the sessions subcommands
+var sessionFlags struct {
+	workspace string
+	agent     string
+	status    string
+	since     string
+	until     string
+	text      string
+	format    string
+	output    string
+
+	maxAge   string
+	maxCount int
+	maxSize  int
+	archive  bool
+	dryRun   bool
+	force    bool
+
+	message string
+}
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Browse agent sessions",
+	Long:  "List, show, search, export, remove, compact, garbage-collect and resume the agent sessions stored under .gorka/sessions",
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sessions, most recently updated first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listSessions(cmd.OutOrStdout()); err != nil {
+			fmt.Printf("Error listing sessions: %v\n", err)
+		}
+	},
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <session-id>",
+	Short: "Print a session transcript",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := exportSession(cmd.OutOrStdout(), args[0], session.FormatMarkdown, ""); err != nil {
+			fmt.Printf("Error showing session: %v\n", err)
+		}
+	},
+}
+
+var sessionsSearchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Find messages containing text",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := searchSessions(cmd.OutOrStdout(), args[0]); err != nil {
+			fmt.Printf("Error searching sessions: %v\n", err)
+		}
+	},
+}
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export <session-id>",
+	Short: "Export a session transcript as Markdown, HTML or JSONL",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := exportSession(cmd.OutOrStdout(), args[0], sessionFlags.format, sessionFlags.output); err != nil {
+			fmt.Printf("Error exporting session: %v\n", err)
+		}
+	},
+}
+
+var sessionsRmCmd = &cobra.Command{
+	Use:   "rm [session-id...]",
+	Short: "Remove sessions by ID, or every session matching the filters",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := removeSessions(cmd.OutOrStdout(), args); err != nil {
+			fmt.Printf("Error removing sessions: %v\n", err)
+		}
+	},
+}
+
+var sessionsCompactCmd = &cobra.Command{
+	Use:   "compact [session-id...]",
+	Short: "Rewrite session logs without superseded records (all completed sessions by default)",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := compactSessions(cmd.OutOrStdout(), args); err != nil {
+			fmt.Printf("Error compacting sessions: %v\n", err)
+		}
+	},
+}
+
+var sessionsGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove or archive completed sessions over the retention limits",
+	Long: "Remove or archive completed sessions over the retention limits, keeping the newest ones, and interrupted sessions over the age limit. " +
+		"Limits default to the SECONDBRAIN_SESSION_RETENTION_* and SECONDBRAIN_SESSION_ARCHIVE environment variables.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := collectSessions(cmd); err != nil {
+			fmt.Printf("Error collecting sessions: %v\n", err)
+		}
+	},
+}
+
+var sessionsResumeCmd = &cobra.Command{
+	Use:   "resume [session-id]",
+	Short: "Resume an interrupted session, or list the interrupted sessions",
+	Long: "Resume a session interrupted by a crash or failed run from its last complete tool exchange. " +
+		"Needs the same configuration as secondbrain-mcp, such as OPENROUTER_API_KEY.",
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		sessionID := ""
+		if len(args) == 1 {
+			sessionID = args[0]
+		}
+		if err := resumeSession(cmd.OutOrStdout(), sessionID); err != nil {
+			fmt.Printf("Error resuming session: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	sessionsCmd.PersistentFlags().StringVar(&sessionFlags.workspace, "workspace", "", "workspace holding .gorka/sessions (default: current directory)")
+	for _, cmd := range []*cobra.Command{sessionsListCmd, sessionsSearchCmd, sessionsRmCmd} {
+		cmd.Flags().StringVar(&sessionFlags.agent, "agent", "", "only sessions of this agent")
+		cmd.Flags().StringVar(&sessionFlags.status, "status", "", "only active, completed or interrupted sessions")
+		cmd.Flags().StringVar(&sessionFlags.since, "since", "", "only sessions used since a date (2006-01-02 or RFC 3339) or a duration ago (36h, 7d)")
+		cmd.Flags().StringVar(&sessionFlags.until, "until", "", "only sessions started until a date or a duration ago")
+	}
+	for _, cmd := range []*cobra.Command{sessionsListCmd, sessionsRmCmd} {
+		cmd.Flags().StringVar(&sessionFlags.text, "text", "", "only sessions containing this text")
+	}
+	sessionsRmCmd.Flags().BoolVar(&sessionFlags.force, "force", false, "also remove active sessions, which a running server may still be using")
+	sessionsExportCmd.Flags().StringVar(&sessionFlags.format, "format", session.FormatMarkdown, "markdown, html or jsonl")
+	sessionsExportCmd.Flags().StringVarP(&sessionFlags.output, "output", "o", "", "file to write (default: standard output)")
+	sessionsGCCmd.Flags().StringVar(&sessionFlags.maxAge, "max-age", "", "collect sessions not used for this long (30d, 12h)")
+	sessionsGCCmd.Flags().IntVar(&sessionFlags.maxCount, "max-count", 0, "keep at most this many completed sessions")
+	sessionsGCCmd.Flags().IntVar(&sessionFlags.maxSize, "max-size", 0, "keep at most this many megabytes of completed sessions")
+	sessionsGCCmd.Flags().BoolVar(&sessionFlags.archive, "archive", false, "gzip collected sessions into .gorka/sessions/archive instead of deleting them")
+	sessionsGCCmd.Flags().BoolVar(&sessionFlags.dryRun, "dry-run", false, "only list the sessions that would be collected")
+	sessionsResumeCmd.Flags().StringVarP(&sessionFlags.message, "message", "m", "", "message for the agent when it resumes (default: carry on with the task)")
+
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsShowCmd, sessionsSearchCmd, sessionsExportCmd, sessionsRmCmd, sessionsCompactCmd, sessionsGCCmd, sessionsResumeCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+// sessionWorkspace returns the workspace given with --workspace, or the current directory
+func sessionWorkspace() (string, error) {
+	if sessionFlags.workspace != "" {
+		return sessionFlags.workspace, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	return cwd, nil
+}
+
+// sessionsDir returns the session storage of the workspace, which must already exist
+func sessionsDir() (string, error) {
+	workspace, err := sessionWorkspace()
+	if err != nil {
+		return "", err
+	}
+
+	storageDir := filepath.Join(workspace, ".gorka", "sessions")
+	if _, err := os.Stat(storageDir); err != nil {
+		return "", fmt.Errorf("no sessions found in %s", workspace)
+	}
+	return storageDir, nil
+}
+
+// openSessions loads the sessions of the workspace for browsing. Nothing in the workspace is
+// created or changed, so it is safe next to a running server.
+func openSessions() (*session.SessionManager, error) {
+	storageDir, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	return session.NewSessionManagerWithStore(session.NewReadOnlyJSONLStore(storageDir)), nil
+}
+
+// openSessionsForWrite loads the sessions of the workspace for the commands that change them,
+// importing sessions saved as whole JSON files into session logs first
+func openSessionsForWrite() (*session.SessionManager, error) {
+	storageDir, err := sessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	return session.NewSessionManagerWithDir(storageDir), nil
+}
+
+// sessionFilter builds the filter from the command-line flags
+func sessionFilter(text string) (session.SessionFilter, error) {
+	filter := session.SessionFilter{AgentID: sessionFlags.agent, Text: text}
+
+	switch sessionFlags.status {
+	case "", session.StatusActive, session.StatusCompleted, session.StatusInterrupted:
+		filter.Status = sessionFlags.status
+	default:
+		return filter, fmt.Errorf("invalid status %q (must be active, completed or interrupted)", sessionFlags.status)
+	}
+
+	var err error
+	if filter.Since, err = parseSessionTime(sessionFlags.since); err != nil {
+		return filter, fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseSessionTime(sessionFlags.until); err != nil {
+		return filter, fmt.Errorf("invalid --until: %w", err)
+	}
+	return filter, nil
+}
+
+// parseSessionTime accepts a date, an RFC 3339 timestamp, or a duration before now such as 36h or 7d
+func parseSessionTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if age, ok := parseAge(value); ok {
+		return time.Now().Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date, timestamp or duration", value)
+}
+
+// parseAge accepts a number of days such as 7d, or a Go duration such as 36h
+func parseAge(value string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, true
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+func listSessions(w io.Writer) error {
+	sm, err := openSessions()
+	if err != nil {
+		return err
+	}
+	filter, err := sessionFilter(sessionFlags.text)
+	if err != nil {
+		return err
+	}
+
+	sessions := sm.ListSessions(filter)
+	if len(sessions) == 0 {
+		fmt.Fprintln(w, "No matching sessions")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAGENT\tSTATUS\tSTOP REASON\tMESSAGES\tUPDATED")
+	for _, s := range sessions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", s.ID, s.AgentID, s.Status(), s.StopReason, len(s.Messages), s.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+func searchSessions(w io.Writer, text string) error {
+	sm, err := openSessions()
+	if err != nil {
+		return err
+	}
+	filter, err := sessionFilter(text)
+	if err != nil {
+		return err
+	}
+
+	hits := sm.SearchSessions(filter)
+	if len(hits) == 0 {
+		fmt.Fprintln(w, "No matches")
+		return nil
+	}
+	for _, hit := range hits {
+		location := fmt.Sprintf("message %d", hit.Index+1)
+		if hit.Index < 0 {
+			location = "working memory"
+		}
+		fmt.Fprintf(w, "%s (%s) %s, %s: %s\n", hit.SessionID, hit.AgentID, location, hit.Role, hit.Snippet)
+	}
+	return nil
+}
+
+func exportSession(w io.Writer, sessionID, format, output string) error {
+	switch format {
+	case "md":
+		format = session.FormatMarkdown
+	case "htm":
+		format = session.FormatHTML
+	}
+
+	sm, err := openSessions()
+	if err != nil {
+		return err
+	}
+	s, exists := sm.GetSession(sessionID)
+	if !exists {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+
+	if output == "" {
+		return session.Export(w, s, format)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := session.Export(file, s, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Exported %s to %s\n", sessionID, output)
+	return nil
+}
+
+func removeSessions(w io.Writer, ids []string) error {
+	sm, err := openSessionsForWrite()
+	if err != nil {
+		return err
+	}
+	defer sm.Close()
+
+	if len(ids) == 0 {
+		// Removing by filter alone must not wipe every session by accident
+		if sessionFlags.agent == "" && sessionFlags.status == "" && sessionFlags.since == "" && sessionFlags.until == "" && sessionFlags.text == "" {
+			return fmt.Errorf("give session IDs or at least one filter")
+		}
+		filter, err := sessionFilter(sessionFlags.text)
+		if err != nil {
+			return err
+		}
+		for _, s := range sm.ListSessions(filter) {
+			ids = append(ids, s.ID)
+		}
+	}
+
+	// An active session may belong to a running server, which would keep writing to it
+	if !sessionFlags.force {
+		for _, id := range ids {
+			if s, exists := sm.GetSession(id); exists && s.Status() == session.StatusActive {
+				return fmt.Errorf("session %s is active and may still be running; use --force to remove it anyway", id)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		if err := sm.RemoveSession(id); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed %s\n", id)
+	}
+	return nil
+}
+
+func compactSessions(w io.Writer, ids []string) error {
+	sm, err := openSessionsForWrite()
+	if err != nil {
+		return err
+	}
+	defer sm.Close()
+
+	if len(ids) == 0 {
+		// Active sessions may still be written to by a running server
+		for _, s := range sm.ListSessions(session.SessionFilter{Status: session.StatusCompleted}) {
+			ids = append(ids, s.ID)
+		}
+	}
+
+	for _, id := range ids {
+		if err := sm.CompactStorage(id); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Compacted %s\n", id)
+	}
+	return nil
+}
+
+func collectSessions(cmd *cobra.Command) error {
+	retention, err := utils.LoadSessionRetention()
+	if err != nil {
+		return err
+	}
+	policy := session.RetentionFromConfig(retention)
+
+	// Flags given on the command line replace the configured limits
+	flags := cmd.Flags()
+	if flags.Changed("max-age") {
+		age, ok := parseAge(sessionFlags.maxAge)
+		if !ok {
+			return fmt.Errorf("invalid --max-age %q (use a number of days such as 30d, or a duration such as 12h)", sessionFlags.maxAge)
+		}
+		policy.MaxAge = age
+	}
+	if flags.Changed("max-count") {
+		policy.MaxCount = sessionFlags.maxCount
+	}
+	if flags.Changed("max-size") {
+		policy.MaxBytes = int64(sessionFlags.maxSize) << 20
+	}
+	if flags.Changed("archive") {
+		policy.Archive = sessionFlags.archive
+	}
+	if !policy.Enabled() {
+		return fmt.Errorf("no retention limits set; use --max-age, --max-count or --max-size")
+	}
+
+	sm, err := openSessionsForWrite()
+	if err != nil {
+		return err
+	}
+	defer sm.Close()
+	collected, err := sm.CollectSessions(policy, sessionFlags.dryRun)
+
+	w := cmd.OutOrStdout()
+	action := "Removed"
+	if policy.Archive {
+		action = "Archived"
+	}
+	if sessionFlags.dryRun {
+		action = "Would " + strings.ToLower(strings.TrimSuffix(action, "d"))
+	}
+	var freed int64
+	for _, s := range collected {
+		freed += s.Size
+		fmt.Fprintf(w, "%s %s (over %s limit, %d bytes, updated %s)\n", action, s.ID, s.Reason, s.Size, s.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintf(w, "%d sessions, %.1f MB\n", len(collected), float64(freed)/(1<<20))
+	return err
+}
+
+// resumeSession resumes an interrupted session with the agents of secondbrain-mcp and prints the
+// result, or lists the interrupted sessions when sessionID is empty
+func resumeSession(w io.Writer, sessionID string) error {
+	if sessionID == "" {
+		sm, err := openSessions()
+		if err != nil {
+			return err
+		}
+		sessions := sm.ListSessions(session.SessionFilter{Status: session.StatusInterrupted})
+		if len(sessions) == 0 {
+			fmt.Fprintln(w, "No interrupted sessions")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tAGENT\tMESSAGES\tLAST ACTIVITY\tREASON")
+		for _, s := range sessions {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", s.ID, s.AgentID, len(s.Messages), s.Interruption.LastActivity.Local().Format("2006-01-02 15:04"), s.Interruption.Reason)
+		}
+		return tw.Flush()
+	}
+
+	// The agents run against the same workspace the sessions are stored in
+	workspace, err := sessionWorkspace()
+	if err != nil {
+		return err
+	}
+	if sessionFlags.workspace != "" || os.Getenv("SECONDBRAIN_WORKSPACE") == "" {
+		os.Setenv("SECONDBRAIN_WORKSPACE", workspace)
+	}
+	config, err := utils.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("configuration loading failed: %w", err)
+	}
+
+	// The server registers the tools the agents call, including the other agents
+	engine := behavioral.NewEngine()
+	mcp.NewBehavioralServer(engine, config)
+	defer engine.CloseSessions()
+	if _, err := engine.RecoverSessions(); err != nil {
+		return fmt.Errorf("failed to recover interrupted sessions: %w", err)
+	}
+
+	result, err := engine.ResumeSessionWithContext(context.Background(), sessionID, sessionFlags.message)
+	if err != nil {
+		return err
+	}
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal behavioral result: %w", err)
+	}
+	fmt.Fprintln(w, string(resultJSON))
+	return nil
+}
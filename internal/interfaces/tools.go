@@ -1,6 +1,8 @@
 package interfaces
 
 import (
+	"context"
+
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 )
@@ -8,7 +10,7 @@ import (
 // ToolRegistrar defines the interface for registering tools
 type ToolRegistrar interface {
 	RegisterMCPTool(name, description string, handler mcp.ToolHandler, schema *jsonschema.Schema)
-	RegisterOpenAITool(name, description string, schema *jsonschema.Schema, executor func(params map[string]interface{}) (string, error))
+	RegisterOpenAITool(name, description string, schema *jsonschema.Schema, executor func(ctx context.Context, params map[string]interface{}) (string, error))
 }
 
 // ToolProvider defines the interface for tool providers
@@ -76,9 +76,33 @@ func (bs *BehavioralServer) setupTools() {
 		
 		fmt.Printf("DEBUG: Registered tool to both MCP and OpenAI: %s -> %s\n", toolDef.Name, toolDef.AgentID)
 	}
+
+	// Follow-up questions reuse the session of an earlier behavioral tool call. This and resuming
+	// are for the MCP client only; an agent continuing its own or a sibling's session would recurse
+	// or write to a log that is already being written.
+	tool, handler := CreateContinueSessionTool(bs.engine)
+	mcp.AddTool(bs.server, tool, handler)
+
+	// Sessions cut short by a crash or failed run are resumed from their last complete tool exchange
+	tool, handler = CreateResumeSessionTool(bs.engine)
+	mcp.AddTool(bs.server, tool, handler)
 }
 
 func (bs *BehavioralServer) Start(ctx context.Context) error {
+	// Sessions a previous server left running are marked interrupted so they can be resumed
+	recovered, err := bs.engine.RecoverSessions()
+	if err != nil {
+		fmt.Printf("WARNING: Failed to recover interrupted sessions: %v\n", err)
+	}
+	for _, agentSession := range recovered {
+		fmt.Printf("DEBUG: Marked session %s of agent %s interrupted: %s\n", agentSession.ID, agentSession.AgentID, agentSession.Interruption.Reason)
+	}
+
+	// Old sessions are collected for as long as the server runs
+	sweepCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	bs.engine.StartSessionSweeper(sweepCtx)
+
 	transport := mcp.NewStdioTransport()
 
 	session, err := bs.server.Connect(ctx, transport)
@@ -87,5 +111,10 @@ func (bs *BehavioralServer) Start(ctx context.Context) error {
 	}
 
 	session.Wait()
+
+	// Writes the store has not synced yet must not be lost when the server exits
+	if err := bs.engine.CloseSessions(); err != nil {
+		fmt.Printf("WARNING: Failed to flush sessions: %v\n", err)
+	}
 	return nil
 }
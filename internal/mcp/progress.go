@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorka/internal/openrouter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressFlushInterval throttles how often partial text is sent to the client
+const progressFlushInterval = 500 * time.Millisecond
+
+// progressReporter turns agent stream events into MCP progress notifications
+type progressReporter struct {
+	ctx       context.Context
+	session   *mcp.ServerSession
+	token     any
+	agentID   string
+	mu        sync.Mutex
+	progress  float64
+	pending   strings.Builder
+	lastFlush time.Time
+	announced map[string]bool
+}
+
+// newProgressReporter creates a reporter bound to a single tool call's progress token
+func newProgressReporter(ctx context.Context, session *mcp.ServerSession, token any, agentID string) *progressReporter {
+	return &progressReporter{
+		ctx:       ctx,
+		session:   session,
+		token:     token,
+		agentID:   agentID,
+		lastFlush: time.Now(),
+		announced: make(map[string]bool),
+	}
+}
+
+// handle implements openrouter.StreamHandler
+func (r *progressReporter) handle(event openrouter.StreamEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch event.Type {
+	case openrouter.StreamEventContent:
+		r.pending.WriteString(event.Text)
+		if time.Since(r.lastFlush) >= progressFlushInterval {
+			r.flushLocked()
+		}
+	case openrouter.StreamEventToolCallDelta:
+		// Announce each streamed tool call once instead of forwarding raw argument fragments
+		if event.ToolName == "" || r.announced[event.ToolCallID] {
+			return
+		}
+		r.announced[event.ToolCallID] = true
+		r.flushLocked()
+		r.notifyLocked(fmt.Sprintf("[%s] preparing tool call %s", r.agentID, event.ToolName))
+	case openrouter.StreamEventToolCall, openrouter.StreamEventToolResult:
+		r.flushLocked()
+		r.notifyLocked(fmt.Sprintf("[%s] %s", r.agentID, event.Text))
+	}
+}
+
+// flush sends any buffered partial text
+func (r *progressReporter) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushLocked()
+}
+
+func (r *progressReporter) flushLocked() {
+	r.lastFlush = time.Now()
+	if r.pending.Len() == 0 {
+		return
+	}
+	text := r.pending.String()
+	r.pending.Reset()
+	r.notifyLocked(fmt.Sprintf("[%s] %s", r.agentID, text))
+}
+
+// notifyLocked sends one progress notification; progress must increase on every call
+func (r *progressReporter) notifyLocked(message string) {
+	r.progress++
+	err := r.session.NotifyProgress(r.ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: r.token,
+		Progress:      r.progress,
+		Message:       message,
+	})
+	if err != nil {
+		fmt.Printf("WARNING: Failed to send progress notification: %v\n", err)
+	}
+}
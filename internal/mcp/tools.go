@@ -6,14 +6,26 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gorka/internal/behavioral"
 	"gorka/internal/embedded"
+	"gorka/internal/openrouter"
 	"gorka/internal/types"
 
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// ContinueSessionToolName is the tool that sends a follow-up message to an earlier agent session
+const ContinueSessionToolName = "continue_agent_session"
+
+// ResumeSessionToolName is the tool that lists and resumes interrupted agent sessions
+const ResumeSessionToolName = "resume_agent_session"
+
+// SessionIDParameter is the tool argument naming the agent session to continue
+const SessionIDParameter = "session_id"
+
 type ToolDefinition struct {
 	Name        string
 	Description string
@@ -71,13 +83,22 @@ func CreateBehavioralToolHandler(engine *behavioral.Engine, agentID string) mcp.
 			inputParams[k] = v
 		}
 
-		behavioralReq := &types.BehavioralRequest{
-			AgentID:          agentID,
-			InputParameters:  inputParams,
-			ExecutionContext: map[string]interface{}{},
+		behavioralReq, err := newBehavioralRequest(agentID, inputParams)
+		if err != nil {
+			return nil, err
+		}
+
+		// Stream progress back to the client when it asked for it
+		var reporter *progressReporter
+		if token := params.GetProgressToken(); token != nil && session != nil {
+			reporter = newProgressReporter(ctx, session, token, agentID)
+			ctx = openrouter.WithStreamHandler(ctx, reporter.handle)
 		}
 
-		result, err := engine.ExecuteBehavioralMatrix(behavioralReq)
+		result, err := engine.ExecuteBehavioralMatrixWithContext(ctx, behavioralReq)
+		if reporter != nil {
+			reporter.flush()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -93,15 +114,14 @@ func CreateBehavioralToolHandler(engine *behavioral.Engine, agentID string) mcp.
 }
 
 // CreateBehavioralOpenAIExecutor creates an OpenAI executor function for behavioral tools
-func CreateBehavioralOpenAIExecutor(engine *behavioral.Engine, agentID string) func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
-		behavioralReq := &types.BehavioralRequest{
-			AgentID:          agentID,
-			InputParameters:  params,
-			ExecutionContext: map[string]interface{}{},
+func CreateBehavioralOpenAIExecutor(engine *behavioral.Engine, agentID string) func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
+		behavioralReq, err := newBehavioralRequest(agentID, params)
+		if err != nil {
+			return "", err
 		}
 
-		result, err := engine.ExecuteBehavioralMatrix(behavioralReq)
+		result, err := engine.ExecuteBehavioralMatrixWithContext(ctx, behavioralReq)
 		if err != nil {
 			return "", err
 		}
@@ -116,6 +136,45 @@ func CreateBehavioralOpenAIExecutor(engine *behavioral.Engine, agentID string) f
 	}
 }
 
+// newBehavioralRequest builds the request for a behavioral tool call, taking the optional
+// session_id argument out of the matrix input parameters
+func newBehavioralRequest(agentID string, params map[string]interface{}) (*types.BehavioralRequest, error) {
+	inputParams := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		inputParams[k] = v
+	}
+
+	var sessionID string
+	if value, ok := inputParams[SessionIDParameter]; ok {
+		sessionID, ok = value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", SessionIDParameter)
+		}
+		delete(inputParams, SessionIDParameter)
+	}
+
+	return &types.BehavioralRequest{
+		AgentID:          agentID,
+		InputParameters:  inputParams,
+		ExecutionContext: map[string]interface{}{},
+		SessionID:        sessionID,
+	}, nil
+}
+
+// withSessionID adds the optional session_id argument to a behavioral tool's input schema
+func withSessionID(schema *jsonschema.Schema) *jsonschema.Schema {
+	extended := *schema
+	extended.Properties = make(map[string]*jsonschema.Schema, len(schema.Properties)+1)
+	for name, property := range schema.Properties {
+		extended.Properties[name] = property
+	}
+	extended.Properties[SessionIDParameter] = &jsonschema.Schema{
+		Type:        "string",
+		Description: "Continue an earlier session of this agent, returned as execution_meta.session_id, instead of starting a new one",
+	}
+	return &extended
+}
+
 // CreateBehavioralToolWithSchema creates a behavioral tool with extracted input schema
 func CreateBehavioralToolWithSchema(engine *behavioral.Engine, toolDef ToolDefinition) (*mcp.Tool, mcp.ToolHandler, error) {
 	// Get the behavioral matrix to extract schema
@@ -147,13 +206,176 @@ func CreateBehavioralToolWithSchema(engine *behavioral.Engine, toolDef ToolDefin
 	tool := &mcp.Tool{
 		Name:        toolDef.Name,
 		Description: toolDef.Description,
-		InputSchema: inputSchema,
+		InputSchema: withSessionID(inputSchema),
 	}
 
 	handler := CreateBehavioralToolHandler(engine, toolDef.AgentID)
 	return tool, handler, nil
 }
 
+// CreateContinueSessionTool creates the continue_agent_session tool, which sends a follow-up
+// message to an earlier agent session
+func CreateContinueSessionTool(engine *behavioral.Engine) (*mcp.Tool, mcp.ToolHandler) {
+	tool := &mcp.Tool{
+		Name:        ContinueSessionToolName,
+		Description: "Send a follow-up message to an earlier behavioral agent session. The agent keeps everything it already read, so follow-up questions need no re-reading.",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				SessionIDParameter: {
+					Type:        "string",
+					Description: "Session to continue, as returned in execution_meta.session_id",
+				},
+				"message": {
+					Type:        "string",
+					Description: "Follow-up message for the agent",
+				},
+			},
+			Required: []string{SessionIDParameter, "message"},
+		},
+	}
+
+	execute := CreateContinueSessionOpenAIExecutor(engine)
+	handler := func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
+		// Stream progress back to the client when it asked for it
+		var reporter *progressReporter
+		if token := params.GetProgressToken(); token != nil && session != nil {
+			reporter = newProgressReporter(ctx, session, token, ContinueSessionToolName)
+			ctx = openrouter.WithStreamHandler(ctx, reporter.handle)
+		}
+
+		resultJSON, err := execute(ctx, params.Arguments)
+		if reporter != nil {
+			reporter.flush()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.CallToolResultFor[any]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultJSON},
+			},
+		}, nil
+	}
+	return tool, handler
+}
+
+// CreateContinueSessionOpenAIExecutor creates an OpenAI executor function for continue_agent_session
+func CreateContinueSessionOpenAIExecutor(engine *behavioral.Engine) func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
+		sessionID, _ := params[SessionIDParameter].(string)
+		message, _ := params["message"].(string)
+		if sessionID == "" || message == "" {
+			return "", fmt.Errorf("%s and message are required", SessionIDParameter)
+		}
+
+		result, err := engine.ContinueSessionWithContext(ctx, sessionID, message)
+		if err != nil {
+			return "", err
+		}
+
+		// Return raw JSON for LLM-to-LLM communication
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal behavioral result: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+}
+
+// CreateResumeSessionTool creates the resume_agent_session tool, which lists interrupted agent
+// sessions or resumes one of them from its last complete tool exchange
+func CreateResumeSessionTool(engine *behavioral.Engine) (*mcp.Tool, mcp.ToolHandler) {
+	tool := &mcp.Tool{
+		Name:        ResumeSessionToolName,
+		Description: "Resume a behavioral agent session that was interrupted by a crash or failed run. Without a session_id, lists the interrupted sessions with why and when they stopped.",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				SessionIDParameter: {
+					Type:        "string",
+					Description: "Interrupted session to resume; omit to list interrupted sessions",
+				},
+				"message": {
+					Type:        "string",
+					Description: "Message for the agent when it resumes; defaults to asking it to carry on",
+				},
+			},
+		},
+	}
+
+	execute := CreateResumeSessionOpenAIExecutor(engine)
+	handler := func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
+		// Stream progress back to the client when it asked for it
+		var reporter *progressReporter
+		if token := params.GetProgressToken(); token != nil && session != nil {
+			reporter = newProgressReporter(ctx, session, token, ResumeSessionToolName)
+			ctx = openrouter.WithStreamHandler(ctx, reporter.handle)
+		}
+
+		resultJSON, err := execute(ctx, params.Arguments)
+		if reporter != nil {
+			reporter.flush()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &mcp.CallToolResultFor[any]{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultJSON},
+			},
+		}, nil
+	}
+	return tool, handler
+}
+
+// interruptedSession is how resume_agent_session lists a session waiting to be resumed
+type interruptedSession struct {
+	SessionID    string    `json:"session_id"`
+	AgentID      string    `json:"agent_id"`
+	Reason       string    `json:"reason"`
+	LastActivity time.Time `json:"last_activity"`
+	Messages     int       `json:"messages"`
+}
+
+// CreateResumeSessionOpenAIExecutor creates an OpenAI executor function for resume_agent_session
+func CreateResumeSessionOpenAIExecutor(engine *behavioral.Engine) func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
+		sessionID, _ := params[SessionIDParameter].(string)
+		message, _ := params["message"].(string)
+
+		var result interface{}
+		if sessionID == "" {
+			sessions := []interruptedSession{}
+			for _, agentSession := range engine.InterruptedSessions() {
+				sessions = append(sessions, interruptedSession{
+					SessionID:    agentSession.ID,
+					AgentID:      agentSession.AgentID,
+					Reason:       agentSession.Interruption.Reason,
+					LastActivity: agentSession.Interruption.LastActivity,
+					Messages:     len(agentSession.Messages),
+				})
+			}
+			result = map[string]interface{}{"interrupted_sessions": sessions}
+		} else {
+			behavioralResult, err := engine.ResumeSessionWithContext(ctx, sessionID, message)
+			if err != nil {
+				return "", err
+			}
+			result = behavioralResult
+		}
+
+		// Return raw JSON for LLM-to-LLM communication
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal behavioral result: %w", err)
+		}
+		return string(resultJSON), nil
+	}
+}
+
 // isVSCodeChatmodeContext detects if the tool is being called from VSCode chatmode
 func isVSCodeChatmodeContext(arguments map[string]any) bool {
 	// Check for VSCode-specific patterns in the arguments
@@ -0,0 +1,65 @@
+package models
+
+// DefaultContextWindow is used for models neither registered nor in the context window table
+const DefaultContextWindow = 128000
+
+// contextWindows holds the input plus output token limit of common model families, for models
+// the registry has no metadata for
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo":  16385,
+	"gpt-4":          8192,
+	"gpt-4-32k":      32768,
+	"gpt-4-turbo":    128000,
+	"gpt-4o":         128000,
+	"chatgpt-4o":     128000,
+	"gpt-4.1":        1047576,
+	"gpt-4.5":        128000,
+	"gpt-5":          400000,
+	"gpt-oss":        131072,
+	"o1":             200000,
+	"o1-mini":        128000,
+	"o3":             200000,
+	"o4-mini":        200000,
+	"claude":         200000,
+	"gemini":         1048576,
+	"gemini-1.5-pro": 2097152,
+	"llama-3":        8192,
+	"llama-3.1":      131072,
+	"llama-3.2":      131072,
+	"llama-3.3":      131072,
+	"llama-4":        1048576,
+	"llama3":         8192,
+	"llama3.1":       131072,
+	"llama3.2":       131072,
+	"llama3.3":       131072,
+	"mistral":        32768,
+	"mistral-large":  131072,
+	"mixtral":        32768,
+	"codestral":      262144,
+	"deepseek":       65536,
+	"qwen":           32768,
+	"qwen2.5":        32768,
+	"qwen3":          131072,
+	"grok":           131072,
+}
+
+// ContextWindow returns the tokens a model accepts across prompt and reply, falling back to
+// the table of common model families
+func ContextWindow(model string) int {
+	if caps, ok := Lookup(model); ok && caps.ContextLength > 0 {
+		return caps.ContextLength
+	}
+
+	// Prefer the longest family so "gpt-4o" is not sized as "gpt-4"
+	_, name := splitModel(model)
+	var best string
+	for family := range contextWindows {
+		if matchesFamily(name, family) && len(family) > len(best) {
+			best = family
+		}
+	}
+	if best == "" {
+		return DefaultContextWindow
+	}
+	return contextWindows[best]
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheFile is the workspace-relative path of the cached /models metadata
+const CacheFile = ".gorka/cache/models.json"
+
+// CacheTTL is how long cached metadata is used before the model list is fetched again
+const CacheTTL = 24 * time.Hour
+
+// Sources reported by Load
+const (
+	SourceFetched = "fetched"
+	SourceCache   = "cache"
+)
+
+// cacheFile is the on-disk form of the registry
+type cacheFile struct {
+	FetchedAt time.Time      `json:"fetched_at"`
+	Models    []Capabilities `json:"models"`
+}
+
+// modelList covers the fields of OpenRouter's /models response the registry uses
+type modelList struct {
+	Data []struct {
+		ID            string `json:"id"`
+		ContextLength int    `json:"context_length"`
+		Architecture  struct {
+			InputModalities []string `json:"input_modalities"`
+		} `json:"architecture"`
+		Pricing struct {
+			Prompt     string `json:"prompt"`
+			Completion string `json:"completion"`
+		} `json:"pricing"`
+		TopProvider struct {
+			MaxCompletionTokens int `json:"max_completion_tokens"`
+		} `json:"top_provider"`
+		SupportedParameters []string `json:"supported_parameters"`
+	} `json:"data"`
+}
+
+// Fetch downloads model metadata from an OpenRouter-compatible /models endpoint
+func Fetch(ctx context.Context, baseURL string, httpClient *http.Client) ([]Capabilities, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/models"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch model list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d", endpoint, resp.StatusCode)
+	}
+
+	var list modelList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+
+	models := make([]Capabilities, 0, len(list.Data))
+	for _, entry := range list.Data {
+		caps := Capabilities{
+			ID:                  entry.ID,
+			ContextLength:       entry.ContextLength,
+			MaxCompletionTokens: entry.TopProvider.MaxCompletionTokens,
+		}
+		for _, parameter := range entry.SupportedParameters {
+			if parameter == "tools" {
+				caps.SupportsTools = true
+			}
+		}
+		for _, modality := range entry.Architecture.InputModalities {
+			if modality == "image" {
+				caps.SupportsImages = true
+			}
+		}
+
+		// Prices are quoted in USD per token; routers such as openrouter/auto quote -1
+		prompt, promptErr := strconv.ParseFloat(entry.Pricing.Prompt, 64)
+		completion, completionErr := strconv.ParseFloat(entry.Pricing.Completion, 64)
+		if promptErr == nil && completionErr == nil && prompt >= 0 && completion >= 0 {
+			caps.Pricing = &Pricing{Prompt: prompt * 1e6, Completion: completion * 1e6}
+		}
+
+		models = append(models, caps)
+	}
+	return models, nil
+}
+
+// Load registers model metadata for a workspace. A cache younger than CacheTTL is used as is;
+// otherwise the list is fetched from baseURL and cached, falling back to a stale cache when the
+// fetch fails. An empty baseURL only reads the cache. It returns where the metadata came from.
+func Load(ctx context.Context, workspace, baseURL string, httpClient *http.Client) (string, error) {
+	cached, cacheErr := readCache(workspace)
+	if cacheErr == nil && (baseURL == "" || time.Since(cached.FetchedAt) < CacheTTL) {
+		Register(cached.Models...)
+		return SourceCache, nil
+	}
+	if baseURL == "" {
+		return "", cacheErr
+	}
+
+	err := Refresh(ctx, workspace, baseURL, httpClient)
+	if err == nil {
+		return SourceFetched, nil
+	}
+	if cacheErr == nil {
+		Register(cached.Models...)
+		return SourceCache, fmt.Errorf("using cached model list from %s: %w", cached.FetchedAt.Format(time.RFC3339), err)
+	}
+	return "", err
+}
+
+// Refresh fetches the model list from baseURL, registers it and writes it to the workspace cache
+func Refresh(ctx context.Context, workspace, baseURL string, httpClient *http.Client) error {
+	models, err := Fetch(ctx, baseURL, httpClient)
+	if err != nil {
+		return err
+	}
+	if len(models) == 0 {
+		return errors.New("model list is empty")
+	}
+	Register(models...)
+
+	// A cache that cannot be written only costs a fetch on the next start
+	if err := writeCache(workspace, cacheFile{FetchedAt: time.Now(), Models: models}); err != nil {
+		fmt.Printf("WARNING: Failed to cache model list: %v\n", err)
+	}
+	return nil
+}
+
+// readCache loads the cached model list of a workspace
+func readCache(workspace string) (cacheFile, error) {
+	var cached cacheFile
+	data, err := os.ReadFile(filepath.Join(workspace, CacheFile))
+	if err != nil {
+		return cached, err
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return cached, fmt.Errorf("failed to parse %s: %w", CacheFile, err)
+	}
+	return cached, nil
+}
+
+// writeCache replaces the cached model list of a workspace
+func writeCache(workspace string, cached cacheFile) error {
+	path := filepath.Join(workspace, CacheFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so concurrent readers never see a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Capabilities describes what a model accepts and what it costs
+type Capabilities struct {
+	ID                  string `json:"id"`
+	ContextLength       int    `json:"context_length,omitempty"`
+	MaxCompletionTokens int    `json:"max_completion_tokens,omitempty"`
+	SupportsTools       bool   `json:"supports_tools"`
+	SupportsImages      bool   `json:"supports_images"`
+	// Pricing is nil when the source does not price the model
+	Pricing *Pricing `json:"pricing,omitempty"`
+}
+
+// Pricing is the USD cost per million tokens
+type Pricing struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// noToolModels lists model families known to reject tool definitions. It is only consulted
+// for models the registry has no metadata for; anything else is assumed to support tools.
+var noToolModels = []string{
+	"o1-mini",
+	"o1-preview",
+	"chatgpt-4o-latest",
+	"gpt-3.5-turbo-instruct",
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Capabilities)
+)
+
+// Register adds or replaces the metadata for each model
+func Register(models ...Capabilities) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	for _, model := range models {
+		registry[model.ID] = model
+	}
+}
+
+// Lookup returns the registered metadata for a model. Besides exact IDs, a name without a
+// vendor prefix or with a date suffix matches the registered model it names, so
+// "claude-sonnet-4-20250514" finds "anthropic/claude-sonnet-4". Other variants such as
+// "gpt-4-turbo" are different models and do not match "gpt-4".
+func Lookup(model string) (Capabilities, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	if caps, ok := registry[model]; ok {
+		return caps, true
+	}
+
+	vendor, name := splitModel(model)
+	var candidates []string
+	for id := range registry {
+		// Tagged variants such as ":free" differ in price and only match exactly
+		if strings.Contains(id, ":") {
+			continue
+		}
+		idVendor, key := splitModel(id)
+		if vendor != "" && idVendor != vendor {
+			continue
+		}
+		if IsRelease(name, key) {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return Capabilities{}, false
+	}
+
+	// Prefer the longest name, then the first ID
+	sort.Slice(candidates, func(i, j int) bool {
+		_, a := splitModel(candidates[i])
+		_, b := splitModel(candidates[j])
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return candidates[i] < candidates[j]
+	})
+	return registry[candidates[0]], true
+}
+
+// SupportsTools reports whether a model accepts tool definitions
+func SupportsTools(model string) bool {
+	if caps, ok := Lookup(model); ok {
+		return caps.SupportsTools
+	}
+	_, name := splitModel(model)
+	for _, family := range noToolModels {
+		if matchesFamily(name, family) {
+			return false
+		}
+	}
+	return true
+}
+
+// Prices returns the pricing of every registered model that has one, keyed by model ID
+func Prices() map[string]Pricing {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	prices := make(map[string]Pricing)
+	for id, caps := range registry {
+		if caps.Pricing != nil {
+			prices[id] = *caps.Pricing
+		}
+	}
+	return prices
+}
+
+// splitModel separates the vendor prefix from a model name, dropping any ":tag"
+func splitModel(model string) (vendor, name string) {
+	name = strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		vendor, name = name[:i], name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return vendor, name
+}
+
+// IsRelease reports whether name, without vendor prefix or tag, is model itself or a dated
+// release of it such as "gpt-4o-2024-08-06", "gpt-4-0613" or "claude-3-5-haiku-latest"
+func IsRelease(name, model string) bool {
+	if name == model {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(name, model+"-")
+	if !ok {
+		return false
+	}
+	if suffix == "latest" {
+		return true
+	}
+	digits := 0
+	for _, r := range suffix {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r != '-':
+			return false
+		}
+	}
+	return digits >= 4
+}
+
+// matchesFamily reports whether name is family itself or a variant of it such as a dated release
+func matchesFamily(name, family string) bool {
+	return name == family || strings.HasPrefix(name, family+"-")
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// modelsServer serves an OpenRouter-style model list and counts the requests it receives
+func modelsServer(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/models" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":[
+			{"id":"fetchvendor/tool-model","context_length":65536,"architecture":{"input_modalities":["text","image"]},
+			 "pricing":{"prompt":"0.000003","completion":"0.000015"},"top_provider":{"max_completion_tokens":8192},
+			 "supported_parameters":["tools","tool_choice","temperature"]},
+			{"id":"fetchvendor/chat-model","context_length":4096,"architecture":{"input_modalities":["text"]},
+			 "pricing":{"prompt":"0","completion":"0"},"supported_parameters":["temperature"]},
+			{"id":"fetchvendor/router","context_length":2000000,"pricing":{"prompt":"-1","completion":"-1"},
+			 "supported_parameters":["tools"]}
+		]}`)
+	}))
+}
+
+func TestLoadFetchesAndCaches(t *testing.T) {
+	requests := 0
+	server := modelsServer(t, &requests)
+	defer server.Close()
+	workspace := t.TempDir()
+
+	source, err := Load(context.Background(), workspace, server.URL, nil)
+	if err != nil || source != SourceFetched {
+		t.Fatalf("Load = %q, %v; want a fetch", source, err)
+	}
+
+	caps, ok := Lookup("fetchvendor/tool-model")
+	if !ok {
+		t.Fatal("Expected the fetched model to be registered")
+	}
+	if !caps.SupportsTools || !caps.SupportsImages || caps.ContextLength != 65536 || caps.MaxCompletionTokens != 8192 {
+		t.Errorf("Unexpected capabilities: %+v", caps)
+	}
+	if caps.Pricing == nil || caps.Pricing.Prompt != 3 || caps.Pricing.Completion != 15 {
+		t.Errorf("Expected prices per million tokens, got %+v", caps.Pricing)
+	}
+	if caps, _ := Lookup("fetchvendor/chat-model"); caps.SupportsTools || caps.Pricing == nil {
+		t.Errorf("Expected a free model without tools, got %+v", caps)
+	}
+	if caps, _ := Lookup("fetchvendor/router"); caps.Pricing != nil {
+		t.Errorf("Expected negative prices to be treated as unknown, got %+v", caps.Pricing)
+	}
+
+	if _, err := os.Stat(filepath.Join(workspace, CacheFile)); err != nil {
+		t.Fatalf("Expected the model list to be cached: %v", err)
+	}
+	source, err = Load(context.Background(), workspace, server.URL, nil)
+	if err != nil || source != SourceCache || requests != 1 {
+		t.Errorf("Expected a fresh cache to be reused, got %q, %v after %d requests", source, err, requests)
+	}
+}
+
+func TestLoadFallsBackToStaleCache(t *testing.T) {
+	workspace := t.TempDir()
+	stale := cacheFile{
+		FetchedAt: time.Now().Add(-2 * CacheTTL),
+		Models:    []Capabilities{{ID: "stalevendor/old-model", ContextLength: 1000, SupportsTools: true}},
+	}
+	if err := writeCache(workspace, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	source, err := Load(context.Background(), workspace, server.URL, nil)
+	if source != SourceCache || err == nil {
+		t.Errorf("Expected the stale cache with a warning, got %q, %v", source, err)
+	}
+	if ContextWindow("stalevendor/old-model") != 1000 {
+		t.Error("Expected the stale cache to be registered")
+	}
+
+	if source, err := Load(context.Background(), t.TempDir(), "", nil); source != "" || err == nil {
+		t.Errorf("Expected an offline load without a cache to fail, got %q, %v", source, err)
+	}
+}
+
+func TestLookupMatchesVariants(t *testing.T) {
+	Register(
+		Capabilities{ID: "lookupvendor/family", ContextLength: 1},
+		Capabilities{ID: "lookupvendor/family-mini", ContextLength: 2},
+		Capabilities{ID: "lookupvendor/family:free", ContextLength: 3},
+	)
+
+	tests := map[string]int{
+		"lookupvendor/family":          1,
+		"lookupvendor/family-20250101": 1,
+		"family-mini":                  2,
+		"lookupvendor/family:free":     3,
+		"lookupvendor/family:nitro":    1,
+	}
+	for model, want := range tests {
+		caps, ok := Lookup(model)
+		if !ok || caps.ContextLength != want {
+			t.Errorf("Lookup(%q) = %+v, %v; want context length %d", model, caps, ok, want)
+		}
+	}
+	if _, ok := Lookup("othervendor/family"); ok {
+		t.Error("Expected a different vendor not to match")
+	}
+	if _, ok := Lookup("lookupvendor/family-turbo"); ok {
+		t.Error("Expected a differently named variant not to match")
+	}
+}
+
+func TestIsRelease(t *testing.T) {
+	tests := map[string]bool{
+		"gpt-4":                  true,
+		"gpt-4-0613":             true,
+		"gpt-4-2024-08-06":       true,
+		"gpt-4-latest":           true,
+		"gpt-4-turbo":            false,
+		"gpt-4-turbo-2024-04-09": false,
+		"gpt-4-32k":              false,
+		"gpt-4o":                 false,
+		"gpt-4-1":                false,
+	}
+	for name, want := range tests {
+		if got := IsRelease(name, "gpt-4"); got != want {
+			t.Errorf("IsRelease(%q, gpt-4) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestStaticFallbacks(t *testing.T) {
+	if SupportsTools("openai/o1-mini-2024-09-12") {
+		t.Error("Expected o1-mini to be known not to support tools")
+	}
+	if !SupportsTools("unknownvendor/unknown-model") {
+		t.Error("Expected unknown models to be assumed to support tools")
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	Register(Capabilities{ID: "windowvendor/claude-listed", ContextLength: 50000})
+
+	tests := map[string]int{
+		"windowvendor/claude-listed": 50000,
+		"anthropic/claude-sonnet-4":  200000,
+		"openai/gpt-4o-mini":         128000,
+		"gpt-4-turbo-preview":        128000,
+		"gpt-4-0613":                 8192,
+		"llama3.1:8b":                131072,
+		"meta-llama/llama-3-8b":      8192,
+		"google/gemini-2.5-pro":      1048576,
+		"some/unknown-model":         DefaultContextWindow,
+	}
+	for model, want := range tests {
+		if got := ContextWindow(model); got != want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package adapters
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files from current parser output")
+
+// goldenResult is what a golden file pins for one corpus response
+type goldenResult struct {
+	ToolCalls []openai.ToolCall `json:"tool_calls"`
+	Error     string            `json:"error,omitempty"`
+}
+
+func TestParseToolCallsGolden(t *testing.T) {
+	adapters := map[string]ModelAdapter{
+		"qwen":     NewQwenAdapter(),
+		"hermes":   NewHermesAdapter(),
+		"mistral":  NewMistralAdapter(),
+		"llama3":   NewLlama3Adapter(),
+		"deepseek": NewDeepSeekAdapter(),
+	}
+
+	for name, adapter := range adapters {
+		inputs, err := filepath.Glob(filepath.Join("testdata", name, "*.txt"))
+		if err != nil {
+			t.Fatalf("Failed to list corpus for %s: %v", name, err)
+		}
+		if len(inputs) == 0 {
+			t.Errorf("No corpus for adapter %s", name)
+		}
+
+		for _, input := range inputs {
+			t.Run(name+"/"+strings.TrimSuffix(filepath.Base(input), ".txt"), func(t *testing.T) {
+				content, err := os.ReadFile(input)
+				if err != nil {
+					t.Fatalf("Failed to read input: %v", err)
+				}
+
+				var got goldenResult
+				got.ToolCalls, err = adapter.ParseToolCalls(string(content))
+				if err != nil {
+					got.Error = err.Error()
+				}
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+
+				goldenPath := strings.TrimSuffix(input, ".txt") + ".golden.json"
+				if *update {
+					if err := os.WriteFile(goldenPath, append(gotJSON, '\n'), 0644); err != nil {
+						t.Fatalf("Failed to write golden file: %v", err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(goldenPath)
+				if err != nil {
+					t.Fatalf("Missing golden file (run go test -update): %v", err)
+				}
+				if strings.TrimSpace(string(want)) != string(gotJSON) {
+					t.Errorf("Parse result changed\n got: %s\nwant: %s", gotJSON, want)
+				}
+			})
+		}
+	}
+}
+
+func TestRegistryRoutesModels(t *testing.T) {
+	registry := NewAdapterRegistry()
+
+	tests := map[string]string{
+		"qwen/qwen3-coder":                          "qwen",
+		"nousresearch/hermes-3-llama-3.1-70b":       "hermes",
+		"NousResearch/Hermes-2-Pro-Mistral-7B":      "hermes",
+		"mistralai/mistral-small-3.2-24b-instruct":  "mistral",
+		"mistralai/devstral-small":                  "mistral",
+		"meta-llama/llama-3.3-70b-instruct":         "llama3",
+		"deepseek/deepseek-chat-v3.1":               "deepseek",
+		"deepseek/deepseek-r1-distill-qwen-32b":     "deepseek",
+		"deepseek-ai/DeepSeek-R1-Distill-Llama-70B": "deepseek",
+		"anthropic/claude-sonnet-4":                 "",
+	}
+
+	for model, want := range tests {
+		got := ""
+		if adapter := registry.GetAdapter(model); adapter != nil {
+			got = adapter.GetName()
+		}
+		if got != want {
+			t.Errorf("%s: expected adapter %q, got %q", model, want, got)
+		}
+	}
+}
+
+func TestConfigRecommendations(t *testing.T) {
+	registry := NewAdapterRegistry()
+
+	tests := map[string]string{
+		"nousresearch/hermes-3-llama-3.1-70b": "hermes",
+		"mistralai/mistral-large":             "mistral",
+		"meta-llama/llama-3.1-8b-instruct":    "llama3_json",
+		"deepseek/deepseek-chat":              "deepseek_v3",
+		"deepseek/deepseek-chat-v3.1":         "deepseek_v31",
+	}
+
+	for model, parser := range tests {
+		recs := registry.GetConfigRecommendations(model)
+		if !recs.HasOptimizations {
+			t.Errorf("%s: expected optimizations", model)
+			continue
+		}
+		if got := recs.ChatTemplateKwargs["tool_call_parser"]; got != parser {
+			t.Errorf("%s: expected parser %s, got %v", model, parser, got)
+		}
+	}
+}
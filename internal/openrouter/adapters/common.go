@@ -0,0 +1,100 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// containsAny reports whether the lowercased model name contains any of the patterns
+func containsAny(modelName string, patterns ...string) bool {
+	modelLower := strings.ToLower(modelName)
+	for _, pattern := range patterns {
+		if strings.Contains(modelLower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// newToolCall builds an OpenAI tool call, encoding arguments as a JSON string when they
+// arrive as an object. An empty id is replaced with call_<index>.
+func newToolCall(index int, id, name string, arguments interface{}) (openai.ToolCall, error) {
+	if name == "" {
+		return openai.ToolCall{}, fmt.Errorf("tool call %d missing or invalid 'name' field", index)
+	}
+
+	argumentsStr, err := argumentsToString(arguments)
+	if err != nil {
+		return openai.ToolCall{}, fmt.Errorf("failed to marshal arguments for tool call %d: %v", index, err)
+	}
+
+	if id == "" {
+		id = fmt.Sprintf("call_%d", index)
+	}
+
+	return openai.ToolCall{
+		ID:   id,
+		Type: openai.ToolTypeFunction,
+		Function: openai.FunctionCall{
+			Name:      name,
+			Arguments: argumentsStr,
+		},
+	}, nil
+}
+
+// argumentsToString normalizes tool arguments to the JSON string OpenAI uses
+func argumentsToString(arguments interface{}) (string, error) {
+	switch v := arguments.(type) {
+	case nil:
+		return "{}", nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "{}", nil
+		}
+		return v, nil
+	default:
+		argsBytes, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(argsBytes), nil
+	}
+}
+
+// toolCallFromObject converts a decoded {"name": ..., "arguments"|"parameters": ...} object.
+// Llama uses "parameters", everyone else uses "arguments".
+func toolCallFromObject(index int, data map[string]interface{}) (openai.ToolCall, error) {
+	name, _ := data["name"].(string)
+	id, _ := data["id"].(string)
+
+	arguments, exists := data["arguments"]
+	if !exists {
+		arguments = data["parameters"]
+	}
+
+	return newToolCall(index, id, name, arguments)
+}
+
+// decodeJSONValues decodes consecutive JSON values from text, as emitted when a model
+// writes several calls back to back or separated by semicolons
+func decodeJSONValues(text string) ([]interface{}, error) {
+	var values []interface{}
+
+	remaining := strings.TrimSpace(text)
+	for remaining != "" {
+		decoder := json.NewDecoder(strings.NewReader(remaining))
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		remaining = strings.TrimSpace(remaining[decoder.InputOffset():])
+		remaining = strings.TrimSpace(strings.TrimPrefix(remaining, ";"))
+	}
+
+	return values, nil
+}
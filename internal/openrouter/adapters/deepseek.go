@@ -0,0 +1,127 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// DeepSeek markers after normalizing the fullwidth bar (U+FF5C) and the SentencePiece
+// space (U+2581) that the real tokens use to their ASCII look-alikes
+const (
+	deepseekCallsBegin = "<|tool_calls_begin|>"
+	deepseekCallsEnd   = "<|tool_calls_end|>"
+	deepseekCallBegin  = "<|tool_call_begin|>"
+	deepseekCallEnd    = "<|tool_call_end|>"
+	deepseekSep        = "<|tool_sep|>"
+)
+
+var deepseekTokenReplacer = strings.NewReplacer("｜", "|", "▁", "_")
+
+// DeepSeekAdapter handles DeepSeek V3/R1 tool-call markers in plain content. V3 and R1 write
+// <｜tool▁call▁begin｜>function<｜tool▁sep｜>name followed by a ```json block; V3.1 writes
+// <｜tool▁call▁begin｜>name<｜tool▁sep｜>{json}
+type DeepSeekAdapter struct{}
+
+// NewDeepSeekAdapter creates a new DeepSeek adapter
+func NewDeepSeekAdapter() *DeepSeekAdapter {
+	return &DeepSeekAdapter{}
+}
+
+// GetName returns the adapter name
+func (a *DeepSeekAdapter) GetName() string {
+	return "deepseek"
+}
+
+// CanHandle determines if this adapter can handle the given model; R1 distills of
+// Qwen and Llama keep the DeepSeek chat template, so they match here first
+func (a *DeepSeekAdapter) CanHandle(modelName string) bool {
+	return containsAny(modelName, "deepseek")
+}
+
+// ParseToolCalls extracts tool calls between DeepSeek tool-call markers
+func (a *DeepSeekAdapter) ParseToolCalls(content string) ([]openai.ToolCall, error) {
+	normalized := deepseekTokenReplacer.Replace(content)
+
+	start := strings.Index(normalized, deepseekCallBegin)
+	if start < 0 {
+		return nil, nil // No tool calls found
+	}
+	body := normalized[start:]
+	if end := strings.Index(body, deepseekCallsEnd); end >= 0 {
+		body = body[:end]
+	}
+
+	var toolCalls []openai.ToolCall
+	for _, block := range strings.Split(body, deepseekCallBegin) {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		if end := strings.Index(block, deepseekCallEnd); end >= 0 {
+			block = block[:end]
+		}
+
+		index := len(toolCalls) + 1
+		sepAt := strings.Index(block, deepseekSep)
+		if sepAt < 0 {
+			return nil, fmt.Errorf("tool call %d has no %s marker", index, deepseekSep)
+		}
+		name := strings.TrimSpace(block[:sepAt])
+		arguments := block[sepAt+len(deepseekSep):]
+
+		// V3/R1 put the call type before the separator and the name on the first line after it
+		if name == "function" {
+			lines := strings.SplitN(strings.TrimSpace(arguments), "\n", 2)
+			name = strings.TrimSpace(lines[0])
+			arguments = ""
+			if len(lines) > 1 {
+				arguments = lines[1]
+			}
+		}
+
+		toolCall, err := newToolCall(index, "", name, stripCodeFence(arguments))
+		if err != nil {
+			return nil, err
+		}
+		toolCalls = append(toolCalls, toolCall)
+	}
+
+	return toolCalls, nil
+}
+
+// stripCodeFence removes a surrounding ```json ... ``` fence
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if newline := strings.Index(text, "\n"); newline >= 0 {
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// ShouldFallbackToStandard returns true to try standard parsing if marker parsing fails
+func (a *DeepSeekAdapter) ShouldFallbackToStandard() bool {
+	return true
+}
+
+// GetConfigRecommendations returns DeepSeek-specific configuration recommendations
+func (a *DeepSeekAdapter) GetConfigRecommendations(modelName string) ModelConfigRecommendations {
+	parser := "deepseek_v3"
+	if containsAny(modelName, "v3.1", "v3-1") {
+		parser = "deepseek_v31"
+	}
+
+	return ModelConfigRecommendations{
+		ChatTemplateKwargs: map[string]interface{}{
+			// vLLM serves DeepSeek with --tool-call-parser deepseek_v3 (or deepseek_v31)
+			"tool_call_parser": parser,
+		},
+		DebugMessage:     fmt.Sprintf("Applied DeepSeek optimizations (%s parser) for model: %s", parser, modelName),
+		HasOptimizations: true,
+	}
+}
@@ -0,0 +1,78 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// hermesToolCallPattern matches <tool_call>...</tool_call> blocks; the closing tag is optional
+// because Hermes models often stop generating right after the final JSON object
+var hermesToolCallPattern = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*(?:</tool_call>|$)`)
+
+// HermesAdapter handles Nous Hermes models, which wrap each call in <tool_call>{json}</tool_call>
+type HermesAdapter struct{}
+
+// NewHermesAdapter creates a new Hermes adapter
+func NewHermesAdapter() *HermesAdapter {
+	return &HermesAdapter{}
+}
+
+// GetName returns the adapter name
+func (a *HermesAdapter) GetName() string {
+	return "hermes"
+}
+
+// CanHandle determines if this adapter can handle the given model
+func (a *HermesAdapter) CanHandle(modelName string) bool {
+	return containsAny(modelName, "hermes", "nousresearch/")
+}
+
+// ParseToolCalls extracts tool calls from Hermes <tool_call> blocks
+func (a *HermesAdapter) ParseToolCalls(content string) ([]openai.ToolCall, error) {
+	matches := hermesToolCallPattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil, nil // No tool calls found
+	}
+
+	var toolCalls []openai.ToolCall
+	for _, match := range matches {
+		body := strings.TrimSpace(match[1])
+		if body == "" {
+			continue
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &data); err != nil {
+			return nil, fmt.Errorf("failed to parse tool call %d: %v", len(toolCalls)+1, err)
+		}
+
+		toolCall, err := toolCallFromObject(len(toolCalls)+1, data)
+		if err != nil {
+			return nil, err
+		}
+		toolCalls = append(toolCalls, toolCall)
+	}
+
+	return toolCalls, nil
+}
+
+// ShouldFallbackToStandard returns true to try standard parsing if tag parsing fails
+func (a *HermesAdapter) ShouldFallbackToStandard() bool {
+	return true
+}
+
+// GetConfigRecommendations returns Hermes-specific configuration recommendations
+func (a *HermesAdapter) GetConfigRecommendations(modelName string) ModelConfigRecommendations {
+	return ModelConfigRecommendations{
+		ChatTemplateKwargs: map[string]interface{}{
+			// vLLM serves Hermes with --tool-call-parser hermes and the tool_use chat template
+			"tool_call_parser": "hermes",
+		},
+		DebugMessage:     fmt.Sprintf("Applied Hermes optimizations (hermes parser) for model: %s", modelName),
+		HasOptimizations: true,
+	}
+}
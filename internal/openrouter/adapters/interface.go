@@ -49,8 +49,13 @@ func NewAdapterRegistry() *AdapterRegistry {
 		adapters: make([]ModelAdapter, 0),
 	}
 	
-	// Register adapters in order of priority
+	// Register adapters in order of priority. DeepSeek comes first because its R1 distills
+	// carry qwen/llama in their names, and Hermes before Llama for Hermes-3-Llama-3.1.
+	registry.RegisterAdapter(NewDeepSeekAdapter())
+	registry.RegisterAdapter(NewHermesAdapter())
 	registry.RegisterAdapter(NewQwenAdapter())
+	registry.RegisterAdapter(NewMistralAdapter())
+	registry.RegisterAdapter(NewLlama3Adapter())
 	
 	return registry
 }
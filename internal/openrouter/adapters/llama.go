@@ -0,0 +1,106 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const llamaPythonTag = "<|python_tag|>"
+
+// llamaEndTokens terminate a Llama 3 message and may leak into content from raw completions
+var llamaEndTokens = []string{"<|eom_id|>", "<|eot_id|>", "<|end_of_text|>"}
+
+// Llama3Adapter handles Llama 3.x models, which emit custom tool calls as
+// <|python_tag|>{"name": ..., "parameters": {...}} or, without the tag, as a bare JSON
+// object making up the whole message
+type Llama3Adapter struct{}
+
+// NewLlama3Adapter creates a new Llama 3 adapter
+func NewLlama3Adapter() *Llama3Adapter {
+	return &Llama3Adapter{}
+}
+
+// GetName returns the adapter name
+func (a *Llama3Adapter) GetName() string {
+	return "llama3"
+}
+
+// CanHandle determines if this adapter can handle the given model
+func (a *Llama3Adapter) CanHandle(modelName string) bool {
+	return containsAny(modelName, "llama-3", "llama3", "llama-v3")
+}
+
+// ParseToolCalls extracts JSON tool calls from python_tag or bare JSON content
+func (a *Llama3Adapter) ParseToolCalls(content string) ([]openai.ToolCall, error) {
+	body := content
+	if tagAt := strings.Index(body, llamaPythonTag); tagAt >= 0 {
+		body = body[tagAt+len(llamaPythonTag):]
+	} else if !a.looksLikeBareCall(body) {
+		return nil, nil // No tool calls found
+	}
+
+	for _, token := range llamaEndTokens {
+		body = strings.ReplaceAll(body, token, "")
+	}
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "{") {
+		// Built-in tools use python call syntax (brave_search.call(query="...")), which
+		// has no counterpart in our tool registry
+		return nil, nil
+	}
+
+	values, err := decodeJSONValues(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tool call: %v", err)
+	}
+
+	var toolCalls []openai.ToolCall
+	for _, value := range values {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("tool call %d is not an object", len(toolCalls)+1)
+		}
+		toolCall, err := toolCallFromObject(len(toolCalls)+1, object)
+		if err != nil {
+			return nil, err
+		}
+		toolCalls = append(toolCalls, toolCall)
+	}
+
+	return toolCalls, nil
+}
+
+// looksLikeBareCall reports whether the whole message is a JSON call object; prose that
+// merely contains JSON must not be executed
+func (a *Llama3Adapter) looksLikeBareCall(content string) bool {
+	trimmed := strings.TrimSpace(content)
+	for _, token := range llamaEndTokens {
+		trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, token))
+	}
+	return strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") &&
+		strings.Contains(trimmed, `"name"`) &&
+		(strings.Contains(trimmed, `"parameters"`) || strings.Contains(trimmed, `"arguments"`))
+}
+
+// ShouldFallbackToStandard returns true to try standard parsing if JSON parsing fails
+func (a *Llama3Adapter) ShouldFallbackToStandard() bool {
+	return true
+}
+
+// GetConfigRecommendations returns Llama 3-specific configuration recommendations
+func (a *Llama3Adapter) GetConfigRecommendations(modelName string) ModelConfigRecommendations {
+	// Llama 3 was not trained for parallel calls and degrades when offered them
+	parallelToolCalls := false
+
+	return ModelConfigRecommendations{
+		ChatTemplateKwargs: map[string]interface{}{
+			// vLLM serves Llama 3.1+ with --tool-call-parser llama3_json
+			"tool_call_parser": "llama3_json",
+		},
+		ParallelToolCalls: &parallelToolCalls,
+		DebugMessage:      fmt.Sprintf("Applied Llama 3 optimizations (llama3_json parser, sequential tools) for model: %s", modelName),
+		HasOptimizations:  true,
+	}
+}
@@ -0,0 +1,167 @@
+package adapters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	mistralToolCallsToken = "[TOOL_CALLS]"
+	mistralArgsToken      = "[ARGS]"
+	mistralCallIDToken    = "[CALL_ID]"
+)
+
+// mistralCallIDPattern matches the 9-character alphanumeric ids Mistral requires on tool calls
+var mistralCallIDPattern = regexp.MustCompile(`^[A-Za-z0-9]{9}$`)
+
+// MistralAdapter handles Mistral family models that emit [TOOL_CALLS] in plain content.
+// Older tokenizers follow it with a JSON array of calls; v11+ (Devstral, Magistral,
+// Mistral Small 3.2) emit [TOOL_CALLS]name[ARGS]{json} for each call instead.
+type MistralAdapter struct{}
+
+// NewMistralAdapter creates a new Mistral adapter
+func NewMistralAdapter() *MistralAdapter {
+	return &MistralAdapter{}
+}
+
+// GetName returns the adapter name
+func (a *MistralAdapter) GetName() string {
+	return "mistral"
+}
+
+// CanHandle determines if this adapter can handle the given model
+func (a *MistralAdapter) CanHandle(modelName string) bool {
+	return containsAny(modelName, "mistral", "mixtral", "codestral", "devstral", "magistral", "ministral", "pixtral")
+}
+
+// ParseToolCalls extracts tool calls following the [TOOL_CALLS] token
+func (a *MistralAdapter) ParseToolCalls(content string) ([]openai.ToolCall, error) {
+	start := strings.Index(content, mistralToolCallsToken)
+	if start < 0 {
+		return nil, nil // No tool calls found
+	}
+
+	var toolCalls []openai.ToolCall
+	segments := strings.Split(content[start:], mistralToolCallsToken)
+	for _, segment := range segments {
+		segment = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(segment), "</s>"))
+		if segment == "" {
+			continue
+		}
+
+		var (
+			parsed []openai.ToolCall
+			err    error
+		)
+		if strings.HasPrefix(segment, "[") || strings.HasPrefix(segment, "{") {
+			parsed, err = a.parseJSONSegment(segment, len(toolCalls))
+		} else {
+			parsed, err = a.parseArgsSegment(segment, len(toolCalls))
+		}
+		if err != nil {
+			return nil, err
+		}
+		toolCalls = append(toolCalls, parsed...)
+	}
+
+	return toolCalls, nil
+}
+
+// parseJSONSegment parses the pre-v11 form: [TOOL_CALLS][{"name": ..., "arguments": {...}}, ...]
+func (a *MistralAdapter) parseJSONSegment(segment string, offset int) ([]openai.ToolCall, error) {
+	values, err := decodeJSONValues(segment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tool call %d: %v", offset+1, err)
+	}
+
+	var objects []map[string]interface{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				object, ok := item.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("tool call %d is not an object", offset+len(objects)+1)
+				}
+				objects = append(objects, object)
+			}
+		case map[string]interface{}:
+			objects = append(objects, v)
+		default:
+			return nil, fmt.Errorf("tool call %d is not an object", offset+len(objects)+1)
+		}
+	}
+
+	var toolCalls []openai.ToolCall
+	for _, object := range objects {
+		index := offset + len(toolCalls) + 1
+		toolCall, err := toolCallFromObject(index, object)
+		if err != nil {
+			return nil, err
+		}
+		toolCall.ID = a.callID(toolCall.ID, index)
+		toolCalls = append(toolCalls, toolCall)
+	}
+	return toolCalls, nil
+}
+
+// parseArgsSegment parses the v11+ form: name[ARGS]{json}, optionally name[CALL_ID]id[ARGS]{json}
+func (a *MistralAdapter) parseArgsSegment(segment string, offset int) ([]openai.ToolCall, error) {
+	index := offset + 1
+
+	argsAt := strings.Index(segment, mistralArgsToken)
+	if argsAt < 0 {
+		return nil, fmt.Errorf("tool call %d has no %s marker", index, mistralArgsToken)
+	}
+
+	name := segment[:argsAt]
+	id := ""
+	if idAt := strings.Index(name, mistralCallIDToken); idAt >= 0 {
+		id = strings.TrimSpace(name[idAt+len(mistralCallIDToken):])
+		name = name[:idAt]
+	}
+
+	values, err := decodeJSONValues(segment[argsAt+len(mistralArgsToken):])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse arguments for tool call %d: %v", index, err)
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("tool call %d has %d argument objects, expected 1", index, len(values))
+	}
+
+	toolCall, err := newToolCall(index, id, strings.TrimSpace(name), values[0])
+	if err != nil {
+		return nil, err
+	}
+	toolCall.ID = a.callID(toolCall.ID, index)
+	return []openai.ToolCall{toolCall}, nil
+}
+
+// callID keeps valid Mistral ids and otherwise generates one, since Mistral chat templates
+// reject tool results whose id is not exactly 9 alphanumeric characters
+func (a *MistralAdapter) callID(id string, index int) string {
+	if mistralCallIDPattern.MatchString(id) {
+		return id
+	}
+	return fmt.Sprintf("call%05d", index)
+}
+
+// ShouldFallbackToStandard returns true to try standard parsing if token parsing fails
+func (a *MistralAdapter) ShouldFallbackToStandard() bool {
+	return true
+}
+
+// GetConfigRecommendations returns Mistral-specific configuration recommendations
+func (a *MistralAdapter) GetConfigRecommendations(modelName string) ModelConfigRecommendations {
+	return ModelConfigRecommendations{
+		ChatTemplateKwargs: map[string]interface{}{
+			// vLLM serves Mistral models with --tool-call-parser mistral
+			"tool_call_parser": "mistral",
+		},
+		DebugMessage:     fmt.Sprintf("Applied Mistral optimizations (mistral parser, 9-char call ids) for model: %s", modelName),
+		HasOptimizations: true,
+	}
+}
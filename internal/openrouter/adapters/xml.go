@@ -0,0 +1,339 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Call shapes recognized by the XML adapter. Tag names may carry a namespace prefix
+// (e.g. <ns:invoke>), and the closing tag must match the opening one.
+var (
+	// <invoke name="tool">, <function_call name="tool">, <tool_use name="tool">, <tool_call name="tool">
+	xmlNamedCallPattern = regexp.MustCompile(`(?s)<((?:[\w-]+:)?(?:invoke|function_call|tool_use|tool_call))\s+(?:name|tool)\s*=\s*"([^"]+)"\s*>(.*?)</(?:[\w-]+:)?(?:invoke|function_call|tool_use|tool_call)>`)
+	// Qwen3-Coder: <function=tool> ... </function>
+	xmlFunctionEqualsPattern = regexp.MustCompile(`(?s)<function=([^>\s]+)\s*>(.*?)</function>`)
+
+	// <parameter name="key">, <param name="key">, <arg name="key">, <argument name="key">
+	xmlNamedParamPattern = regexp.MustCompile(`(?s)<((?:[\w-]+:)?(?:parameter|param|arg|argument))\s+name\s*=\s*"([^"]+)"\s*>(.*?)</(?:[\w-]+:)?(?:parameter|param|arg|argument)>`)
+	// Qwen3-Coder: <parameter=key> ... </parameter>
+	xmlParamEqualsPattern = regexp.MustCompile(`(?s)<parameter=([^>\s]+)\s*>(.*?)</parameter>`)
+
+	// <item>value</item> children spell out array parameters
+	xmlItemPattern = regexp.MustCompile(`(?s)<item>(.*?)</item>`)
+)
+
+// XMLCall is a tool call parsed from XML with its parameters still as raw text
+type XMLCall struct {
+	Function   string
+	Parameters map[string]string
+}
+
+// XMLAdapter parses XML function calls written in plain content by models without
+// native tool calling, and coerces their string parameters to the types declared in
+// each tool's JSON schema. Unlike the model adapters it applies to every model, so it
+// is used as a fallback rather than registered in the AdapterRegistry.
+type XMLAdapter struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+// NewXMLAdapter creates an XML adapter that knows the given tools
+func NewXMLAdapter(tools []openai.Tool) *XMLAdapter {
+	adapter := &XMLAdapter{schemas: make(map[string]*jsonschema.Schema)}
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		adapter.schemas[tool.Function.Name] = toSchema(tool.Function.Parameters)
+	}
+	return adapter
+}
+
+// toSchema returns tool parameters as a *jsonschema.Schema, round-tripping through JSON
+// for tools declared with a plain map
+func toSchema(parameters interface{}) *jsonschema.Schema {
+	if schema, ok := parameters.(*jsonschema.Schema); ok {
+		return schema
+	}
+	if parameters == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return nil
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil
+	}
+	return &schema
+}
+
+// GetName returns the adapter name
+func (a *XMLAdapter) GetName() string {
+	return "xml"
+}
+
+// CanHandle returns true for every model; XML calls are recognized by content alone
+func (a *XMLAdapter) CanHandle(modelName string) bool {
+	return true
+}
+
+// ParseXMLCalls extracts XML tool calls from content in the order they appear
+func (a *XMLAdapter) ParseXMLCalls(content string) []XMLCall {
+	type positioned struct {
+		at   int
+		call XMLCall
+	}
+	var found []positioned
+
+	for _, match := range xmlNamedCallPattern.FindAllStringSubmatchIndex(content, -1) {
+		found = append(found, positioned{at: match[0], call: XMLCall{
+			Function:   strings.TrimSpace(content[match[4]:match[5]]),
+			Parameters: parseXMLParams(content[match[6]:match[7]]),
+		}})
+	}
+	for _, match := range xmlFunctionEqualsPattern.FindAllStringSubmatchIndex(content, -1) {
+		found = append(found, positioned{at: match[0], call: XMLCall{
+			Function:   strings.TrimSpace(content[match[2]:match[3]]),
+			Parameters: parseXMLParams(content[match[4]:match[5]]),
+		}})
+	}
+
+	// Only fall back to <tool_name><param>value</param></tool_name> when nothing else
+	// matched, since parameter tags inside the shapes above could otherwise collide
+	if len(found) == 0 {
+		for name := range a.schemas {
+			pattern := regexp.MustCompile(`(?s)<` + regexp.QuoteMeta(name) + `>(.*?)</` + regexp.QuoteMeta(name) + `>`)
+			for _, match := range pattern.FindAllStringSubmatchIndex(content, -1) {
+				found = append(found, positioned{at: match[0], call: XMLCall{
+					Function:   name,
+					Parameters: a.parseChildParams(name, content[match[2]:match[3]]),
+				}})
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].at < found[j].at })
+
+	calls := make([]XMLCall, 0, len(found))
+	for _, f := range found {
+		calls = append(calls, f.call)
+	}
+	return calls
+}
+
+// parseXMLParams collects named parameter elements from a call body
+func parseXMLParams(body string) map[string]string {
+	params := make(map[string]string)
+	for _, match := range xmlNamedParamPattern.FindAllStringSubmatch(body, -1) {
+		params[match[2]] = match[3]
+	}
+	for _, match := range xmlParamEqualsPattern.FindAllStringSubmatch(body, -1) {
+		params[match[1]] = match[2]
+	}
+	return params
+}
+
+// parseChildParams collects <property>value</property> children declared in the tool's schema
+func (a *XMLAdapter) parseChildParams(toolName, body string) map[string]string {
+	params := make(map[string]string)
+	schema := a.schemas[toolName]
+	if schema == nil {
+		return params
+	}
+	for property := range schema.Properties {
+		pattern := regexp.MustCompile(`(?s)<` + regexp.QuoteMeta(property) + `>(.*?)</` + regexp.QuoteMeta(property) + `>`)
+		if match := pattern.FindStringSubmatch(body); match != nil {
+			params[property] = match[1]
+		}
+	}
+	return params
+}
+
+// CoerceParameters converts raw XML parameter text to the types declared in the tool's schema.
+// Parameters the schema does not declare are passed through as strings.
+func (a *XMLAdapter) CoerceParameters(toolName string, raw map[string]string) (map[string]interface{}, error) {
+	schema := a.schemas[toolName]
+
+	params := make(map[string]interface{}, len(raw))
+	for name, value := range raw {
+		var property *jsonschema.Schema
+		if schema != nil {
+			property = schema.Properties[name]
+		}
+
+		coerced, err := coerceValue(value, property)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s of %s: %w", name, toolName, err)
+		}
+		params[name] = coerced
+	}
+	return params, nil
+}
+
+// coerceValue converts text to the type a schema declares, trying anyOf/oneOf options in order
+func coerceValue(value string, schema *jsonschema.Schema) (interface{}, error) {
+	if schema == nil {
+		return trimValueNewlines(value), nil
+	}
+
+	types := schema.Types
+	if schema.Type != "" {
+		types = []string{schema.Type}
+	}
+
+	if len(types) == 0 {
+		options := append(append([]*jsonschema.Schema{}, schema.AnyOf...), schema.OneOf...)
+		for _, option := range options {
+			if coerced, err := coerceValue(value, option); err == nil {
+				return coerced, nil
+			}
+		}
+		return trimValueNewlines(value), nil
+	}
+
+	var lastErr error
+	for _, typ := range types {
+		coerced, err := coerceToType(value, typ, schema)
+		if err == nil {
+			return coerced, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// coerceToType converts text to a single JSON schema type
+func coerceToType(value, typ string, schema *jsonschema.Schema) (interface{}, error) {
+	trimmed := strings.TrimSpace(value)
+
+	switch typ {
+	case "string":
+		return trimValueNewlines(value), nil
+	case "integer":
+		// Numbers stay float64, as if the arguments had been decoded from JSON; accept 3.0 but not 3.5
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil || f != math.Trunc(f) {
+			return nil, fmt.Errorf("expected integer, got %q", trimmed)
+		}
+		return f, nil
+	case "number":
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected number, got %q", trimmed)
+		}
+		return f, nil
+	case "boolean":
+		switch strings.ToLower(trimmed) {
+		case "true", "yes", "1":
+			return true, nil
+		case "false", "no", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("expected boolean, got %q", trimmed)
+	case "null":
+		if trimmed == "" || trimmed == "null" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("expected null, got %q", trimmed)
+	case "array":
+		return coerceArray(trimmed, schema.Items)
+	case "object":
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &object); err != nil {
+			return nil, fmt.Errorf("expected JSON object: %v", err)
+		}
+		return object, nil
+	default:
+		return trimValueNewlines(value), nil
+	}
+}
+
+// coerceArray accepts a JSON array, <item> children, one item per line, or a comma-separated list
+func coerceArray(value string, items *jsonschema.Schema) (interface{}, error) {
+	if strings.HasPrefix(value, "[") {
+		var array []interface{}
+		if err := json.Unmarshal([]byte(value), &array); err != nil {
+			return nil, fmt.Errorf("expected JSON array: %v", err)
+		}
+		return array, nil
+	}
+
+	var parts []string
+	if matches := xmlItemPattern.FindAllStringSubmatch(value, -1); len(matches) > 0 {
+		for _, match := range matches {
+			parts = append(parts, match[1])
+		}
+	} else if strings.Contains(value, "\n") {
+		parts = strings.Split(value, "\n")
+	} else {
+		parts = strings.Split(value, ",")
+	}
+
+	array := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		coerced, err := coerceValue(part, items)
+		if err != nil {
+			return nil, fmt.Errorf("array item: %w", err)
+		}
+		array = append(array, coerced)
+	}
+	return array, nil
+}
+
+// trimValueNewlines drops the single newline models put after an opening tag and before
+// a closing one, keeping any other whitespace that may be meaningful in file content
+func trimValueNewlines(value string) string {
+	value = strings.TrimPrefix(value, "\r\n")
+	value = strings.TrimPrefix(value, "\n")
+	value = strings.TrimSuffix(value, "\n")
+	value = strings.TrimSuffix(value, "\r")
+	return value
+}
+
+// ParseToolCalls extracts XML calls as OpenAI tool calls with schema-typed JSON arguments
+func (a *XMLAdapter) ParseToolCalls(content string) ([]openai.ToolCall, error) {
+	calls := a.ParseXMLCalls(content)
+	if len(calls) == 0 {
+		return nil, nil // No tool calls found
+	}
+
+	toolCalls := make([]openai.ToolCall, 0, len(calls))
+	for i, call := range calls {
+		params, err := a.CoerceParameters(call.Function, call.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		toolCall, err := newToolCall(i+1, "", call.Function, params)
+		if err != nil {
+			return nil, err
+		}
+		toolCalls = append(toolCalls, toolCall)
+	}
+	return toolCalls, nil
+}
+
+// ShouldFallbackToStandard returns true to try standard parsing if XML parsing fails
+func (a *XMLAdapter) ShouldFallbackToStandard() bool {
+	return true
+}
+
+// GetConfigRecommendations returns no recommendations; the XML format is model independent
+func (a *XMLAdapter) GetConfigRecommendations(modelName string) ModelConfigRecommendations {
+	return ModelConfigRecommendations{
+		HasOptimizations: false,
+	}
+}
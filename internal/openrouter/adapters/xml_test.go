@@ -0,0 +1,187 @@
+package adapters
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/sashabaranov/go-openai"
+)
+
+// xmlTestTools mirrors the shapes of the file and exec tool schemas
+func xmlTestTools() []openai.Tool {
+	return []openai.Tool{
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+			Name: "read_file",
+			Parameters: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"path":       {Type: "string"},
+					"start_line": {Type: "integer"},
+				},
+			},
+		}},
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+			Name: "run_command",
+			Parameters: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"command": {Type: "string"},
+					"args":    {Type: "array", Items: &jsonschema.Schema{Type: "string"}},
+					"timeout": {Types: []string{"null", "number"}},
+					"shell":   {Type: "boolean"},
+					"env":     {Type: "object"},
+				},
+			},
+		}},
+		// Tools registered from MCP servers arrive as plain maps
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+			Name: "search_nodes",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"limit": map[string]interface{}{"type": "integer"},
+				},
+			},
+		}},
+	}
+}
+
+func TestParseXMLCallShapes(t *testing.T) {
+	adapter := NewXMLAdapter(xmlTestTools())
+
+	tests := []struct {
+		name    string
+		content string
+		want    []XMLCall
+	}{
+		{
+			name: "function_calls invoke",
+			content: `Let me look.
+<function_calls>
+<invoke name="read_file">
+<parameter name="path">main.go</parameter>
+<parameter name="start_line">10</parameter>
+</invoke>
+</function_calls>`,
+			want: []XMLCall{{Function: "read_file", Parameters: map[string]string{"path": "main.go", "start_line": "10"}}},
+		},
+		{
+			name: "namespaced invoke",
+			content: "<tools:function_calls>\n" +
+				`<tools:invoke name="read_file"><tools:parameter name="path">a.go</tools:parameter></tools:invoke>` + "\n" +
+				`<tools:invoke name="run_command"><tools:parameter name="command">ls</tools:parameter></tools:invoke>` + "\n" +
+				"</tools:function_calls>",
+			want: []XMLCall{
+				{Function: "read_file", Parameters: map[string]string{"path": "a.go"}},
+				{Function: "run_command", Parameters: map[string]string{"command": "ls"}},
+			},
+		},
+		{
+			name:    "tool_use with args",
+			content: `<tool_use tool="run_command"><arg name="command">go test</arg><argument name="shell">true</argument></tool_use>`,
+			want:    []XMLCall{{Function: "run_command", Parameters: map[string]string{"command": "go test", "shell": "true"}}},
+		},
+		{
+			name:    "qwen3 coder",
+			content: "<tool_call>\n<function=read_file>\n<parameter=path>\nREADME.md\n</parameter>\n</function>\n</tool_call>",
+			want:    []XMLCall{{Function: "read_file", Parameters: map[string]string{"path": "\nREADME.md\n"}}},
+		},
+		{
+			name:    "tool name tags",
+			content: "<read_file>\n<path>go.mod</path>\n</read_file>",
+			want:    []XMLCall{{Function: "read_file", Parameters: map[string]string{"path": "go.mod"}}},
+		},
+		{
+			name:    "unknown tool name tags",
+			content: "<delete_everything><path>/</path></delete_everything>",
+			want:    []XMLCall{},
+		},
+		{
+			name:    "prose",
+			content: "The answer is 42.",
+			want:    []XMLCall{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := adapter.ParseXMLCalls(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoerceParameters(t *testing.T) {
+	adapter := NewXMLAdapter(xmlTestTools())
+
+	got, err := adapter.CoerceParameters("run_command", map[string]string{
+		"command": "\ngo test ./...\n",
+		"args":    "-v, -race",
+		"timeout": " 30.5 ",
+		"shell":   "False",
+		"env":     `{"GOFLAGS": "-mod=mod"}`,
+		"extra":   "kept",
+	})
+	if err != nil {
+		t.Fatalf("Coercion failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"command": "go test ./...",
+		"args":    []interface{}{"-v", "-race"},
+		"timeout": 30.5,
+		"shell":   false,
+		"env":     map[string]interface{}{"GOFLAGS": "-mod=mod"},
+		"extra":   "kept",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Integers decode as float64, the same as JSON arguments
+	got, err = adapter.CoerceParameters("search_nodes", map[string]string{"limit": "5"})
+	if err != nil || got["limit"] != float64(5) {
+		t.Errorf("expected limit 5 from map schema, got %#v (err=%v)", got["limit"], err)
+	}
+
+	arrays := map[string][]interface{}{
+		`["a", "b"]`:                   {"a", "b"},
+		"<item>a</item><item>b</item>": {"a", "b"},
+		"a\nb\n":                       {"a", "b"},
+	}
+	for raw, want := range arrays {
+		got, err := adapter.CoerceParameters("run_command", map[string]string{"args": raw})
+		if err != nil || !reflect.DeepEqual(got["args"], want) {
+			t.Errorf("%q: expected %v, got %#v (err=%v)", raw, want, got["args"], err)
+		}
+	}
+
+	for _, raw := range []map[string]string{{"start_line": "ten"}, {"start_line": "3.5"}} {
+		if _, err := adapter.CoerceParameters("read_file", raw); err == nil {
+			t.Errorf("expected %v to be rejected", raw)
+		}
+	}
+}
+
+func TestXMLAdapterParseToolCalls(t *testing.T) {
+	adapter := NewXMLAdapter(xmlTestTools())
+
+	toolCalls, err := adapter.ParseToolCalls(`<tool_call name="read_file"><param name="path">x.go</param><param name="start_line">3</param></tool_call>`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(toolCalls) != 1 || toolCalls[0].ID != "call_1" || toolCalls[0].Function.Name != "read_file" {
+		t.Fatalf("Unexpected tool calls: %+v", toolCalls)
+	}
+
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(toolCalls[0].Function.Arguments), &args); err != nil {
+		t.Fatalf("Arguments are not JSON: %v", err)
+	}
+	if args["path"] != "x.go" || args["start_line"] != float64(3) {
+		t.Errorf("Unexpected arguments: %s", toolCalls[0].Function.Arguments)
+	}
+}
@@ -3,12 +3,18 @@ package openrouter
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"gorka/internal/session"
+	"gorka/internal/tokenizer"
 	"gorka/internal/tools"
+	"gorka/internal/tools/media"
 	"gorka/internal/types"
+	"gorka/internal/usage"
 	"gorka/internal/utils"
 	"github.com/sashabaranov/go-openai"
 )
@@ -26,6 +32,10 @@ type AgentSpawner struct {
 	sessionManager       *session.SessionManager
 	toolsManager         *tools.ToolsManager
 	behavioralEngine     BehavioralEngine
+	llmOverrides         *utils.LLMOverrides
+	ledger               *usage.Ledger
+	// runningSessions holds the IDs of sessions with a conversation loop in progress
+	runningSessions sync.Map
 }
 
 // NewAgentSpawner creates a new agent spawner with OpenRouter integration
@@ -48,23 +58,157 @@ func NewAgentSpawner(toolsManager *tools.ToolsManager, behavioralEngine Behavior
 		coreSystemPrinciples = ""
 	}
 
+	// Workspace overrides are optional; a broken file should not take the server down
+	llmOverrides, err := utils.LoadLLMOverrides(config.Workspace)
+	if err != nil {
+		fmt.Printf("WARNING: Ignoring LLM overrides: %v\n", err)
+		llmOverrides = nil
+	}
+
+	// A broken price table only loses cost figures; token counts are still recorded
+	prices, err := usage.LoadPriceTable(config.Workspace)
+	if err != nil {
+		fmt.Printf("WARNING: Using default model prices: %v\n", err)
+		prices = usage.DefaultPriceTable()
+	}
+
+	// Replayed calls cost nothing, so keep them out of the real daily totals
+	usageDir := filepath.Join(config.Workspace, ".gorka", "usage")
+	if config.CassetteMode == CassetteModeReplay {
+		usageDir = filepath.Join(usageDir, "replay")
+	}
+
+	ledger, err := usage.NewLedger(usageDir, prices, config.RunBudgetUSD, config.DailyBudgetUSD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+
 	return &AgentSpawner{
 		client:               client,
 		coreSystemPrinciples: coreSystemPrinciples,
 		sessionManager:       session.NewSessionManagerWithConfig(config),
 		toolsManager:         toolsManager,
 		behavioralEngine:     behavioralEngine,
+		llmOverrides:         llmOverrides,
+		ledger:               ledger,
 	}, nil
 }
 
+// Ledger returns the usage ledger that every agent call is charged to
+func (s *AgentSpawner) Ledger() *usage.Ledger {
+	return s.ledger
+}
+
+// AgentRunResult is the outcome of a spawned agent run
+type AgentRunResult struct {
+	Response      *openai.ChatCompletionResponse
+	SessionID     string
+	ModelAttempts []ModelAttempt
+	// Usage totals every LLM call made by this agent, not just the final response
+	Usage usage.Totals
+	// StructuredOutput is the final answer decoded and validated against the matrix output schema
+	StructuredOutput map[string]interface{}
+	// OutputError explains why the final answer still failed the schema after repairs ran out
+	OutputError string
+	// StopReason says why the conversation ended: completed, a loop limit, or the budget
+	StopReason string
+	// ToolCalls records every tool call made during the run, in the order they were made
+	ToolCalls []types.ToolCallRecord
+}
+
+// FallbackUsed reports whether any call in the run was answered by a model other than the first one tried
+func (r *AgentRunResult) FallbackUsed() bool {
+	if len(r.ModelAttempts) == 0 {
+		return false
+	}
+	primary := r.ModelAttempts[0].Model
+	for _, attempt := range r.ModelAttempts {
+		if attempt.Outcome == "success" && attempt.Model != primary {
+			return true
+		}
+	}
+	return false
+}
+
 // SpawnAgent spawns an OpenRouter LLM agent with automatic session management
 func (s *AgentSpawner) SpawnAgent(matrix *types.BehavioralMatrix, userInput string) (*openai.ChatCompletionResponse, error) {
+	result, err := s.SpawnAgentWithContext(context.Background(), matrix, userInput)
+	if err != nil {
+		return nil, err
+	}
+	return result.Response, nil
+}
+
+// SpawnAgentWithContext spawns an agent using ctx for API calls and progress reporting
+func (s *AgentSpawner) SpawnAgentWithContext(ctx context.Context, matrix *types.BehavioralMatrix, userInput string) (*AgentRunResult, error) {
 	// Create a new session for this agent execution
 	agentSession, err := s.sessionManager.CreateSession(matrix.AgentID, matrix, s.coreSystemPrinciples)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
-	
+	if err := s.claimSession(agentSession.ID); err != nil {
+		return nil, err
+	}
+	defer s.runningSessions.Delete(agentSession.ID)
+
+	return s.runSession(ctx, agentSession, matrix, userInput)
+}
+
+// ContinueAgentWithContext appends userInput to an existing session, active, completed or
+// interrupted, and runs the agent again with the files and tool results it already has in its history
+func (s *AgentSpawner) ContinueAgentWithContext(ctx context.Context, sessionID string, userInput string) (*AgentRunResult, error) {
+	existing, exists := s.sessionManager.GetSession(sessionID)
+	if !exists {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+
+	// Sessions loaded from disk have no matrix, so look it up again by agent ID
+	matrix := existing.Matrix
+	if matrix == nil {
+		var err error
+		if matrix, err = s.lookupMatrix(existing.AgentID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.claimSession(sessionID); err != nil {
+		return nil, err
+	}
+	defer s.runningSessions.Delete(sessionID)
+
+	agentSession, err := s.sessionManager.ReopenSession(sessionID, matrix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen session: %w", err)
+	}
+	fmt.Printf("DEBUG: Continuing session %s with %d messages\n", sessionID, len(agentSession.Messages))
+
+	return s.runSession(ctx, agentSession, matrix, userInput)
+}
+
+// claimSession marks a session as running so a second caller cannot interleave messages with it
+func (s *AgentSpawner) claimSession(sessionID string) error {
+	if _, running := s.runningSessions.LoadOrStore(sessionID, true); running {
+		return fmt.Errorf("session %s is already running", sessionID)
+	}
+	return nil
+}
+
+// lookupMatrix finds the behavioral matrix of an agent, preferring the engine's loaded matrices
+func (s *AgentSpawner) lookupMatrix(agentID string) (*types.BehavioralMatrix, error) {
+	if s.behavioralEngine != nil {
+		if matrix, ok := s.behavioralEngine.GetBehavioralMatrices()[agentID]; ok {
+			return matrix, nil
+		}
+	}
+	matrix, err := utils.GetBehavioralMatrixFromEmbedded(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load behavioral matrix for agent %s: %w", agentID, err)
+	}
+	return matrix, nil
+}
+
+// runSession adds the user message to a session and runs the conversation loop until it stops
+func (s *AgentSpawner) runSession(ctx context.Context, agentSession *session.AgentSession, matrix *types.BehavioralMatrix, userInput string) (*AgentRunResult, error) {
 	// Add user message to session
 	userMessage := openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleUser,
@@ -74,68 +218,183 @@ func (s *AgentSpawner) SpawnAgent(matrix *types.BehavioralMatrix, userInput stri
 		return nil, fmt.Errorf("failed to add user message: %w", err)
 	}
 	
+	// Resolve per-agent model and sampling settings
+	settings := s.llmOverrides.ResolveAgentSettings(matrix)
+	if settings.Model != "" {
+		fmt.Printf("DEBUG: Agent %s using model override: %s\n", matrix.AgentID, settings.Model)
+	}
+
+	// Ask for the output shape the matrix declares
+	output, err := newStructuredOutput(matrix)
+	if err != nil {
+		fmt.Printf("WARNING: Agent %s output will not be validated: %v\n", matrix.AgentID, err)
+	}
+	if output != nil {
+		ctx = withStructuredOutput(ctx, output)
+	}
+
 	// Execute conversation loop until completion (no more tool calls)
-	response, err := s.executeConversationLoop(agentSession)
+	result, err := s.executeConversationLoop(ctx, agentSession, settings, output)
 	if err != nil {
+		if errors.Is(err, usage.ErrBudgetExceeded) {
+			// Keep what the agent produced before the cap so the caller can report it
+			s.recordStopReason(agentSession.ID, result.StopReason)
+			s.sessionManager.CompleteSession(agentSession.ID)
+			return result, err
+		}
+		// Keep the session for a resume from its last complete tool exchange
+		if interruptErr := s.sessionManager.InterruptSession(agentSession.ID, fmt.Sprintf("run failed: %v", err)); interruptErr != nil {
+			fmt.Printf("WARNING: Failed to mark session %s as interrupted: %v\n", agentSession.ID, interruptErr)
+		}
 		return nil, err
 	}
 	
 	// Mark session as completed and clean it up
+	s.recordStopReason(agentSession.ID, result.StopReason)
 	s.sessionManager.CompleteSession(agentSession.ID)
 	
-	return response, nil
+	return result, nil
+}
+
+// recordStopReason stores why the conversation ended on its session
+func (s *AgentSpawner) recordStopReason(sessionID, reason string) {
+	if err := s.sessionManager.SetStopReason(sessionID, reason); err != nil {
+		fmt.Printf("WARNING: Failed to record stop reason for session %s: %v\n", sessionID, err)
+	}
+}
+
+// loopLimits returns the limits applied to each agent conversation
+func (s *AgentSpawner) loopLimits() LoopLimits {
+	if s.client == nil || s.client.config == nil {
+		return LoopLimits{}
+	}
+	return loopLimitsFromConfig(s.client.config)
+}
+
+// contextBudget sizes the conversation for the agent's model, holding back room for the
+// tool schemas sent with every request and for the reply
+func (s *AgentSpawner) contextBudget(settings *types.LLMSettings) session.ContextBudget {
+	request := s.client.buildRequest(nil, settings)
+	tools := tokenizer.CountTools(tokenizer.ForModel(request.Model), request.Tools)
+	return session.ContextBudget{Model: request.Model, Reserved: tools + request.MaxCompletionTokens}
 }
 
 // executeConversationLoop handles the full conversation including tool calls
-func (s *AgentSpawner) executeConversationLoop(agentSession *session.AgentSession) (*openai.ChatCompletionResponse, error) {
-	var lastResponse *openai.ChatCompletionResponse
+func (s *AgentSpawner) executeConversationLoop(ctx context.Context, agentSession *session.AgentSession, settings *types.LLMSettings, output *structuredOutput) (*AgentRunResult, error) {
+	result := &AgentRunResult{SessionID: agentSession.ID, StopReason: StopReasonCompleted}
+	run := usage.RunFromContext(ctx)
+	repairs := 0
+	guard := newLoopGuard(s.loopLimits())
+	budget := s.contextBudget(settings)
+	vision := s.client.supportsVision(budget.Model, settings)
+	if threshold := s.client.config.CompactionThreshold; threshold > 0 {
+		budget.Compaction = s.compactionStrategy(agentSession, settings, run, result)
+		budget.CompactAt = threshold
+	}
 	
 	for {
-		// Get filtered session messages to prevent API token limits
-		messages, err := s.sessionManager.GetFilteredSessionMessages(agentSession.ID)
+		// Stop once the caller has gone away, e.g. the MCP client cancelled the request
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Stop before the next call once a budget is spent; the last response is the partial result
+		if s.ledger != nil {
+			if err := s.ledger.CheckBudget(run); err != nil {
+				result.StopReason = StopReasonBudgetExceeded
+				return result, err
+			}
+		}
+
+		// Fold older turns into working memory before they crowd out the newest ones
+		if _, err := s.sessionManager.CompactSession(ctx, agentSession.ID, budget); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			fmt.Printf("WARNING: Falling back to heuristic context filtering for session %s: %v\n", agentSession.ID, err)
+			budget.Compaction = nil
+		}
+
+		// Get session messages filtered to fit the model's context window
+		messages, err := s.sessionManager.GetFilteredSessionMessages(agentSession.ID, budget)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get filtered session messages: %w", err)
 		}
 		
-		// Execute via OpenRouter with current session history
-		response, err := s.client.CreateChatCompletion(context.Background(), messages)
+		// Execute via OpenRouter with current session history; the wrap-up turn may not call tools
+		turnCtx := ctx
+		if guard.wrapUp != "" {
+			turnCtx = withToolsDisabled(ctx)
+		}
+		completion, err := s.client.CreateChatCompletionWithSettings(turnCtx, messages, settings)
 		if err != nil {
+			// Failed chains can still have billed calls (empty responses)
+			var fallbackErr *FallbackError
+			if errors.As(err, &fallbackErr) {
+				s.recordUsage(run, agentSession, fallbackErr.Attempts, result)
+			}
 			return nil, err
 		}
 		
-		lastResponse = response
+		response := completion.Response
+		result.Response = response
+		result.ModelAttempts = append(result.ModelAttempts, completion.Attempts...)
+		s.recordUsage(run, agentSession, completion.Attempts, result)
 		
 		if len(response.Choices) == 0 {
 			return nil, fmt.Errorf("OpenRouter returned response with no choices")
 		}
 		
 		choice := response.Choices[0]
+
+		// Count every call the model made this turn, native or written as XML
+		toolCalls := choice.Message.ToolCalls
+		for _, xmlToolCall := range completion.XMLToolCalls {
+			toolCalls = append(toolCalls, xmlToolCall.toolCall())
+		}
+		limit := guard.record(toolCalls)
 		
 		// Add assistant response to session
 		assistantMessage := openai.ChatCompletionMessage{
-			Role:    choice.Message.Role,
-			Content: choice.Message.Content,
+			Role:             choice.Message.Role,
+			Content:          choice.Message.Content,
+			ReasoningContent: choice.Message.ReasoningContent,
 		}
 		
 		// Handle tool calls if present
-		if len(choice.Message.ToolCalls) > 0 {
-			// Tool calls are already properly parsed by the OpenRouter client adapter system
+		if len(toolCalls) > 0 {
+			// Native and adapter calls are kept on the message; XML calls stay in its content
 			assistantMessage.ToolCalls = choice.Message.ToolCalls
 			if err := s.sessionManager.AddMessage(agentSession.ID, assistantMessage); err != nil {
 				return nil, fmt.Errorf("failed to add assistant message: %w", err)
 			}
-			
-			// Execute tool calls and add results
-			for _, toolCall := range choice.Message.ToolCalls {
-				toolResult := s.executeToolCall(toolCall)
-				toolMessage := openai.ChatCompletionMessage{
-					Role:       openai.ChatMessageRoleTool,
-					Content:    toolResult,
-					ToolCallID: toolCall.ID,
+
+			if guard.wrapUp != "" {
+				// The agent ignored the request to finish; drop its calls so nothing downstream runs them,
+				// and answer them so the saved history can be continued
+				fmt.Printf("WARNING: Agent %s still calling tools after wrap-up (%s), stopping\n", agentSession.AgentID, guard.wrapUp)
+				if err := s.skipToolCalls(agentSession, choice.Message.ToolCalls, guard.wrapUp); err != nil {
+					return nil, err
 				}
-				if err := s.sessionManager.AddMessage(agentSession.ID, toolMessage); err != nil {
-					return nil, fmt.Errorf("failed to add tool message: %w", err)
+				response.Choices[0].Message.ToolCalls = nil
+				result.StopReason = guard.wrapUp
+				break
+			}
+			if limit != "" {
+				if err := s.requestWrapUp(agentSession, choice.Message.ToolCalls, limit); err != nil {
+					return nil, err
 				}
+				guard.wrapUp = limit
+				continue
+			}
+			
+			if len(completion.XMLToolCalls) > 0 {
+				err = s.runXMLToolCalls(ctx, agentSession, completion.XMLToolCalls, vision, result)
+			} else {
+				err = s.runToolCalls(ctx, agentSession, choice.Message.ToolCalls, completion.ToolCallMode, vision, result)
+			}
+			if err != nil {
+				return nil, err
 			}
 			
 			// If thinking continuation is needed, add a prompt for the next thought
@@ -157,28 +416,224 @@ func (s *AgentSpawner) executeConversationLoop(agentSession *session.AgentSessio
 			if err := s.sessionManager.AddMessage(agentSession.ID, assistantMessage); err != nil {
 				return nil, fmt.Errorf("failed to add final assistant message: %w", err)
 			}
-			break
+			if guard.wrapUp != "" {
+				result.StopReason = guard.wrapUp
+			}
+			if output == nil {
+				break
+			}
+
+			structured, parseErr := output.parse(choice.Message.Content)
+			if parseErr == nil {
+				result.StructuredOutput = structured
+				break
+			}
+			if repairs >= maxOutputRepairs {
+				fmt.Printf("WARNING: Agent %s output failed schema after %d repairs: %v\n", agentSession.AgentID, repairs, parseErr)
+				result.OutputError = parseErr.Error()
+				break
+			}
+			if limit != "" {
+				fmt.Printf("WARNING: Agent %s reached %s before its output passed the schema: %v\n", agentSession.AgentID, limit, parseErr)
+				result.OutputError = parseErr.Error()
+				result.StopReason = limit
+				break
+			}
+
+			// Ask for a corrected answer within the same session
+			repairs++
+			fmt.Printf("DEBUG: Agent %s output repair %d/%d: %v\n", agentSession.AgentID, repairs, maxOutputRepairs, parseErr)
+			repairMessage := openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: output.repairPrompt(parseErr),
+			}
+			if err := s.sessionManager.AddMessage(agentSession.ID, repairMessage); err != nil {
+				return nil, fmt.Errorf("failed to add output repair message: %w", err)
+			}
 		}
 	}
 	
-	return lastResponse, nil
+	return result, nil
+}
+
+// runToolCalls runs one turn's tool calls, overlapping those that cannot interfere, and adds
+// their results to the session in call order
+func (s *AgentSpawner) runToolCalls(ctx context.Context, agentSession *session.AgentSession, toolCalls []openai.ToolCall, mode string, vision bool, result *AgentRunResult) error {
+	for _, toolCall := range toolCalls {
+		emitStreamEvent(ctx, StreamEvent{
+			Type:       StreamEventToolCall,
+			AgentID:    agentSession.AgentID,
+			Text:       fmt.Sprintf("calling tool %s", toolCall.Function.Name),
+			ToolName:   toolCall.Function.Name,
+			ToolCallID: toolCall.ID,
+		})
+	}
+	toolResults := make([]string, len(toolCalls))
+	toolErrors := make([]error, len(toolCalls))
+	toolImages := make([][]*media.Image, len(toolCalls))
+	durations := make([]time.Duration, len(toolCalls))
+	s.client.scheduler().run(toolCalls, func(i int, toolCall openai.ToolCall) {
+		started := time.Now()
+		callCtx, images := imageContext(ctx, vision)
+		toolResults[i], toolErrors[i] = s.executeToolCall(callCtx, toolCall)
+		toolImages[i] = collectedImages(images)
+		durations[i] = time.Since(started)
+	})
+
+	// Record and add results in call order, whatever order the calls finished in
+	var shown []openai.ChatMessagePart
+	for i, toolCall := range toolCalls {
+		toolResult := toolResults[i]
+		shown = append(shown, imageParts(toolCall.Function.Name, toolImages[i])...)
+		result.ToolCalls = append(result.ToolCalls, newToolCallRecord(mode, toolCall, durations[i], toolResult, toolErrors[i]))
+		if toolErrors[i] != nil {
+			toolResult = fmt.Sprintf("Error executing tool %s: %v", toolCall.Function.Name, toolErrors[i])
+		}
+
+		emitStreamEvent(ctx, StreamEvent{
+			Type:       StreamEventToolResult,
+			AgentID:    agentSession.AgentID,
+			Text:       fmt.Sprintf("tool %s finished", toolCall.Function.Name),
+			ToolName:   toolCall.Function.Name,
+			ToolCallID: toolCall.ID,
+		})
+		toolMessage := openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    toolResult,
+			ToolCallID: toolCall.ID,
+		}
+		if err := s.sessionManager.AddMessage(agentSession.ID, toolMessage); err != nil {
+			return fmt.Errorf("failed to add tool message: %w", err)
+		}
+	}
+	if len(shown) > 0 {
+		if err := s.sessionManager.AddMessage(agentSession.ID, imageMessage(shown)); err != nil {
+			return fmt.Errorf("failed to add image message: %w", err)
+		}
+	}
+	return nil
+}
+
+// runXMLToolCalls runs the calls a model wrote as XML and adds their results to the session as
+// one <function_results> user message
+func (s *AgentSpawner) runXMLToolCalls(ctx context.Context, agentSession *session.AgentSession, xmlToolCalls []XMLToolCall, vision bool, result *AgentRunResult) error {
+	toolCalls := make([]openai.ToolCall, len(xmlToolCalls))
+	for i, xmlToolCall := range xmlToolCalls {
+		toolCalls[i] = xmlToolCall.toolCall()
+		emitStreamEvent(ctx, StreamEvent{
+			Type:     StreamEventToolCall,
+			AgentID:  agentSession.AgentID,
+			Text:     fmt.Sprintf("calling tool %s", xmlToolCall.Function),
+			ToolName: xmlToolCall.Function,
+		})
+	}
+	toolResults := make([]string, len(toolCalls))
+	toolErrors := make([]error, len(toolCalls))
+	toolImages := make([][]*media.Image, len(toolCalls))
+	durations := make([]time.Duration, len(toolCalls))
+	s.client.scheduler().run(toolCalls, func(i int, _ openai.ToolCall) {
+		started := time.Now()
+		callCtx, images := imageContext(ctx, vision)
+		toolResults[i], toolErrors[i] = s.client.executeXMLToolCall(callCtx, xmlToolCalls[i])
+		toolImages[i] = collectedImages(images)
+		durations[i] = time.Since(started)
+	})
+
+	var shown []openai.ChatMessagePart
+	for i, toolCall := range toolCalls {
+		shown = append(shown, imageParts(toolCall.Function.Name, toolImages[i])...)
+		result.ToolCalls = append(result.ToolCalls, newToolCallRecord("xml_tools", toolCall, durations[i], toolResults[i], toolErrors[i]))
+		emitStreamEvent(ctx, StreamEvent{
+			Type:     StreamEventToolResult,
+			AgentID:  agentSession.AgentID,
+			Text:     fmt.Sprintf("tool %s finished", toolCall.Function.Name),
+			ToolName: toolCall.Function.Name,
+		})
+	}
+	if err := s.sessionManager.AddMessage(agentSession.ID, xmlResultsMessage(xmlToolCalls, toolResults, toolErrors, shown)); err != nil {
+		return fmt.Errorf("failed to add function results message: %w", err)
+	}
+	return nil
+}
+
+// requestWrapUp answers the pending tool calls without running them and asks the agent for
+// its final answer, keeping the conversation valid for providers that require every call answered
+func (s *AgentSpawner) requestWrapUp(agentSession *session.AgentSession, toolCalls []openai.ToolCall, limit string) error {
+	fmt.Printf("DEBUG: Agent %s reached %s, asking it to wrap up\n", agentSession.AgentID, limit)
+
+	if err := s.skipToolCalls(agentSession, toolCalls, limit); err != nil {
+		return err
+	}
+
+	wrapUp := openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: wrapUpPrompt(limit),
+	}
+	if err := s.sessionManager.AddMessage(agentSession.ID, wrapUp); err != nil {
+		return fmt.Errorf("failed to add wrap-up message: %w", err)
+	}
+	return nil
+}
+
+// skipToolCalls answers each call with a result saying it was not run because of limit
+func (s *AgentSpawner) skipToolCalls(agentSession *session.AgentSession, toolCalls []openai.ToolCall, limit string) error {
+	for _, toolCall := range toolCalls {
+		skipped := openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    fmt.Sprintf("Not executed: %s limit reached", limit),
+			ToolCallID: toolCall.ID,
+		}
+		if err := s.sessionManager.AddMessage(agentSession.ID, skipped); err != nil {
+			return fmt.Errorf("failed to add tool message: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordUsage charges every billed attempt to the ledger and the agent's running totals
+func (s *AgentSpawner) recordUsage(run *usage.Run, agentSession *session.AgentSession, attempts []ModelAttempt, result *AgentRunResult) {
+	if s.ledger == nil {
+		return
+	}
+
+	for _, attempt := range attempts {
+		if attempt.Usage == nil {
+			continue
+		}
+		entry, err := s.ledger.Record(run, agentSession.ID, agentSession.AgentID, attempt.Model, tokensFromUsage(attempt.Usage))
+		if err != nil {
+			fmt.Printf("WARNING: Failed to persist usage entry: %v\n", err)
+		}
+		result.Usage.Add(entry)
+	}
+}
+
+// tokensFromUsage converts SDK usage into ledger token counts
+func tokensFromUsage(u *openai.Usage) usage.Tokens {
+	tokens := usage.Tokens{
+		Prompt:     u.PromptTokens,
+		Completion: u.CompletionTokens,
+		Total:      u.TotalTokens,
+	}
+	if u.CompletionTokensDetails != nil {
+		tokens.Reasoning = u.CompletionTokensDetails.ReasoningTokens
+	}
+	if tokens.Total == 0 {
+		tokens.Total = tokens.Prompt + tokens.Completion
+	}
+	return tokens
 }
 
 // executeToolCall executes a tool call and returns the result
-func (s *AgentSpawner) executeToolCall(toolCall openai.ToolCall) string {
+func (s *AgentSpawner) executeToolCall(ctx context.Context, toolCall openai.ToolCall) (string, error) {
 	// Parse tool arguments
 	var params map[string]interface{}
 	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &params); err != nil {
-		return fmt.Sprintf("Error parsing tool arguments: %v", err)
+		return "", fmt.Errorf("failed to parse tool arguments: %w", err)
 	}
 	
 	// Execute tool via the centralized tools manager
-	result, err := s.toolsManager.ExecuteOpenAITool(toolCall.Function.Name, params)
-	if err != nil {
-		return fmt.Sprintf("Error executing tool %s: %v", toolCall.Function.Name, err)
-	}
-	
-	return result
+	return s.toolsManager.ExecuteOpenAITool(ctx, toolCall.Function.Name, params)
 }
 
 // needsThinkingContinuation checks if a tool call requires thinking continuation
@@ -242,3 +697,24 @@ func (s *AgentSpawner) CleanupOldSessions(maxAge time.Duration) {
 func (s *AgentSpawner) GetSessionCount() int {
 	return s.sessionManager.GetSessionCount()
 }
+
+// StartSessionSweeper collects completed sessions over the configured retention limits until ctx is done
+func (s *AgentSpawner) StartSessionSweeper(ctx context.Context) {
+	retention := s.client.config.SessionRetention
+	s.sessionManager.StartSweeper(ctx, session.RetentionFromConfig(retention), time.Duration(retention.GCInterval)*time.Second)
+}
+
+// CloseSessions flushes the session writes not yet synced to disk (delegates to SessionManager)
+func (s *AgentSpawner) CloseSessions() error {
+	return s.sessionManager.Close()
+}
+
+// RecoverSessions marks sessions left active by a process that is gone as interrupted (delegates to SessionManager)
+func (s *AgentSpawner) RecoverSessions() ([]*session.AgentSession, error) {
+	return s.sessionManager.RecoverSessions()
+}
+
+// InterruptedSessions returns the sessions waiting to be resumed (delegates to SessionManager)
+func (s *AgentSpawner) InterruptedSessions() []*session.AgentSession {
+	return s.sessionManager.ListSessions(session.SessionFilter{Status: session.StatusInterrupted})
+}
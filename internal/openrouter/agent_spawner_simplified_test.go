@@ -1,7 +1,6 @@
 package openrouter
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"path/filepath"
@@ -408,7 +407,7 @@ func TestExecuteConversationLoop_ToolCallError_Simplified(t *testing.T) {
 	
 	if toolResultMessage == nil {
 		t.Error("Should have tool result message")
-	} else if !contains(toolResultMessage.Content, "Error executing tool") {
+	} else if !stringContains(toolResultMessage.Content, "Error executing tool") {
 		t.Errorf("Tool result should contain error message, got: %s", toolResultMessage.Content)
 	}
 }
@@ -507,7 +506,7 @@ func TestExecuteConversationLoop_ThinkingContinuation_Simplified(t *testing.T) {
 	// Should find continuation message
 	foundContinuation := false
 	for _, msg := range messages {
-		if msg.Role == openai.ChatMessageRoleUser && contains(msg.Content, "continue with your next thought") {
+		if msg.Role == openai.ChatMessageRoleUser && stringContains(msg.Content, "continue with your next thought") {
 			foundContinuation = true
 			break
 		}
@@ -536,7 +535,7 @@ func TestExecuteConversationLoop_APIError_Simplified(t *testing.T) {
 		t.Error("Expected conversation loop to fail with API error")
 	}
 	
-	if !contains(err.Error(), "API connection failed") {
+	if !stringContains(err.Error(), "API connection failed") {
 		t.Errorf("Expected API error message, got: %s", err.Error())
 	}
 }
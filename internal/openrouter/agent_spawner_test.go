@@ -1,12 +1,23 @@
 package openrouter
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"gorka/internal/session"
+	"gorka/internal/tools"
 	"gorka/internal/types"
+	"gorka/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -190,3 +201,200 @@ func TestAutomaticSessionCompletion(t *testing.T) {
 		t.Error("Session should be marked as completed")
 	}
 }
+
+func TestSpawnAgentStopsWhenCancelled(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"gen-1","model":"test/model","choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"slow_tool","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	workspace := t.TempDir()
+	toolsManager := tools.NewToolsManager(workspace, filepath.Join(workspace, "storage"))
+	var toolErr error
+	toolsManager.RegisterOpenAITool("slow_tool", "Blocks until cancelled", &jsonschema.Schema{Type: "object"},
+		func(ctx context.Context, params map[string]interface{}) (string, error) {
+			// The client disconnects while the tool is running
+			cancel()
+			<-ctx.Done()
+			toolErr = ctx.Err()
+			return "", toolErr
+		})
+
+	client := newTestClient(server.URL, false)
+	client.toolsManager = toolsManager
+	spawner := &AgentSpawner{
+		client:         client,
+		toolsManager:   toolsManager,
+		sessionManager: session.NewSessionManagerWithDir(filepath.Join(workspace, "sessions")),
+	}
+
+	_, err := spawner.SpawnAgentWithContext(ctx, &types.BehavioralMatrix{AgentID: "test_agent"}, "do it")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if !errors.Is(toolErr, context.Canceled) {
+		t.Errorf("Expected the tool to observe cancellation, got %v", toolErr)
+	}
+	if requests != 1 {
+		t.Errorf("Expected no model calls after cancellation, got %d requests", requests)
+	}
+}
+
+// matrixEngine serves behavioral matrices to a spawner
+type matrixEngine map[string]*types.BehavioralMatrix
+
+func (e matrixEngine) GetAvailableAgents() []string { return nil }
+
+func (e matrixEngine) GetBehavioralMatrices() map[string]*types.BehavioralMatrix { return e }
+
+func TestContinueAgentAfterRestart(t *testing.T) {
+	var requests []openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+		replyContent(w, "test/model", fmt.Sprintf("Answer %d", len(requests)))
+	}))
+	defer server.Close()
+
+	workspace := t.TempDir()
+	matrix := &types.BehavioralMatrix{AgentID: "test_agent"}
+	newSpawner := func() *AgentSpawner {
+		return &AgentSpawner{
+			client:           newTestClient(server.URL, false),
+			sessionManager:   session.NewSessionManagerWithDir(filepath.Join(workspace, "sessions")),
+			behavioralEngine: matrixEngine{"test_agent": matrix},
+		}
+	}
+
+	first, err := newSpawner().SpawnAgentWithContext(context.Background(), matrix, "read a.go")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+
+	// A restarted server only has the session on disk, without its matrix
+	spawner := newSpawner()
+	if _, err := spawner.ContinueAgentWithContext(context.Background(), "unknown_session", "hello"); err == nil {
+		t.Error("Expected an unknown session to be rejected")
+	}
+	result, err := spawner.ContinueAgentWithContext(context.Background(), first.SessionID, "and b.go?")
+	if err != nil {
+		t.Fatalf("Continue failed: %v", err)
+	}
+	if result.SessionID != first.SessionID || result.Response.Choices[0].Message.Content != "Answer 2" {
+		t.Errorf("Expected the second answer in the same session, got %+v", result)
+	}
+
+	// The follow-up request replays the earlier conversation
+	messages := requests[1].Messages
+	if len(messages) != 4 || messages[1].Content != "read a.go" || messages[2].Content != "Answer 1" || messages[3].Content != "and b.go?" {
+		t.Errorf("Expected the earlier conversation before the follow-up, got %+v", messages)
+	}
+	if agentSession, _ := spawner.GetSession(first.SessionID); !agentSession.Completed || agentSession.Matrix != matrix {
+		t.Errorf("Expected the continued session to be completed with its matrix restored, got %+v", agentSession)
+	}
+
+	// A session cannot take a second message while its loop is running
+	spawner.runningSessions.Store(first.SessionID, true)
+	if _, err := spawner.ContinueAgentWithContext(context.Background(), first.SessionID, "again"); err == nil {
+		t.Error("Expected a running session to be rejected")
+	}
+}
+
+// readFileServer asks to read a.go once, then answers in prose, reporting usage on every reply
+func readFileServer(requests *[]openai.ChatCompletionRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		*requests = append(*requests, request)
+
+		w.Header().Set("Content-Type", "application/json")
+		usage := `"usage":{"prompt_tokens":100,"completion_tokens":10,"total_tokens":110}`
+		if len(*requests) == 1 {
+			fmt.Fprintf(w, `{"id":"gen-1","model":"test/model","choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"read_file","arguments":"{\"file_path\":\"a.go\"}"}}]},"finish_reason":"tool_calls"}],%s}`, usage)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"gen-%d","model":"test/model","choices":[{"index":0,"message":{"role":"assistant","content":"Answer %d"},"finish_reason":"stop"}],%s}`, len(*requests), len(*requests), usage)
+	}))
+}
+
+func TestContinueAgentKeepsToolExchanges(t *testing.T) {
+	var requests []openai.ChatCompletionRequest
+	server := readFileServer(&requests)
+	defer server.Close()
+
+	workspace := t.TempDir()
+	toolsManager := tools.NewToolsManager(workspace, filepath.Join(workspace, "storage"))
+	client := newTestClient(server.URL, false)
+	client.toolsManager = toolsManager
+	spawner := &AgentSpawner{
+		client:         client,
+		toolsManager:   toolsManager,
+		sessionManager: session.NewSessionManagerWithDir(filepath.Join(workspace, "sessions")),
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "a.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := spawner.SpawnAgentWithContext(context.Background(), &types.BehavioralMatrix{AgentID: "test_agent"}, "read a.go")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+	if _, err := spawner.ContinueAgentWithContext(context.Background(), first.SessionID, "what package is it?"); err != nil {
+		t.Fatalf("Continue failed: %v", err)
+	}
+
+	// The follow-up replays the tool call and what it read, not just the final answers
+	var call, read bool
+	for _, msg := range requests[2].Messages {
+		if msg.Role == openai.ChatMessageRoleAssistant && len(msg.ToolCalls) == 1 && msg.ToolCalls[0].ID == "call_1" {
+			call = true
+		}
+		if msg.Role == openai.ChatMessageRoleTool && msg.ToolCallID == "call_1" && strings.Contains(msg.Content, "package main") {
+			read = true
+		}
+	}
+	if !call || !read {
+		t.Errorf("Expected the earlier tool exchange in the follow-up, got %+v", requests[2].Messages)
+	}
+}
+
+func TestEveryToolRoundIsCharged(t *testing.T) {
+	var requests []openai.ChatCompletionRequest
+	server := readFileServer(&requests)
+	defer server.Close()
+
+	spawner := loopTestSpawner(t, server.URL, LoopLimits{})
+	ledger, err := usage.NewLedger(t.TempDir(), usage.PriceTable{"model": {Prompt: 1000, Completion: 1000}}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spawner.ledger = ledger
+
+	result, err := spawner.SpawnAgentWithContext(context.Background(), &types.BehavioralMatrix{AgentID: "test_agent"}, "read a.go")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+	if result.Usage.Calls != 2 || result.Usage.Tokens.Total != 220 {
+		t.Errorf("Expected both rounds in the run totals, got %+v", result.Usage)
+	}
+
+	// A run budget spent by the tool round stops the agent before the next call
+	requests = nil
+	ledger, _ = usage.NewLedger(t.TempDir(), usage.PriceTable{"model": {Prompt: 1000, Completion: 1000}}, 0.05, 0)
+	spawner.ledger = ledger
+	ctx := usage.WithRun(context.Background(), ledger.NewRun("test_agent"))
+	result, err = spawner.SpawnAgentWithContext(ctx, &types.BehavioralMatrix{AgentID: "test_agent"}, "read a.go")
+	if !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Fatalf("Expected the run budget to stop the agent, got %v", err)
+	}
+	if len(requests) != 1 || result.StopReason != StopReasonBudgetExceeded {
+		t.Errorf("Expected one call before the budget stop, got %d calls and stop reason %q", len(requests), result.StopReason)
+	}
+}
@@ -0,0 +1,493 @@
+package openrouter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicAPIVersion       = "2023-06-01"
+	anthropicDefaultMaxTokens = 4096
+)
+
+// AnthropicProvider talks to the native Anthropic Messages API
+type AnthropicProvider struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewAnthropicProvider creates a provider for the Anthropic Messages API
+func NewAnthropicProvider(apiKey, baseURL string, httpClient *http.Client) *AnthropicProvider {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &AnthropicProvider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// anthropicRequest is the body of a POST /v1/messages call
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	System        string               `json:"system,omitempty"`
+	Messages      []anthropicMessage   `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   *float32             `json:"temperature,omitempty"`
+	TopP          *float32             `json:"top_p,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+// anthropicContentBlock covers the text, image, tool_use, tool_result and thinking block types
+type anthropicContentBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+}
+
+// anthropicImageSource carries an image inline as base64
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Model      string                  `json:"model"`
+	Role       string                  `json:"role"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Name returns the provider identifier
+func (p *AnthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// CreateChatCompletion performs a blocking Messages API call
+func (p *AnthropicProvider) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	body, err := p.buildRequest(request, false)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+
+	httpResp, err := p.send(ctx, body)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp anthropicResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("failed to decode Anthropic response: %w", err)
+	}
+
+	return convertAnthropicResponse(resp), nil
+}
+
+// CreateChatCompletionStream performs a streaming Messages API call and assembles the events
+func (p *AnthropicProvider) CreateChatCompletionStream(ctx context.Context, request openai.ChatCompletionRequest, handler StreamHandler) (openai.ChatCompletionResponse, error) {
+	body, err := p.buildRequest(request, true)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+
+	httpResp, err := p.send(ctx, body)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp anthropicResponse
+	blocks := make(map[int]*anthropicContentBlock)
+	partialInputs := make(map[int]*strings.Builder)
+
+	scanner := bufio.NewScanner(httpResp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+
+		var event struct {
+			Type         string                `json:"type"`
+			Index        int                   `json:"index"`
+			Message      anthropicResponse     `json:"message"`
+			ContentBlock anthropicContentBlock `json:"content_block"`
+			Delta        struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				Thinking    string `json:"thinking"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage anthropicUsage `json:"usage"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return openai.ChatCompletionResponse{}, fmt.Errorf("failed to decode Anthropic stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			resp.ID = event.Message.ID
+			resp.Model = event.Message.Model
+			resp.Role = event.Message.Role
+			resp.Usage = event.Message.Usage
+		case "content_block_start":
+			block := event.ContentBlock
+			block.Input = nil
+			blocks[event.Index] = &block
+			if block.Type == "tool_use" {
+				partialInputs[event.Index] = &strings.Builder{}
+			}
+		case "content_block_delta":
+			block, exists := blocks[event.Index]
+			if !exists {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				block.Text += event.Delta.Text
+				handler(StreamEvent{Type: StreamEventContent, Text: event.Delta.Text})
+			case "thinking_delta":
+				block.Thinking += event.Delta.Thinking
+			case "input_json_delta":
+				partialInputs[event.Index].WriteString(event.Delta.PartialJSON)
+				handler(StreamEvent{
+					Type:       StreamEventToolCallDelta,
+					Text:       event.Delta.PartialJSON,
+					ToolName:   block.Name,
+					ToolCallID: block.ID,
+				})
+			}
+		case "content_block_stop":
+			if input, exists := partialInputs[event.Index]; exists {
+				blocks[event.Index].Input = json.RawMessage(input.String())
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				resp.StopReason = event.Delta.StopReason
+			}
+			resp.Usage.OutputTokens = event.Usage.OutputTokens
+		case "error":
+			return openai.ChatCompletionResponse{}, &openai.APIError{
+				Type:    event.Error.Type,
+				Message: event.Error.Message,
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("stream receive failed: %w", err)
+	}
+
+	indexes := make([]int, 0, len(blocks))
+	for index := range blocks {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		resp.Content = append(resp.Content, *blocks[index])
+	}
+
+	fmt.Printf("DEBUG: Anthropic stream assembled %d content blocks for model %s\n", len(resp.Content), resp.Model)
+	return convertAnthropicResponse(resp), nil
+}
+
+// send posts a request body to the Messages endpoint and converts error statuses
+func (p *AnthropicProvider) send(ctx context.Context, body []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Anthropic request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	httpResp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		defer httpResp.Body.Close()
+		data, _ := io.ReadAll(httpResp.Body)
+
+		// Surface errors as openai.APIError so callers handle every provider the same way
+		apiErr := &openai.APIError{
+			HTTPStatusCode: httpResp.StatusCode,
+			Message:        strings.TrimSpace(string(data)),
+		}
+		var errResp anthropicErrorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error.Message != "" {
+			apiErr.Type = errResp.Error.Type
+			apiErr.Code = errResp.Error.Type
+			apiErr.Message = errResp.Error.Message
+		}
+		return nil, apiErr
+	}
+
+	return httpResp, nil
+}
+
+// buildRequest converts an OpenAI-style request into a Messages API body
+func (p *AnthropicProvider) buildRequest(request openai.ChatCompletionRequest, stream bool) ([]byte, error) {
+	req := anthropicRequest{
+		Model:         request.Model,
+		MaxTokens:     request.MaxCompletionTokens,
+		StopSequences: request.Stop,
+		Stream:        stream,
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = request.MaxTokens
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = anthropicDefaultMaxTokens
+	}
+	if request.Temperature != 0 {
+		temperature := request.Temperature
+		req.Temperature = &temperature
+	}
+	if request.TopP != 0 {
+		topP := request.TopP
+		req.TopP = &topP
+	}
+	if request.ReasoningEffort != "" {
+		// Extended thinking needs signed thinking blocks replayed during tool use, which sessions do not keep
+		fmt.Printf("DEBUG: Anthropic provider ignores reasoning effort %s\n", request.ReasoningEffort)
+	}
+
+	var systemParts []string
+	for _, msg := range request.Messages {
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			// The Messages API takes the system prompt as a top-level field
+			systemParts = append(systemParts, msg.Content)
+		case openai.ChatMessageRoleTool:
+			req.Messages = appendAnthropicBlock(req.Messages, "user", anthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Content,
+			})
+		case openai.ChatMessageRoleAssistant:
+			if msg.Content != "" {
+				req.Messages = appendAnthropicBlock(req.Messages, "assistant", anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, toolCall := range msg.ToolCalls {
+				input := json.RawMessage(toolCall.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				req.Messages = appendAnthropicBlock(req.Messages, "assistant", anthropicContentBlock{
+					Type:  "tool_use",
+					ID:    toolCall.ID,
+					Name:  toolCall.Function.Name,
+					Input: input,
+				})
+			}
+		default:
+			if len(msg.MultiContent) == 0 {
+				req.Messages = appendAnthropicBlock(req.Messages, "user", anthropicContentBlock{Type: "text", Text: msg.Content})
+				continue
+			}
+			for _, part := range msg.MultiContent {
+				block, err := anthropicPartBlock(part)
+				if err != nil {
+					return nil, err
+				}
+				req.Messages = appendAnthropicBlock(req.Messages, "user", block)
+			}
+		}
+	}
+	req.System = strings.Join(systemParts, "\n\n")
+
+	for _, tool := range request.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := json.RawMessage(`{"type":"object"}`)
+		if tool.Function.Parameters != nil {
+			data, err := json.Marshal(tool.Function.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal schema for tool %s: %w", tool.Function.Name, err)
+			}
+			schema = data
+		}
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	if len(req.Tools) > 0 {
+		req.ToolChoice = &anthropicToolChoice{Type: "auto"}
+		switch request.ToolChoice {
+		case "required":
+			req.ToolChoice.Type = "any"
+		case "none":
+			req.ToolChoice.Type = "none"
+		}
+		if parallel, ok := request.ParallelToolCalls.(bool); ok && !parallel && req.ToolChoice.Type != "none" {
+			req.ToolChoice.DisableParallelToolUse = true
+		}
+	}
+
+	return json.Marshal(req)
+}
+
+// anthropicPartBlock maps a content part onto a text or image block. Images must be
+// data URLs, which is how tool images are sent.
+func anthropicPartBlock(part openai.ChatMessagePart) (anthropicContentBlock, error) {
+	if part.Type != openai.ChatMessagePartTypeImageURL || part.ImageURL == nil {
+		return anthropicContentBlock{Type: "text", Text: part.Text}, nil
+	}
+
+	header, data, found := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ";base64,")
+	if !found || !strings.HasPrefix(part.ImageURL.URL, "data:") {
+		return anthropicContentBlock{}, fmt.Errorf("anthropic images must be base64 data URLs")
+	}
+	return anthropicContentBlock{
+		Type:   "image",
+		Source: &anthropicImageSource{Type: "base64", MediaType: header, Data: data},
+	}, nil
+}
+
+// appendAnthropicBlock adds a block, merging into the previous message when the role repeats
+// because the Messages API requires user and assistant turns to alternate
+func appendAnthropicBlock(messages []anthropicMessage, role string, block anthropicContentBlock) []anthropicMessage {
+	if len(messages) > 0 && messages[len(messages)-1].Role == role {
+		last := &messages[len(messages)-1]
+		last.Content = append(last.Content, block)
+		return messages
+	}
+	return append(messages, anthropicMessage{Role: role, Content: []anthropicContentBlock{block}})
+}
+
+// convertAnthropicResponse maps a Messages API response onto the OpenAI response shape
+func convertAnthropicResponse(resp anthropicResponse) openai.ChatCompletionResponse {
+	message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+
+	var text, thinking strings.Builder
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "thinking":
+			thinking.WriteString(block.Thinking)
+		case "tool_use":
+			arguments := string(block.Input)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			message.ToolCalls = append(message.ToolCalls, openai.ToolCall{
+				ID:   block.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      block.Name,
+					Arguments: arguments,
+				},
+			})
+		}
+	}
+	message.Content = text.String()
+	message.ReasoningContent = thinking.String()
+
+	promptTokens := resp.Usage.InputTokens + resp.Usage.CacheReadInputTokens + resp.Usage.CacheCreationInputTokens
+	usage := openai.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: resp.Usage.OutputTokens,
+		TotalTokens:      promptTokens + resp.Usage.OutputTokens,
+	}
+	if resp.Usage.CacheReadInputTokens > 0 {
+		usage.PromptTokensDetails = &openai.PromptTokensDetails{CachedTokens: resp.Usage.CacheReadInputTokens}
+	}
+
+	return openai.ChatCompletionResponse{
+		ID:     resp.ID,
+		Object: "chat.completion",
+		Model:  resp.Model,
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: mapAnthropicStopReason(resp.StopReason),
+			},
+		},
+		Usage: usage,
+	}
+}
+
+// mapAnthropicStopReason translates stop_reason values into OpenAI finish reasons
+func mapAnthropicStopReason(stopReason string) openai.FinishReason {
+	switch stopReason {
+	case "end_turn", "stop_sequence", "pause_turn":
+		return openai.FinishReasonStop
+	case "max_tokens":
+		return openai.FinishReasonLength
+	case "tool_use":
+		return openai.FinishReasonToolCalls
+	case "refusal":
+		return openai.FinishReasonContentFilter
+	default:
+		return openai.FinishReason(stopReason)
+	}
+}
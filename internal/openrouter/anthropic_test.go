@@ -0,0 +1,164 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestAnthropicRequestTranslation(t *testing.T) {
+	var captured map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("missing auth headers: %v", r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&captured); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "msg_1",
+			"model": "claude-test",
+			"role": "assistant",
+			"content": [
+				{"type": "text", "text": "Reading the file."},
+				{"type": "tool_use", "id": "toolu_2", "name": "read_file", "input": {"path": "b.go"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 20, "output_tokens": 5, "cache_read_input_tokens": 10}
+		}`)
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider("test-key", server.URL, nil)
+	request := openai.ChatCompletionRequest{
+		Model: "claude-test",
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "You are an agent."},
+			{Role: openai.ChatMessageRoleUser, Content: "Read a.go"},
+			{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{
+				{ID: "toolu_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "read_file", Arguments: `{"path":"a.go"}`}},
+			}},
+			{Role: openai.ChatMessageRoleTool, ToolCallID: "toolu_1", Content: "package a"},
+		},
+		Tools: []openai.Tool{
+			{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+				Name:       "read_file",
+				Parameters: map[string]interface{}{"type": "object"},
+			}},
+		},
+		ToolChoice:        "auto",
+		ParallelToolCalls: false,
+	}
+
+	response, err := provider.CreateChatCompletion(context.Background(), request)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	if captured["system"] != "You are an agent." {
+		t.Errorf("system prompt not sent as top-level field: %v", captured["system"])
+	}
+	messages := captured["messages"].([]interface{})
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 alternating messages, got %d", len(messages))
+	}
+	toolResult := messages[2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	if toolResult["type"] != "tool_result" || toolResult["tool_use_id"] != "toolu_1" {
+		t.Errorf("tool result not converted: %v", toolResult)
+	}
+	toolChoice := captured["tool_choice"].(map[string]interface{})
+	if toolChoice["disable_parallel_tool_use"] != true {
+		t.Errorf("parallel tool use should be disabled: %v", toolChoice)
+	}
+
+	choice := response.Choices[0]
+	if choice.FinishReason != openai.FinishReasonToolCalls {
+		t.Errorf("unexpected finish reason: %s", choice.FinishReason)
+	}
+	if choice.Message.Content != "Reading the file." {
+		t.Errorf("unexpected content: %q", choice.Message.Content)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"path": "b.go"}` {
+		t.Errorf("unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if response.Usage.PromptTokens != 30 || response.Usage.TotalTokens != 35 {
+		t.Errorf("unexpected usage: %+v", response.Usage)
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_2","model":"claude-test","role":"assistant","usage":{"input_tokens":12,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_9","name":"list_dir","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\".\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":18}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	var text string
+	provider := NewAnthropicProvider("test-key", server.URL, nil)
+	response, err := provider.CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{Model: "claude-test"}, func(event StreamEvent) {
+		if event.Type == StreamEventContent {
+			text += event.Text
+		}
+	})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	if text != "Let me check." {
+		t.Errorf("unexpected streamed text: %q", text)
+	}
+	message := response.Choices[0].Message
+	if message.Content != "Let me check." {
+		t.Errorf("unexpected content: %q", message.Content)
+	}
+	if len(message.ToolCalls) != 1 || message.ToolCalls[0].Function.Arguments != `{"path":"."}` {
+		t.Errorf("unexpected tool calls: %+v", message.ToolCalls)
+	}
+	if response.Usage.CompletionTokens != 18 || response.Usage.PromptTokens != 12 {
+		t.Errorf("unexpected usage: %+v", response.Usage)
+	}
+}
+
+func TestAnthropicErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicProvider("test-key", server.URL, nil)
+	_, err := provider.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "claude-test"})
+
+	apiErr, ok := err.(*openai.APIError)
+	if !ok {
+		t.Fatalf("expected *openai.APIError, got %T: %v", err, err)
+	}
+	if apiErr.HTTPStatusCode != http.StatusTooManyRequests || apiErr.Type != "rate_limit_error" || apiErr.Message != "slow down" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
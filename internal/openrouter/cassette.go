@@ -0,0 +1,403 @@
+package openrouter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Cassette modes accepted by SECONDBRAIN_CASSETTE_MODE
+const (
+	CassetteModeRecord = "record"
+	CassetteModeReplay = "replay"
+)
+
+const cassetteVersion = 1
+
+// errCassetteMiss marks a replayed request that has no recorded response
+var errCassetteMiss = errors.New("no recorded response in cassette")
+
+// secretPatterns match credentials that must never be written to a cassette
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}`),
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9\-]{10,}`),
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._\-]{16,}`),
+}
+
+const redactedSecret = "[REDACTED]"
+
+// Cassette is the on-disk record of every completion request made during a run
+type Cassette struct {
+	Version      int                   `json:"version"`
+	Provider     string                `json:"provider"`
+	RecordedAt   time.Time             `json:"recorded_at"`
+	// Model is the model the run was configured with, and LocalModel the local model serving it,
+	// so a replay can set up the client without asking the provider
+	Model        string                `json:"model,omitempty"`
+	LocalModel   *LocalModel           `json:"local_model,omitempty"`
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// CassetteInteraction is one request and the response or error it produced
+type CassetteInteraction struct {
+	// Fingerprint hashes the unredacted request so replay can match it exactly
+	Fingerprint string `json:"fingerprint"`
+	// Conversation hashes the model and first message, identifying one agent's session
+	Conversation string                         `json:"conversation"`
+	Request      json.RawMessage                `json:"request"` // raw, since response_format schemas do not unmarshal into the SDK type
+	Response     *openai.ChatCompletionResponse `json:"response,omitempty"`
+	Error        *CassetteError                 `json:"error,omitempty"`
+}
+
+// CassetteError is a recorded API failure, replayed so retries and fallbacks behave the same
+type CassetteError struct {
+	StatusCode int    `json:"status_code,omitempty"`
+	Message    string `json:"message"`
+	Type       string `json:"type,omitempty"`
+}
+
+// CassetteProvider records completions from a real provider, or replays them without network access
+type CassetteProvider struct {
+	mode    string
+	path    string
+	inner   Provider
+	secrets []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewCassetteProvider wraps inner in record or replay mode. Recording starts a new cassette at
+// path; replay loads it and never calls inner. Secrets are redacted from recorded data verbatim,
+// in addition to anything matching the built-in credential patterns.
+func NewCassetteProvider(mode, path string, inner Provider, secrets []string) (*CassetteProvider, error) {
+	p := &CassetteProvider{
+		mode:  mode,
+		path:  path,
+		inner: inner,
+	}
+	for _, secret := range secrets {
+		if secret != "" {
+			p.secrets = append(p.secrets, secret)
+		}
+	}
+
+	switch mode {
+	case CassetteModeRecord:
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+		p.cassette = &Cassette{
+			Version:    cassetteVersion,
+			Provider:   inner.Name(),
+			RecordedAt: time.Now().UTC(),
+		}
+		if err := p.save(); err != nil {
+			return nil, err
+		}
+	case CassetteModeReplay:
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		p.cassette = cassette
+		p.used = make([]bool, len(cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("invalid cassette mode: %s (must be record or replay)", mode)
+	}
+
+	return p, nil
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if cassette.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", cassette.Version, path)
+	}
+	return &cassette, nil
+}
+
+// Name returns the provider identifier
+func (p *CassetteProvider) Name() string {
+	if p.mode == CassetteModeReplay {
+		return "cassette:" + p.cassette.Provider
+	}
+	return p.inner.Name()
+}
+
+// CreateChatCompletion records or replays a blocking completion request
+func (p *CassetteProvider) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if p.mode == CassetteModeReplay {
+		return p.replay(request)
+	}
+
+	response, err := p.inner.CreateChatCompletion(ctx, request)
+	p.record(ctx, request, response, err)
+	return response, err
+}
+
+// CreateChatCompletionStream records a streamed request, or replays it by reporting the
+// recorded response to handler as a single delta
+func (p *CassetteProvider) CreateChatCompletionStream(ctx context.Context, request openai.ChatCompletionRequest, handler StreamHandler) (openai.ChatCompletionResponse, error) {
+	if p.mode == CassetteModeReplay {
+		response, err := p.replay(request)
+		if err == nil {
+			replayStreamEvents(response, handler)
+		}
+		return response, err
+	}
+
+	response, err := p.inner.CreateChatCompletionStream(ctx, request, handler)
+	p.record(ctx, request, response, err)
+	return response, err
+}
+
+// record appends one interaction and rewrites the cassette so a crashed run still leaves a usable file
+func (p *CassetteProvider) record(ctx context.Context, request openai.ChatCompletionRequest, response openai.ChatCompletionResponse, err error) {
+	// A cancelled call says nothing about the backend and would not replay meaningfully
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	interaction := CassetteInteraction{
+		Fingerprint:  requestFingerprint(request),
+		Conversation: conversationKey(request),
+	}
+
+	if redactErr := p.redactInto(request, &interaction.Request); redactErr != nil {
+		fmt.Printf("WARNING: Not recording request to cassette: %v\n", redactErr)
+		return
+	}
+	if err != nil {
+		interaction.Error = cassetteErrorFrom(err)
+		interaction.Error.Message = p.redactString(interaction.Error.Message)
+	} else {
+		interaction.Response = &openai.ChatCompletionResponse{}
+		if redactErr := p.redactInto(response, interaction.Response); redactErr != nil {
+			fmt.Printf("WARNING: Not recording response to cassette: %v\n", redactErr)
+			return
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cassette.Interactions = append(p.cassette.Interactions, interaction)
+	if saveErr := p.save(); saveErr != nil {
+		fmt.Printf("WARNING: Failed to write cassette: %v\n", saveErr)
+	}
+}
+
+// replay serves the recorded response for request. An exact fingerprint match is preferred;
+// otherwise the next unused interaction from the same conversation is served, so a run still
+// replays when tool results differ from the recording machine.
+func (p *CassetteProvider) replay(request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	fingerprint := requestFingerprint(request)
+	conversation := conversationKey(request)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	match := -1
+	for i, interaction := range p.cassette.Interactions {
+		if !p.used[i] && interaction.Fingerprint == fingerprint {
+			match = i
+			break
+		}
+	}
+	if match < 0 {
+		for i, interaction := range p.cassette.Interactions {
+			if !p.used[i] && interaction.Conversation == conversation {
+				fmt.Printf("WARNING: Cassette request %s drifted from the recording, replaying interaction %d of its conversation\n", fingerprint[:12], i)
+				match = i
+				break
+			}
+		}
+	}
+	if match < 0 {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("%w for model %s (fingerprint %s)", errCassetteMiss, request.Model, fingerprint[:12])
+	}
+
+	p.used[match] = true
+	interaction := p.cassette.Interactions[match]
+	fmt.Printf("DEBUG: Replaying cassette interaction %d for model %s\n", match, request.Model)
+
+	if interaction.Error != nil {
+		return openai.ChatCompletionResponse{}, interaction.Error.toError()
+	}
+	if interaction.Response == nil {
+		return openai.ChatCompletionResponse{}, fmt.Errorf("cassette interaction %d has neither response nor error", match)
+	}
+	return *interaction.Response, nil
+}
+
+// SetModel records the model a run uses, and the local model serving it when the provider is local
+func (p *CassetteProvider) SetModel(model string, localModel *LocalModel) error {
+	if p.mode != CassetteModeRecord {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cassette.Model = model
+	p.cassette.LocalModel = localModel
+	return p.save()
+}
+
+// RecordedModel returns the model a replayed run was recorded with. Cassettes recorded before the
+// model was kept fall back to the model of the first request.
+func (p *CassetteProvider) RecordedModel() string {
+	if p.cassette.Model != "" || len(p.cassette.Interactions) == 0 {
+		return p.cassette.Model
+	}
+	var request struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(p.cassette.Interactions[0].Request, &request)
+	return request.Model
+}
+
+// Remaining returns how many recorded interactions have not been replayed yet
+func (p *CassetteProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	remaining := 0
+	for _, used := range p.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// save writes the cassette atomically; callers hold p.mu except during construction
+func (p *CassetteProvider) save() error {
+	data, err := json.MarshalIndent(p.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+
+	tmpPath := p.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmpPath, p.path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// redactInto copies src into dst through JSON with secrets removed
+func (p *CassetteProvider) redactInto(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(p.redactString(string(data))), dst)
+}
+
+// redactString replaces configured secrets and anything that looks like a credential
+func (p *CassetteProvider) redactString(text string) string {
+	for _, secret := range p.secrets {
+		text = strings.ReplaceAll(text, secret, redactedSecret)
+	}
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, redactedSecret)
+	}
+	return text
+}
+
+// requestFingerprint hashes the parts of a request that determine the response.
+// Streaming is transport detail, so streamed and blocking calls share a fingerprint.
+func requestFingerprint(request openai.ChatCompletionRequest) string {
+	request.Stream = false
+	request.StreamOptions = nil
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%+v", request))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// conversationKey identifies the agent session a request belongs to by its model, system prompt
+// and first user message, the task, so parallel agents of one type keep apart
+func conversationKey(request openai.ChatCompletionRequest) string {
+	var prompt, task string
+	if len(request.Messages) > 0 {
+		prompt = request.Messages[0].Content
+	}
+	for _, msg := range request.Messages {
+		if msg.Role == openai.ChatMessageRoleUser {
+			task = msg.Content
+			break
+		}
+	}
+	sum := sha256.Sum256([]byte(request.Model + "\n" + prompt + "\n" + task))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cassetteErrorFrom captures the parts of an error that classifyError looks at
+func cassetteErrorFrom(err error) *CassetteError {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return &CassetteError{StatusCode: apiErr.HTTPStatusCode, Message: apiErr.Message, Type: apiErr.Type}
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return &CassetteError{StatusCode: reqErr.HTTPStatusCode, Message: string(reqErr.Body)}
+	}
+	return &CassetteError{Message: err.Error()}
+}
+
+// toError rebuilds an error that classifies the same way as the recorded one
+func (e *CassetteError) toError() error {
+	if e.StatusCode == 0 {
+		return errors.New(e.Message)
+	}
+	return &openai.APIError{HTTPStatusCode: e.StatusCode, Message: e.Message, Type: e.Type}
+}
+
+// replayStreamEvents reports a replayed response to a stream handler as if it had been streamed
+func replayStreamEvents(response openai.ChatCompletionResponse, handler StreamHandler) {
+	if handler == nil {
+		return
+	}
+	for _, choice := range response.Choices {
+		if choice.Message.Content != "" {
+			handler(StreamEvent{Type: StreamEventContent, Text: choice.Message.Content})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			handler(StreamEvent{
+				Type:       StreamEventToolCallDelta,
+				Text:       call.Function.Arguments,
+				ToolName:   call.Function.Name,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
@@ -3,18 +3,24 @@ package openrouter
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"gorka/internal/models"
 	"gorka/internal/openrouter/adapters"
 	"gorka/internal/tools"
+	"gorka/internal/types"
 	"gorka/internal/utils"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+// modelsFetchTimeout bounds the /models lookup done at client startup
+const modelsFetchTimeout = 10 * time.Second
+
 // customTransport wraps http.RoundTripper to add custom headers
 type customTransport struct {
 	Transport http.RoundTripper
@@ -29,13 +35,19 @@ func (t *customTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return t.Transport.RoundTrip(req)
 }
 
-// Client wraps the OpenAI client configured for OpenRouter
+// Client drives chat completions through the configured provider
 type Client struct {
-	client          *openai.Client
+	provider        Provider
 	config          *utils.Config
 	toolsManager    *tools.ToolsManager
 	openaiTools     []openai.Tool
 	adapterRegistry *adapters.AdapterRegistry
+	xmlAdapter      *adapters.XMLAdapter
+
+	supportsChatTemplateKwargs bool
+	supportsResponseFormat     bool
+	retryBaseDelay             time.Duration
+	retryMaxDelay              time.Duration
 }
 
 // NewClient creates a new OpenRouter client
@@ -50,24 +62,45 @@ func NewClientWithToolsManager(sharedToolsManager *tools.ToolsManager) (*Client,
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	var client *openai.Client
-
-	if config.UseOpenAI {
-		// Configure for direct OpenAI API
-		clientConfig := openai.DefaultConfig(config.OpenAIAPIKey)
-		client = openai.NewClientWithConfig(clientConfig)
-	} else {
-		// Configure OpenAI client for OpenRouter
-		httpClient := &http.Client{
-			Timeout:   time.Duration(config.RequestTimeout) * time.Second,
-			Transport: &customTransport{Transport: http.DefaultTransport},
+	provider, err := NewProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+	if config.CassetteMode != "" {
+		provider, err = NewCassetteProvider(config.CassetteMode, config.CassettePath, provider,
+			[]string{config.OpenRouterAPIKey, config.AnthropicAPIKey, config.OpenAIAPIKey, config.LocalAPIKey})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open cassette: %w", err)
 		}
+		fmt.Printf("DEBUG: Cassette %s mode using %s\n", config.CassetteMode, config.CassettePath)
+	}
+	fmt.Printf("DEBUG: Using %s provider\n", provider.Name())
 
-		clientConfig := openai.DefaultConfig(config.OpenRouterAPIKey)
-		clientConfig.BaseURL = config.OpenRouterBaseURL
-		clientConfig.HTTPClient = httpClient
+	if config.Provider == ProviderOpenRouter {
+		loadModelRegistry(config)
+	}
 
-		client = openai.NewClientWithConfig(clientConfig)
+	// Only vLLM understands chat_template_kwargs among the local servers
+	supportsChatTemplateKwargs, supportsResponseFormat := true, false
+	var localModel *LocalModel
+	if config.Provider == ProviderLocal {
+		var model LocalModel
+		if cassette, ok := provider.(*CassetteProvider); ok && config.CassetteMode == CassetteModeReplay {
+			model, err = replayedLocalModel(config, cassette)
+		} else {
+			model, err = configureLocalModel(config)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure local model: %w", err)
+		}
+		localModel = &model
+		supportsChatTemplateKwargs = model.SupportsChatTemplateKwargs()
+		supportsResponseFormat = model.SupportsResponseFormat()
+	}
+	if recorder, ok := provider.(*CassetteProvider); ok {
+		if err := recorder.SetModel(config.Model, localModel); err != nil {
+			return nil, err
+		}
 	}
 
 	// Use shared tools manager if provided, otherwise create new one
@@ -86,18 +119,31 @@ func NewClientWithToolsManager(sharedToolsManager *tools.ToolsManager) (*Client,
 	// Initialize adapter registry
 	adapterRegistry := adapters.NewAdapterRegistry()
 
+	// Replayed failures need no real backoff
+	retryBaseDelay, retryMaxDelay := defaultRetryBaseDelay, defaultRetryMaxDelay
+	if config.CassetteMode == CassetteModeReplay {
+		retryBaseDelay, retryMaxDelay = 0, 0
+	}
+
 	return &Client{
-		client:          client,
+		provider:        provider,
 		config:          config,
 		toolsManager:    toolsManager,
 		openaiTools:     openaiTools,
 		adapterRegistry: adapterRegistry,
+		xmlAdapter:      adapters.NewXMLAdapter(openaiTools),
+
+		supportsChatTemplateKwargs: supportsChatTemplateKwargs,
+		supportsResponseFormat:     supportsResponseFormat,
+		retryBaseDelay:             retryBaseDelay,
+		retryMaxDelay:              retryMaxDelay,
 	}, nil
 }
 
 // RefreshTools updates the tools list from the tools manager
 func (c *Client) RefreshTools() {
 	c.openaiTools = c.toolsManager.GetOpenAITools()
+	c.xmlAdapter = adapters.NewXMLAdapter(c.openaiTools)
 	fmt.Printf("DEBUG: Refreshed tools list - now have %d tools available\n", len(c.openaiTools))
 }
 
@@ -110,48 +156,55 @@ func (c *Client) GetAvailableToolNames() []string {
 	return toolNames
 }
 
-// ToolExecutionMetadata tracks tool execution during the conversation
-type ToolExecutionMetadata struct {
-	ToolsExecuted     int      `json:"tools_executed"`
-	ToolTypes         []string `json:"tool_types"`
-	ExecutionResults  []string `json:"execution_results"`
-	ExecutionMode     string   `json:"execution_mode"`
-	ToolCallsDetected int      `json:"tool_calls_detected"`
-	XMLToolsUsed      int      `json:"xml_tools_used"`
-	ErrorsEncountered []string `json:"errors_encountered"`
+// CompletionResult is a chat completion together with every model attempt made to produce it.
+// Tool calls are returned to the caller rather than run, so the caller owns every round.
+type CompletionResult struct {
+	Response *openai.ChatCompletionResponse
+	Attempts []ModelAttempt
+	// ToolCallMode says how the response's tool calls were found: openai_tools, adapter_tools or xml_tools
+	ToolCallMode string
+	// XMLToolCalls lists the calls the model wrote as XML in its content, for models without
+	// native tool calling; their results go back as a <function_results> user message
+	XMLToolCalls []XMLToolCall
 }
 
-// EnhancedChatCompletionResponse wraps the OpenAI response with tool execution metadata
-type EnhancedChatCompletionResponse struct {
-	*openai.ChatCompletionResponse
-	ToolExecutionMeta *ToolExecutionMetadata `json:"tool_execution_metadata"`
+// newToolCallRecord describes a finished tool call
+func newToolCallRecord(mode string, call openai.ToolCall, duration time.Duration, result string, err error) types.ToolCallRecord {
+	record := types.ToolCallRecord{
+		Name:       call.Function.Name,
+		Arguments:  call.Function.Arguments,
+		Mode:       mode,
+		DurationMs: duration.Milliseconds(),
+		ResultSize: len(result),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
 }
 
 // CreateChatCompletion creates a chat completion using OpenRouter with tool support and enhanced tracking
 func (c *Client) CreateChatCompletion(ctx context.Context, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionResponse, error) {
-	fmt.Printf("DEBUG: CreateChatCompletion called with %d messages\n", len(messages))
-
-	// Initialize tool execution tracking
-	toolMeta := &ToolExecutionMetadata{
-		ToolTypes:         []string{},
-		ExecutionResults:  []string{},
-		ErrorsEncountered: []string{},
+	result, err := c.CreateChatCompletionWithSettings(ctx, messages, nil)
+	if err != nil {
+		return nil, err
 	}
+	return result.Response, nil
+}
+
+// CreateChatCompletionWithSettings creates a chat completion using per-agent LLM settings,
+// walking the model fallback chain on failures; nil settings fall back to the global configuration.
+// Tool calls in the response, native, parsed by a model adapter or written as XML, are returned
+// without being run.
+func (c *Client) CreateChatCompletionWithSettings(ctx context.Context, messages []openai.ChatCompletionMessage, settings *types.LLMSettings) (*CompletionResult, error) {
+	fmt.Printf("DEBUG: CreateChatCompletion called with %d messages\n", len(messages))
 
 	fmt.Printf("DEBUG: Available tools count: %d\n", len(c.openaiTools))
 	for i, tool := range c.openaiTools {
 		fmt.Printf("DEBUG: Tool %d: %s\n", i, tool.Function.Name)
 	}
 
-	request := openai.ChatCompletionRequest{
-		Model:               c.config.Model,
-		Messages:            messages,
-		MaxCompletionTokens: c.config.MaxContextSize,
-		Temperature:         0.7,
-		Tools:               c.openaiTools,
-		ToolChoice:          "auto",
-		ParallelToolCalls:   false, // Disable parallel tool calls to prevent chaos
-	}
+	request := c.buildRequest(messages, settings)
 
 	// Log the request details
 	fmt.Printf("DEBUG: Creating OpenRouter request:\n")
@@ -172,37 +225,9 @@ func (c *Client) CreateChatCompletion(ctx context.Context, messages []openai.Cha
 		}
 	}
 
-	// Apply adapter-specific configuration recommendations
-	configRecs := c.adapterRegistry.GetConfigRecommendations(c.config.Model)
-	if configRecs.HasOptimizations {
-		fmt.Printf("DEBUG: %s\n", configRecs.DebugMessage)
-
-		// Apply ChatTemplateKwargs for vLLM/compatible services
-		if len(configRecs.ChatTemplateKwargs) > 0 {
-			request.ChatTemplateKwargs = configRecs.ChatTemplateKwargs
-			fmt.Printf("DEBUG: Applied ChatTemplateKwargs: %+v\n", configRecs.ChatTemplateKwargs)
-		}
-
-		// Enable parallel tool calls if recommended (DISABLED - causes chaos)
-		// if configRecs.ParallelToolCalls != nil {
-		//     request.ParallelToolCalls = *configRecs.ParallelToolCalls
-		//     fmt.Printf("DEBUG: Set ParallelToolCalls to: %t\n", *configRecs.ParallelToolCalls)
-		// }
-		// Always disable parallel tool calls to prevent chaos
-		request.ParallelToolCalls = false
-		fmt.Printf("DEBUG: ParallelToolCalls forcibly disabled\n")
-
-		// Apply optimized TopP sampling if recommended
-		if configRecs.TopP != nil {
-			request.TopP = *configRecs.TopP
-			fmt.Printf("DEBUG: Set TopP to: %.2f\n", *configRecs.TopP)
-		}
-	} else {
-		fmt.Printf("DEBUG: No adapter optimizations for model: %s\n", c.config.Model)
-	}
-
 	fmt.Printf("DEBUG: Sending request to API...\n")
-	response, err := c.createChatCompletionWithRetry(ctx, request, 3)
+	var attempts []ModelAttempt
+	response, model, err := c.createChatCompletionWithFallback(ctx, messages, settings, &attempts)
 	if err != nil {
 		// Enhanced error logging
 		fmt.Printf("DEBUG: API call failed with error: %v\n", err)
@@ -211,60 +236,53 @@ func (c *Client) CreateChatCompletion(ctx context.Context, messages []openai.Cha
 		fmt.Printf("DEBUG: Request had %d tools\n", len(request.Tools))
 
 		// Try to extract more details from the error if it's an OpenAI API error
-		if apiErr, ok := err.(*openai.APIError); ok {
+		var apiErr *openai.APIError
+		if errors.As(err, &apiErr) {
 			fmt.Printf("DEBUG: OpenAI API Error Details:\n")
 			fmt.Printf("  - HTTPStatusCode: %d\n", apiErr.HTTPStatusCode)
 			fmt.Printf("  - Code: %s\n", apiErr.Code)
 			fmt.Printf("  - Message: %s\n", apiErr.Message)
-			fmt.Printf("  - Param: %s\n", apiErr.Param)
+			if apiErr.Param != nil {
+				fmt.Printf("  - Param: %s\n", *apiErr.Param)
+			}
 			fmt.Printf("  - Type: %s\n", apiErr.Type)
 		}
 
 		return nil, fmt.Errorf("API call failed: %w", err)
 	}
 
-	// Handle tool calls if present - find first non-empty choice
-	if len(response.Choices) > 0 {
+	result := &CompletionResult{Response: &response, Attempts: attempts}
+
+	// Find tool calls in the first non-empty choice. A wrap-up turn looks for none, so the
+	// caller sees any calls the model made anyway.
+	if len(response.Choices) > 0 && !toolsDisabledFromContext(ctx) {
 		selectedChoice, _, found := c.selectNonEmptyChoice(response.Choices)
 		
 		if !found {
 			fmt.Printf("DEBUG: No non-empty choices found in response\n")
-			toolMeta.ExecutionMode = "no_tools"
-			c.attachToolMetadata(&response, toolMeta)
-			return &response, nil
+			return result, nil
 		}
+		// Callers read the first choice
+		response.Choices[0] = *selectedChoice
+		selectedChoice = &response.Choices[0]
 		
 		// First try OpenAI SDK tool calls
 		if len(selectedChoice.Message.ToolCalls) > 0 {
-			toolMeta.ToolCallsDetected = len(selectedChoice.Message.ToolCalls)
-			toolMeta.ExecutionMode = "openai_tools"
-
-			enhancedResponse, err := c.handleToolCallsWithTracking(ctx, messages, &response, selectedChoice, toolMeta)
-			if err != nil {
-				return nil, err
-			}
-			return enhancedResponse, nil
+			result.ToolCallMode = "openai_tools"
+			return result, nil
 		}
 
 		// Try adapter-based parsing for model-specific formats
 		content := selectedChoice.Message.Content
-		fmt.Printf("DEBUG: Checking adapter for model: %s, content length: %d\n", c.config.Model, len(content))
-		if adapter := c.adapterRegistry.GetAdapter(c.config.Model); adapter != nil {
+		fmt.Printf("DEBUG: Checking adapter for model: %s, content length: %d\n", model, len(content))
+		if adapter := c.adapterRegistry.GetAdapter(model); adapter != nil {
 			fmt.Printf("DEBUG: Found adapter: %s, attempting to parse tool calls\n", adapter.GetName())
 			if adaptedToolCalls, err := adapter.ParseToolCalls(content); err == nil && len(adaptedToolCalls) > 0 {
 				fmt.Printf("DEBUG: Adapter parsed %d tool calls successfully\n", len(adaptedToolCalls))
-				// Convert to OpenAI ToolCall format and handle them
+				// Hand them back in OpenAI ToolCall format
 				selectedChoice.Message.ToolCalls = adaptedToolCalls
-				
-				fmt.Printf("DEBUG: Set ToolCalls field to %d items\n", len(adaptedToolCalls))
-				toolMeta.ToolCallsDetected = len(adaptedToolCalls)
-				toolMeta.ExecutionMode = "adapter_tools"
-
-				enhancedResponse, err := c.handleToolCallsWithTracking(ctx, messages, &response, selectedChoice, toolMeta)
-				if err != nil {
-					return nil, err
-				}
-				return enhancedResponse, nil
+				result.ToolCallMode = "adapter_tools"
+				return result, nil
 			} else {
 				if err != nil {
 					fmt.Printf("DEBUG: Adapter parsing failed: %v\n", err)
@@ -273,42 +291,132 @@ func (c *Client) CreateChatCompletion(ctx context.Context, messages []openai.Cha
 				}
 			}
 		} else {
-			fmt.Printf("DEBUG: No adapter found for model: %s\n", c.config.Model)
+			fmt.Printf("DEBUG: No adapter found for model: %s\n", model)
+		}
+
+		// Models without native tool calling may still write XML function calls
+		if xmlToolCalls := c.parseXMLToolCalls(content); len(xmlToolCalls) > 0 {
+			fmt.Printf("DEBUG: Parsed %d XML tool calls\n", len(xmlToolCalls))
+			result.ToolCallMode = "xml_tools"
+			result.XMLToolCalls = xmlToolCalls
+		}
+	}
+
+	return result, nil
+}
+
+// buildRequest creates a chat completion request, applying agent settings over the
+// global configuration and then any adapter-specific recommendations for the model
+func (c *Client) buildRequest(messages []openai.ChatCompletionMessage, settings *types.LLMSettings) openai.ChatCompletionRequest {
+	request := openai.ChatCompletionRequest{
+		Model:               c.config.Model,
+		Messages:            messages,
+		MaxCompletionTokens: c.config.MaxContextSize,
+		Temperature:         0.7,
+		Tools:               c.openaiTools,
+		ToolChoice:          "auto",
+		ParallelToolCalls:   c.config.MaxParallelTools > 1,
+		ReasoningEffort:     c.config.ReasoningEffort,
+	}
+
+	if settings != nil {
+		if settings.Model != "" {
+			request.Model = settings.Model
+		}
+		if settings.Temperature != nil {
+			request.Temperature = *settings.Temperature
+		}
+		if settings.MaxTokens > 0 {
+			request.MaxCompletionTokens = settings.MaxTokens
+		}
+		if settings.ReasoningEffort != "" {
+			request.ReasoningEffort = settings.ReasoningEffort
 		}
 	}
 
-	// No tools executed
-	toolMeta.ExecutionMode = "no_tools"
+	// Apply adapter-specific configuration recommendations
+	configRecs := c.adapterRegistry.GetConfigRecommendations(request.Model)
+	if configRecs.HasOptimizations {
+		fmt.Printf("DEBUG: %s\n", configRecs.DebugMessage)
+
+		// Apply ChatTemplateKwargs for vLLM/compatible services
+		if len(configRecs.ChatTemplateKwargs) > 0 && c.supportsChatTemplateKwargs {
+			request.ChatTemplateKwargs = configRecs.ChatTemplateKwargs
+			fmt.Printf("DEBUG: Applied ChatTemplateKwargs: %+v\n", configRecs.ChatTemplateKwargs)
+		} else if len(configRecs.ChatTemplateKwargs) > 0 {
+			fmt.Printf("DEBUG: Skipped ChatTemplateKwargs, backend does not support them\n")
+		}
+
+		// Models that degrade on parallel calls can opt out; the scheduler keeps the rest safe
+		if configRecs.ParallelToolCalls != nil && !*configRecs.ParallelToolCalls {
+			request.ParallelToolCalls = false
+			fmt.Printf("DEBUG: ParallelToolCalls disabled for model: %s\n", request.Model)
+		}
 
-	// Add metadata to the response (we'll store it in a custom field)
-	c.attachToolMetadata(&response, toolMeta)
+		// Apply optimized TopP sampling if recommended
+		if configRecs.TopP != nil {
+			request.TopP = *configRecs.TopP
+			fmt.Printf("DEBUG: Set TopP to: %.2f\n", *configRecs.TopP)
+		}
+	} else {
+		fmt.Printf("DEBUG: No adapter optimizations for model: %s\n", request.Model)
+	}
 
-	return &response, nil
+	// Explicit agent settings win over adapter recommendations
+	if settings != nil && settings.TopP != nil {
+		request.TopP = *settings.TopP
+		fmt.Printf("DEBUG: Set TopP from agent settings to: %.2f\n", *settings.TopP)
+	}
+
+	return request
 }
 
-// HealthCheck validates OpenRouter connectivity and model availability
+// HealthCheck validates connectivity and model availability without spending a completion.
+// OpenRouter's model list is fetched fresh; other providers are only checked against the registry.
 func (c *Client) HealthCheck(ctx context.Context) error {
-	// Simple test message to validate connectivity
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: "Hello",
-		},
+	if c.config.CassetteMode == CassetteModeReplay {
+		// Replays run offline, so the cassette stands in for the provider's model list
+		if cassette, ok := c.provider.(*CassetteProvider); ok {
+			if recorded := cassette.RecordedModel(); recorded != "" && recorded != c.config.Model {
+				return fmt.Errorf("%s health check failed: the cassette was recorded with %s, not %s", c.provider.Name(), recorded, c.config.Model)
+			}
+		}
+	} else if c.config.Provider == ProviderOpenRouter {
+		if err := models.Refresh(ctx, c.config.Workspace, c.config.OpenRouterBaseURL, nil); err != nil {
+			return fmt.Errorf("%s health check failed: %w", c.provider.Name(), err)
+		}
+		if _, ok := models.Lookup(c.config.Model); !ok {
+			return fmt.Errorf("%s health check failed: model %s is not listed", c.provider.Name(), c.config.Model)
+		}
 	}
 
-	request := openai.ChatCompletionRequest{
-		Model:               c.config.Model,
-		Messages:            messages,
-		MaxCompletionTokens: 10,
-		Temperature:         0.0,
+	if len(c.openaiTools) > 0 && !models.SupportsTools(c.config.Model) {
+		return fmt.Errorf("%s health check failed: %s: %w", c.provider.Name(), c.config.Model, ErrToolsUnsupported)
 	}
+	return nil
+}
 
-	_, err := c.client.CreateChatCompletion(ctx, request)
-	if err != nil {
-		return fmt.Errorf("OpenRouter health check failed: %w", err)
+// loadModelRegistry fills the model registry from OpenRouter or the workspace cache; models
+// it cannot describe fall back to the static tables
+func loadModelRegistry(config *utils.Config) {
+	// Replays run offline, so only the cache is read
+	baseURL := config.OpenRouterBaseURL
+	if config.CassetteMode == CassetteModeReplay {
+		baseURL = ""
 	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), modelsFetchTimeout)
+	defer cancel()
+
+	source, err := models.Load(ctx, config.Workspace, baseURL, nil)
+	if source == "" {
+		fmt.Printf("WARNING: Using static model capabilities: %v\n", err)
+		return
+	}
+	if err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+	}
+	fmt.Printf("DEBUG: Loaded model capabilities (%s)\n", source)
 }
 
 // GetModel returns the configured model name
@@ -341,26 +449,134 @@ func (c *Client) selectNonEmptyChoice(choices []openai.ChatCompletionChoice) (*o
 	return nil, -1, false
 }
 
-// createChatCompletionWithRetry attempts to create a chat completion with retry logic for empty responses
-func (c *Client) createChatCompletionWithRetry(ctx context.Context, request openai.ChatCompletionRequest, maxRetries int) (openai.ChatCompletionResponse, error) {
+// modelChain returns the primary model followed by its fallbacks, without duplicates
+func (c *Client) modelChain(settings *types.LLMSettings) []string {
+	primary := c.config.Model
+	fallbacks := c.config.FallbackModels
+	if settings != nil {
+		if settings.Model != "" {
+			primary = settings.Model
+		}
+		if len(settings.FallbackModels) > 0 {
+			fallbacks = settings.FallbackModels
+		}
+	}
+
+	chain := []string{primary}
+	seen := map[string]bool{primary: true}
+	for _, model := range fallbacks {
+		if model != "" && !seen[model] {
+			chain = append(chain, model)
+			seen[model] = true
+		}
+	}
+	return chain
+}
+
+// createChatCompletionWithFallback tries each model in the chain until one succeeds, returning
+// the response and the model that produced it; every attempt is appended to attempts
+func (c *Client) createChatCompletionWithFallback(ctx context.Context, messages []openai.ChatCompletionMessage, settings *types.LLMSettings, attempts *[]ModelAttempt) (openai.ChatCompletionResponse, string, error) {
+	chain := c.modelChain(settings)
+
+	var lastErr error
+	for i, model := range chain {
+		request := c.buildRequest(messages, settings.Merge(&types.LLMSettings{Model: model}))
+		if output := structuredOutputFromContext(ctx); output != nil && c.supportsStructuredOutputs(model, settings) {
+			request.ResponseFormat = output.responseFormat()
+			fmt.Printf("DEBUG: Requesting %s structured output from %s\n", output.name, model)
+		}
+		if toolsDisabledFromContext(ctx) {
+			// No tool will be called, so a model without tool support can still answer
+			if models.SupportsTools(model) {
+				request.ToolChoice = "none"
+			} else {
+				request.Tools, request.ToolChoice, request.ParallelToolCalls = nil, nil, nil
+			}
+		} else if len(request.Tools) > 0 && !models.SupportsTools(model) {
+			lastErr = fmt.Errorf("%s: %w", model, ErrToolsUnsupported)
+			*attempts = append(*attempts, ModelAttempt{Model: model, Outcome: string(ErrorClassToolsUnsupported), Error: lastErr.Error()})
+			fmt.Printf("DEBUG: Model %s does not support tool calling, skipping it\n", model)
+			continue
+		}
+
+		response, err := c.createChatCompletionWithRetry(ctx, request, c.config.MaxAttemptsPerModel, attempts)
+		if err == nil {
+			if i > 0 {
+				fmt.Printf("DEBUG: Fallback model %s succeeded after %d hops\n", model, i)
+			}
+			return response, model, nil
+		}
+		lastErr = err
+
+		class := classifyError(err)
+		if class == ErrorClassFatal || ctx.Err() != nil {
+			fmt.Printf("DEBUG: Model %s failed with %s error, not falling back\n", model, class)
+			break
+		}
+		if i < len(chain)-1 {
+			fmt.Printf("DEBUG: Model %s failed with %s error, falling back to %s\n", model, class, chain[i+1])
+		}
+	}
+
+	return openai.ChatCompletionResponse{}, "", &FallbackError{
+		Attempts: append([]ModelAttempt(nil), (*attempts)...),
+		Err:      lastErr,
+	}
+}
+
+// createChatCompletionWithRetry calls a single model, retrying retryable errors with backoff
+// and empty responses immediately
+func (c *Client) createChatCompletionWithRetry(ctx context.Context, request openai.ChatCompletionRequest, maxRetries int, attempts *[]ModelAttempt) (openai.ChatCompletionResponse, error) {
+	if maxRetries <= 0 {
+		maxRetries = 1
+	}
+
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		fmt.Printf("DEBUG: API call attempt %d/%d\n", attempt, maxRetries)
-		
-		response, err := c.client.CreateChatCompletion(ctx, request)
+		fmt.Printf("DEBUG: API call attempt %d/%d (model %s)\n", attempt, maxRetries, request.Model)
+
+		hint := &retryAfterHint{}
+		started := time.Now()
+		response, err := c.sendChatCompletion(withRetryAfterHint(ctx, hint), request)
+		record := ModelAttempt{
+			Model:      request.Model,
+			Attempt:    attempt,
+			DurationMs: time.Since(started).Milliseconds(),
+		}
+
 		if err != nil {
-			fmt.Printf("DEBUG: Attempt %d failed with error: %v\n", attempt, err)
-			if attempt == maxRetries {
+			class := classifyError(err)
+			record.Outcome = string(class)
+			record.StatusCode, _ = errorStatus(err)
+			record.Error = err.Error()
+			fmt.Printf("DEBUG: Attempt %d failed with %s error: %v\n", attempt, class, err)
+
+			if class != ErrorClassRetryable || attempt == maxRetries || ctx.Err() != nil {
+				*attempts = append(*attempts, record)
+				return openai.ChatCompletionResponse{}, err
+			}
+
+			delay := backoffDelay(attempt, hint.get(), c.retryBaseDelay, c.retryMaxDelay)
+			record.BackoffMs = delay.Milliseconds()
+			*attempts = append(*attempts, record)
+
+			fmt.Printf("DEBUG: Backing off %v before retrying\n", delay)
+			if err := sleepWithContext(ctx, delay); err != nil {
 				return openai.ChatCompletionResponse{}, err
 			}
 			continue
 		}
-		
+
+		usage := reasoningUsage(request.Model, response)
+		record.Usage = &usage
+
 		// Check if we have choices
 		if len(response.Choices) > 0 {
 			// Check if we have at least one non-empty choice
 			selectedChoice, _, found := c.selectNonEmptyChoice(response.Choices)
 			if found {
 				fmt.Printf("DEBUG: Attempt %d succeeded with %d choices\n", attempt, len(response.Choices))
+				record.Outcome = "success"
+				*attempts = append(*attempts, record)
 				return response, nil
 			}
 			fmt.Printf("DEBUG: Attempt %d returned %d choices but all were empty\n", attempt, len(response.Choices))
@@ -368,18 +584,32 @@ func (c *Client) createChatCompletionWithRetry(ctx context.Context, request open
 			// Special case: If this is a thinking tool call with next_thought_needed=false, don't retry
 			if c.isThinkingComplete(selectedChoice) {
 				fmt.Printf("DEBUG: Thinking is complete (next_thought_needed=false), not retrying\n")
+				record.Outcome = "success"
+				*attempts = append(*attempts, record)
 				return response, nil
 			}
 		} else {
 			fmt.Printf("DEBUG: Attempt %d returned 0 choices\n", attempt)
 		}
+
+		record.Outcome = string(ErrorClassEmptyResponse)
+		*attempts = append(*attempts, record)
 		
 		if attempt < maxRetries {
 			fmt.Printf("DEBUG: Retrying API call...\n")
 		}
 	}
 	
-	return openai.ChatCompletionResponse{}, fmt.Errorf("all %d attempts returned empty or no choices", maxRetries)
+	return openai.ChatCompletionResponse{}, fmt.Errorf("all %d attempts returned empty or no choices: %w", maxRetries, errEmptyResponse)
+}
+
+// sendChatCompletion performs a single API call, streaming when a handler is registered on ctx
+func (c *Client) sendChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if handler := streamHandlerFromContext(ctx); handler != nil && c.config.EnableStreaming {
+		fmt.Printf("DEBUG: Streaming chat completion\n")
+		return c.provider.CreateChatCompletionStream(ctx, request, handler)
+	}
+	return c.provider.CreateChatCompletion(ctx, request)
 }
 
 // isThinkingComplete checks if a choice contains a think_hard tool call with next_thought_needed=false
@@ -406,99 +636,70 @@ func (c *Client) isThinkingComplete(choice *openai.ChatCompletionChoice) bool {
 	return false
 }
 
-// handleToolCallsWithTracking processes tool calls with enhanced tracking
-func (c *Client) handleToolCallsWithTracking(ctx context.Context, originalMessages []openai.ChatCompletionMessage, response *openai.ChatCompletionResponse, selectedChoice *openai.ChatCompletionChoice, toolMeta *ToolExecutionMetadata) (*openai.ChatCompletionResponse, error) {
-	// Add the assistant's message with tool calls
-	messages := append(originalMessages, selectedChoice.Message)
-
-	// Execute each tool call with tracking
-	for _, toolCall := range selectedChoice.Message.ToolCalls {
-		toolResult, err := c.executeToolCall(toolCall)
-		if err != nil {
-			toolMeta.ErrorsEncountered = append(toolMeta.ErrorsEncountered, fmt.Sprintf("Tool %s: %v", toolCall.Function.Name, err))
-			toolResult = fmt.Sprintf("Error: %v", err)
-		} else {
-			toolMeta.ToolsExecuted++
-			toolMeta.ToolTypes = append(toolMeta.ToolTypes, toolCall.Function.Name)
-			toolMeta.ExecutionResults = append(toolMeta.ExecutionResults, fmt.Sprintf("%s executed successfully", toolCall.Function.Name))
-		}
+// XMLToolCall represents a parsed XML tool call
+type XMLToolCall struct {
+	Function   string
+	Parameters map[string]string
+}
 
-		// Add tool result message
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:       openai.ChatMessageRoleTool,
-			Content:    toolResult,
-			ToolCallID: toolCall.ID,
-		})
-	}
-
-	// Continue conversation with tool results
-	request := openai.ChatCompletionRequest{
-		Model:               c.config.Model,
-		Messages:            messages,
-		MaxCompletionTokens: c.config.MaxContextSize,
-		Temperature:         0.7,
-		Tools:               c.openaiTools,
-		ToolChoice:          "auto",
-		ParallelToolCalls:   false, // Disable parallel tool calls to prevent chaos
+// toolCall converts the call to the OpenAI form used for records
+func (x XMLToolCall) toolCall() openai.ToolCall {
+	arguments, _ := json.Marshal(x.Parameters)
+	return openai.ToolCall{
+		Type:     openai.ToolTypeFunction,
+		Function: openai.FunctionCall{Name: x.Function, Arguments: string(arguments)},
 	}
+}
 
-	newResponse, err := c.createChatCompletionWithRetry(ctx, request, 3)
-	if err != nil {
-		return nil, fmt.Errorf("follow-up call failed: %w", err)
-	}
-	if len(newResponse.Choices) == 0 {
-		return nil, fmt.Errorf("follow-up call returned no choices after retries")
+// parseXMLToolCalls extracts XML tool calls from response content
+func (c *Client) parseXMLToolCalls(content string) []XMLToolCall {
+	var xmlToolCalls []XMLToolCall
+	for _, call := range c.xmlAdapter.ParseXMLCalls(content) {
+		xmlToolCalls = append(xmlToolCalls, XMLToolCall(call))
 	}
-	
-	// Find first non-empty choice in follow-up response
-	followUpChoice, _, found := c.selectNonEmptyChoice(newResponse.Choices)
-	if !found {
-		return nil, fmt.Errorf("follow-up call returned empty content in all choices")
-	}
-
-	// Update the response to use the selected choice as the first choice for consistency
-	newResponse.Choices[0] = *followUpChoice
-
-	// Attach tool metadata to the response
-	c.attachToolMetadata(&newResponse, toolMeta)
-
-	return &newResponse, nil
+	return xmlToolCalls
 }
 
-// attachToolMetadata attaches tool execution metadata to the response
-func (c *Client) attachToolMetadata(response *openai.ChatCompletionResponse, toolMeta *ToolExecutionMetadata) {
-	// We'll store the metadata in the response's Object field or as a special marker in the content
-	// Since we can't modify the OpenAI struct directly, we'll use a custom approach
-	if len(response.Choices) > 0 {
-		// Add a special metadata comment to track tool execution
-		metadataJSON, _ := json.Marshal(toolMeta)
-		originalContent := response.Choices[0].Message.Content
-
-		// Add metadata as a special comment that can be parsed later
-		response.Choices[0].Message.Content = originalContent + fmt.Sprintf("\n\n<!-- TOOL_EXECUTION_METADATA: %s -->", string(metadataJSON))
+// xmlResultsMessage reports the results of XML tool calls as a <function_results> user
+// message, since models writing XML calls have no tool role to read; errs[i] is the error of calls[i]
+func xmlResultsMessage(calls []XMLToolCall, results []string, errs []error, shown []openai.ChatMessagePart) openai.ChatCompletionMessage {
+	var b strings.Builder
+	b.WriteString("<function_results>\n")
+	for i, call := range calls {
+		if errs[i] != nil {
+			fmt.Fprintf(&b, "<error>\n<tool_name>%s</tool_name>\n<stderr>\n%v\n</stderr>\n</error>\n", call.Function, errs[i])
+			continue
+		}
+		fmt.Fprintf(&b, "<result>\n<tool_name>%s</tool_name>\n<stdout>\n%s\n</stdout>\n</result>\n", call.Function, results[i])
 	}
-}
+	b.WriteString("</function_results>")
 
-// XMLToolCall represents a parsed XML tool call
-type XMLToolCall struct {
-	Function   string
-	Parameters map[string]string
+	if len(shown) > 0 {
+		// A message carries either text or parts, so the results become the first part
+		return imageMessage(append([]openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: b.String()}}, shown...))
+	}
+	return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: b.String()}
 }
 
 // executeXMLToolCall executes a specific XML tool call using the centralized tool system
-func (c *Client) executeXMLToolCall(xmlToolCall XMLToolCall) (string, error) {
-	// Convert XML parameters to map[string]interface{} for tool execution
-	params := make(map[string]interface{})
-	for key, value := range xmlToolCall.Parameters {
-		params[key] = value
+func (c *Client) executeXMLToolCall(ctx context.Context, xmlToolCall XMLToolCall) (string, error) {
+	// Coerce XML text parameters to the types the tool schema declares
+	params, err := c.xmlAdapter.CoerceParameters(xmlToolCall.Function, xmlToolCall.Parameters)
+	if err != nil {
+		return "", err
 	}
 
 	// Use the centralized tool execution system
-	return c.toolsManager.ExecuteOpenAITool(xmlToolCall.Function, params)
+	return c.toolsManager.ExecuteOpenAITool(ctx, xmlToolCall.Function, params)
+}
+
+// scheduler returns the tool scheduler for one turn's tool calls
+func (c *Client) scheduler() *toolScheduler {
+	return newToolScheduler(c.config.Workspace, c.config.MaxParallelTools)
 }
 
 // executeToolCall executes a specific tool call using the centralized tool system
-func (c *Client) executeToolCall(toolCall openai.ToolCall) (string, error) {
+func (c *Client) executeToolCall(ctx context.Context, toolCall openai.ToolCall) (string, error) {
 	// Debug logging to understand the parsing issue
 	fmt.Printf("DEBUG: Tool call function name: %s\n", toolCall.Function.Name)
 	fmt.Printf("DEBUG: Tool call arguments: %s\n", toolCall.Function.Arguments)
@@ -509,5 +710,5 @@ func (c *Client) executeToolCall(toolCall openai.ToolCall) (string, error) {
 	}
 
 	// Use the centralized tool execution system
-	return c.toolsManager.ExecuteOpenAITool(toolCall.Function.Name, params)
+	return c.toolsManager.ExecuteOpenAITool(ctx, toolCall.Function.Name, params)
 }
@@ -0,0 +1,124 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gorka/internal/models"
+	"gorka/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestBuildRequestAppliesAgentSettings(t *testing.T) {
+	client := newTestClient("http://unused", false)
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}}
+
+	defaults := client.buildRequest(messages, nil)
+	if defaults.Model != "test/model" || defaults.Temperature != 0.7 || defaults.MaxCompletionTokens != 1024 {
+		t.Errorf("unexpected defaults: model=%s temperature=%v max=%d", defaults.Model, defaults.Temperature, defaults.MaxCompletionTokens)
+	}
+
+	temperature := float32(0.1)
+	topP := float32(0.5)
+	request := client.buildRequest(messages, &types.LLMSettings{
+		Model:           "other/model",
+		Temperature:     &temperature,
+		TopP:            &topP,
+		MaxTokens:       4096,
+		ReasoningEffort: "low",
+	})
+	if request.Model != "other/model" {
+		t.Errorf("expected model override, got %s", request.Model)
+	}
+	if request.Temperature != 0.1 || request.TopP != 0.5 {
+		t.Errorf("expected sampling overrides, got temperature=%v top_p=%v", request.Temperature, request.TopP)
+	}
+	if request.MaxCompletionTokens != 4096 || request.ReasoningEffort != "low" {
+		t.Errorf("unexpected max tokens or reasoning effort: %d %s", request.MaxCompletionTokens, request.ReasoningEffort)
+	}
+}
+
+func TestToolRequestsSkipModelsWithoutTools(t *testing.T) {
+	models.Register(models.Capabilities{ID: "test/no-tools", SupportsTools: false})
+
+	var requested []string
+	var sent openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		requested = append(requested, request.Model)
+		sent = request
+		replyContent(w, request.Model, "done")
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, false)
+	client.openaiTools = []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "read_file"}}}
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}}
+
+	_, err := client.CreateChatCompletionWithSettings(context.Background(), messages, &types.LLMSettings{Model: "test/no-tools"})
+	if !errors.Is(err, ErrToolsUnsupported) {
+		t.Fatalf("expected ErrToolsUnsupported, got %v", err)
+	}
+	if len(requested) != 0 {
+		t.Fatalf("expected no request to be sent, got %v", requested)
+	}
+
+	result, err := client.CreateChatCompletionWithSettings(context.Background(), messages, &types.LLMSettings{
+		Model:          "test/no-tools",
+		FallbackModels: []string{"test/model"},
+	})
+	if err != nil {
+		t.Fatalf("expected the fallback model to answer: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != "test/model" {
+		t.Errorf("expected only the fallback model to be called, got %v", requested)
+	}
+	if result.Attempts[0].Outcome != string(ErrorClassToolsUnsupported) {
+		t.Errorf("expected the skipped model in the attempts, got %+v", result.Attempts)
+	}
+
+	// With tools disabled the model is asked without them
+	requested = nil
+	if _, err := client.CreateChatCompletionWithSettings(withToolsDisabled(context.Background()), messages, &types.LLMSettings{Model: "test/no-tools"}); err != nil {
+		t.Fatalf("expected the model to answer with tools disabled: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != "test/no-tools" || len(sent.Tools) != 0 || sent.ToolChoice != nil {
+		t.Errorf("expected one request to test/no-tools without tools, got %v with %d tools", requested, len(sent.Tools))
+	}
+}
+
+func TestHealthCheckUsesModelList(t *testing.T) {
+	completions := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			completions++
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"health/listed","context_length":8192,"supported_parameters":["tools"]}]}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, false)
+	client.config.Provider = ProviderOpenRouter
+	client.config.OpenRouterBaseURL = server.URL
+	client.config.Workspace = t.TempDir()
+
+	client.config.Model = "health/listed"
+	if err := client.HealthCheck(context.Background()); err != nil {
+		t.Errorf("expected a listed model to pass: %v", err)
+	}
+	client.config.Model = "health/missing"
+	if err := client.HealthCheck(context.Background()); err == nil {
+		t.Error("expected an unlisted model to fail")
+	}
+	if completions != 0 {
+		t.Errorf("expected no completion calls, got %d", completions)
+	}
+}
@@ -0,0 +1,108 @@
+package openrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorka/internal/session"
+	"gorka/internal/types"
+	"gorka/internal/usage"
+	"github.com/sashabaranov/go-openai"
+)
+
+// compactionPrompt asks for working memory the agent can carry on from without the folded turns
+const compactionPrompt = `You maintain the working memory of an AI agent whose conversation has grown too long. You are given the current working memory, which may be empty, and the turns that are about to be removed from the conversation. Rewrite the working memory so the agent can continue without those turns.
+
+Keep, as concise bullet points under these headings:
+## Task
+The task as the user stated it, including any follow-up requests.
+## Files
+Every file path read, created or changed, with what was learned from or done to each file. Keep exact names of functions, types and settings that matter.
+## Findings and decisions
+Facts established and decisions made, with the reason when one was given.
+## Open TODOs
+Work still to do, questions still open and errors not yet resolved.
+
+Drop greetings, repetition and raw file contents that are not needed to continue. Answer with the working memory only.`
+
+// maxCompactedToolResult caps how much of one tool result is shown to the summarizing model
+const maxCompactedToolResult = 20000
+
+// compactionStrategy summarizes older turns of an agent session with the compaction model, or the
+// agent's own model, and charges the call to the run like any other
+func (s *AgentSpawner) compactionStrategy(agentSession *session.AgentSession, settings *types.LLMSettings, run *usage.Run, result *AgentRunResult) session.CompactionStrategy {
+	if model := s.client.config.CompactionModel; model != "" {
+		settings = settings.Merge(&types.LLMSettings{Model: model})
+	}
+
+	return session.CompactionFunc(func(ctx context.Context, memory string, turns []openai.ChatCompletionMessage) (string, error) {
+		messages := []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: compactionPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: renderCompaction(memory, turns)},
+		}
+
+		// The summary is neither streamed to the client, nor shaped by the agent's output schema
+		ctx = withStructuredOutput(withToolsDisabled(ctx), nil)
+		ctx = WithStreamHandler(ctx, nil)
+
+		completion, err := s.client.CreateChatCompletionWithSettings(ctx, messages, settings)
+		if err != nil {
+			var fallbackErr *FallbackError
+			if errors.As(err, &fallbackErr) {
+				s.recordUsage(run, agentSession, fallbackErr.Attempts, result)
+			}
+			return "", err
+		}
+		s.recordUsage(run, agentSession, completion.Attempts, result)
+
+		if len(completion.Response.Choices) == 0 {
+			return "", errors.New("compaction returned no choices")
+		}
+		summary := strings.TrimSpace(completion.Response.Choices[0].Message.Content)
+		if summary == "" {
+			return "", errors.New("compaction returned an empty summary")
+		}
+		return summary, nil
+	})
+}
+
+// renderCompaction writes the working memory and the turns being folded as plain text
+func renderCompaction(memory string, turns []openai.ChatCompletionMessage) string {
+	var b strings.Builder
+	b.WriteString("# Current working memory\n\n")
+	if memory == "" {
+		b.WriteString("(empty)\n")
+	} else {
+		b.WriteString(memory + "\n")
+	}
+
+	b.WriteString("\n# Turns to fold into it\n")
+	for _, msg := range turns {
+		content := msg.Content
+		for _, part := range msg.MultiContent {
+			if part.Type == openai.ChatMessagePartTypeText {
+				content += part.Text
+			} else {
+				content += "[image]"
+			}
+		}
+
+		switch msg.Role {
+		case openai.ChatMessageRoleTool:
+			if len(content) > maxCompactedToolResult {
+				content = content[:maxCompactedToolResult] + "...[tool result truncated]"
+			}
+			fmt.Fprintf(&b, "\n[tool result %s]\n%s\n", msg.ToolCallID, content)
+		case openai.ChatMessageRoleAssistant:
+			fmt.Fprintf(&b, "\n[assistant]\n%s\n", content)
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&b, "[assistant called %s %s with %s]\n", call.Function.Name, call.ID, call.Function.Arguments)
+			}
+		default:
+			fmt.Fprintf(&b, "\n[%s]\n%s\n", msg.Role, content)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorka/internal/models"
+	"gorka/internal/session"
+	"gorka/internal/tools"
+	"gorka/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestRenderCompactionShowsCallsAndResults(t *testing.T) {
+	rendered := renderCompaction("", []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "review main.go"},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{ID: "call_1", Function: openai.FunctionCall{Name: "read_file", Arguments: `{"file_path":"main.go"}`}}}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: strings.Repeat("x", maxCompactedToolResult+10)},
+		{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,AAAA"}}}},
+	})
+
+	for _, want := range []string{"(empty)", "[user]\nreview main.go", `[assistant called read_file call_1 with {"file_path":"main.go"}]`, "[tool result call_1]", "...[tool result truncated]", "[image]"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("Expected %q in the rendered turns:\n%s", want, rendered)
+		}
+	}
+	if strings.Contains(rendered, "base64") {
+		t.Error("Expected image data to be left out")
+	}
+}
+
+func TestLongConversationIsCompacted(t *testing.T) {
+	workspace := t.TempDir()
+	for i := 0; i < 12; i++ {
+		content := fmt.Sprintf("package main // file %d\n", i) + strings.Repeat("func helper() int { return 42 }\n", 30)
+		os.WriteFile(filepath.Join(workspace, fmt.Sprintf("f%d.go", i)), []byte(content), 0644)
+	}
+
+	var agentRequests, compactions []openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Messages[0].Content == compactionPrompt {
+			compactions = append(compactions, request)
+			replyContent(w, "test/summary-model", "## Files\n- f0.go: helpers returning 42")
+			return
+		}
+
+		agentRequests = append(agentRequests, request)
+		if len(agentRequests) > 12 {
+			replyContent(w, "test/compaction-model", "All files reviewed.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"gen-1","model":"test/compaction-model","choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_%d","type":"function","function":{"name":"read_file","arguments":"{\"file_path\":\"f%d.go\"}"}}]},"finish_reason":"tool_calls"}]}`, len(agentRequests), len(agentRequests)-1)
+	}))
+	defer server.Close()
+
+	toolsManager := tools.NewToolsManager(workspace, filepath.Join(workspace, "storage"))
+	client := newTestClient(server.URL, false)
+	client.toolsManager = toolsManager
+	client.config.Model = "test/compaction-model"
+	client.config.CompactionThreshold = 0.5
+	client.config.CompactionModel = "test/summary-model"
+	spawner := &AgentSpawner{
+		client:         client,
+		toolsManager:   toolsManager,
+		sessionManager: session.NewSessionManagerWithDir(filepath.Join(workspace, "sessions")),
+	}
+
+	// Leave about 3000 tokens for the conversation, so the reads cross half of it
+	models.Register(models.Capabilities{ID: "test/compaction-model", ContextLength: spawner.contextBudget(nil).Reserved+3000, SupportsTools: true})
+
+	result, err := spawner.SpawnAgentWithContext(context.Background(), &types.BehavioralMatrix{AgentID: "test_agent"}, "review every file")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+
+	if len(compactions) == 0 {
+		t.Fatal("Expected the conversation to be compacted")
+	}
+	if compactions[0].Model != "test/summary-model" || compactions[0].ToolChoice != "none" {
+		t.Errorf("Expected a tool-free call to the compaction model, got model %s, tool choice %v", compactions[0].Model, compactions[0].ToolChoice)
+	}
+	if !strings.Contains(compactions[0].Messages[1].Content, "review every file") {
+		t.Error("Expected the original task among the folded turns")
+	}
+
+	last := agentRequests[len(agentRequests)-1]
+	if last.Messages[1].Role != openai.ChatMessageRoleSystem || !strings.HasPrefix(last.Messages[1].Content, session.WorkingMemoryHeader) {
+		t.Errorf("Expected the working memory after the system prompt, got %+v", last.Messages[1])
+	}
+	agentSession, _ := spawner.GetSession(result.SessionID)
+	if agentSession.CompactedMessages == 0 || !strings.Contains(agentSession.WorkingMemory, "f0.go") {
+		t.Errorf("Expected the working memory to be stored on the session, got %q", agentSession.WorkingMemory)
+	}
+}
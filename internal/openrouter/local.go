@@ -0,0 +1,213 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorka/internal/models"
+	"gorka/internal/utils"
+)
+
+// localDiscoveryTimeout bounds the /v1/models lookup done at client startup
+const localDiscoveryTimeout = 10 * time.Second
+
+// LocalBackend identifies the server software behind a local provider
+type LocalBackend string
+
+const (
+	LocalBackendVLLM     LocalBackend = "vllm"
+	LocalBackendLlamaCpp LocalBackend = "llamacpp"
+	LocalBackendOllama   LocalBackend = "ollama"
+	LocalBackendUnknown  LocalBackend = "unknown"
+)
+
+// LocalModel describes a model reported by a local server's /v1/models endpoint
+type LocalModel struct {
+	ID            string       `json:"id"`
+	OwnedBy       string       `json:"owned_by,omitempty"`
+	Backend       LocalBackend `json:"backend"`
+	ContextLength int          `json:"context_length,omitempty"`
+}
+
+// SupportsChatTemplateKwargs reports whether the backend honors chat_template_kwargs
+func (m LocalModel) SupportsChatTemplateKwargs() bool {
+	return m.Backend == LocalBackendVLLM
+}
+
+// SupportsResponseFormat reports whether the backend constrains output to a json_schema response format
+func (m LocalModel) SupportsResponseFormat() bool {
+	return m.Backend != LocalBackendUnknown
+}
+
+// localModelsResponse covers the fields the supported servers add to the OpenAI list format
+type localModelsResponse struct {
+	Data []struct {
+		ID          string `json:"id"`
+		OwnedBy     string `json:"owned_by"`
+		MaxModelLen int    `json:"max_model_len"` // vLLM
+		Meta        *struct {
+			NCtxTrain int `json:"n_ctx_train"`
+		} `json:"meta"` // llama.cpp
+	} `json:"data"`
+}
+
+// DiscoverLocalModels lists the models served at baseURL and detects the backend for each
+func DiscoverLocalModels(ctx context.Context, baseURL, apiKey string, httpClient *http.Client) ([]LocalModel, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	body, err := getLocalJSON(ctx, httpClient, strings.TrimSuffix(baseURL, "/")+"/models", apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("model discovery failed: %w", err)
+	}
+
+	var list localModelsResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+
+	var models []LocalModel
+	ollamaChecked, isOllama := false, false
+	for _, entry := range list.Data {
+		model := LocalModel{ID: entry.ID, OwnedBy: entry.OwnedBy, Backend: LocalBackendUnknown}
+
+		switch {
+		case entry.OwnedBy == "vllm" || entry.MaxModelLen > 0:
+			model.Backend = LocalBackendVLLM
+			model.ContextLength = entry.MaxModelLen
+		case entry.OwnedBy == "llamacpp" || entry.Meta != nil:
+			model.Backend = LocalBackendLlamaCpp
+			if entry.Meta != nil {
+				model.ContextLength = entry.Meta.NCtxTrain
+			}
+		default:
+			// Ollama reports owned_by as the model namespace, so probe its native API instead
+			if !ollamaChecked {
+				isOllama = probeOllama(ctx, httpClient, baseURL)
+				ollamaChecked = true
+			}
+			if isOllama {
+				model.Backend = LocalBackendOllama
+			}
+		}
+
+		models = append(models, model)
+	}
+
+	return models, nil
+}
+
+// FindLocalModel returns the discovered model with the given ID
+func FindLocalModel(models []LocalModel, id string) (LocalModel, bool) {
+	for _, model := range models {
+		if model.ID == id {
+			return model, true
+		}
+	}
+	return LocalModel{}, false
+}
+
+// probeOllama checks for Ollama's native /api/version endpoint next to the OpenAI-compatible API
+func probeOllama(ctx context.Context, httpClient *http.Client, baseURL string) bool {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+	parsed.Path = "/api/version"
+
+	body, err := getLocalJSON(ctx, httpClient, parsed.String(), "")
+	if err != nil {
+		return false
+	}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	return json.Unmarshal(body, &version) == nil && version.Version != ""
+}
+
+// getLocalJSON performs a GET request and returns the body of a successful response
+func getLocalJSON(ctx context.Context, httpClient *http.Client, endpoint, apiKey string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d", endpoint, resp.StatusCode)
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// configureLocalModel discovers the models on the local server, filling in config.Model
+// when it was left empty, and returns the entry describing the selected model
+func configureLocalModel(config *utils.Config) (LocalModel, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), localDiscoveryTimeout)
+	defer cancel()
+
+	discovered, err := DiscoverLocalModels(ctx, config.LocalBaseURL, config.LocalAPIKey, nil)
+	if err != nil {
+		if config.Model == "" {
+			return LocalModel{}, fmt.Errorf("SECONDBRAIN_MODEL is not set and %w", err)
+		}
+		fmt.Printf("WARNING: %v - continuing with configured model %s\n", err, config.Model)
+		return LocalModel{ID: config.Model, Backend: LocalBackendUnknown}, nil
+	}
+
+	if config.Model == "" {
+		if len(discovered) == 0 {
+			return LocalModel{}, fmt.Errorf("no models available at %s", config.LocalBaseURL)
+		}
+		config.Model = discovered[0].ID
+		fmt.Printf("DEBUG: No model configured, using first discovered model: %s\n", config.Model)
+	}
+
+	model, found := FindLocalModel(discovered, config.Model)
+	if !found {
+		fmt.Printf("WARNING: Model %s is not listed by %s\n", config.Model, config.LocalBaseURL)
+		return LocalModel{ID: config.Model, Backend: LocalBackendUnknown}, nil
+	}
+
+	fmt.Printf("DEBUG: Local model %s served by %s (context length: %d)\n", model.ID, model.Backend, model.ContextLength)
+	models.Register(models.Capabilities{ID: model.ID, ContextLength: model.ContextLength, SupportsTools: true})
+	return model, nil
+}
+
+// replayedLocalModel sets up the local model from a cassette instead of the server, so replays run
+// offline. Cassettes that did not record it replay with the configured model on an unknown backend.
+func replayedLocalModel(config *utils.Config, cassette *CassetteProvider) (LocalModel, error) {
+	if config.Model == "" {
+		config.Model = cassette.RecordedModel()
+	}
+	if config.Model == "" {
+		return LocalModel{}, fmt.Errorf("SECONDBRAIN_MODEL is not set and the cassette does not record a model")
+	}
+
+	model := LocalModel{ID: config.Model, Backend: LocalBackendUnknown}
+	if recorded := cassette.cassette.LocalModel; recorded != nil && recorded.ID == config.Model {
+		model = *recorded
+	}
+	fmt.Printf("DEBUG: Replaying local model %s served by %s (context length: %d)\n", model.ID, model.Backend, model.ContextLength)
+	models.Register(models.Capabilities{ID: model.ID, ContextLength: model.ContextLength, SupportsTools: true})
+	return model, nil
+}
@@ -0,0 +1,102 @@
+package openrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscoverLocalModelsDetectsBackend(t *testing.T) {
+	tests := []struct {
+		name           string
+		models         string
+		ollamaVersion  bool
+		expectBackend  LocalBackend
+		expectContext  int
+		expectTemplate bool
+	}{
+		{
+			name:           "vllm",
+			models:         `{"object":"list","data":[{"id":"Qwen/Qwen3-8B","object":"model","owned_by":"vllm","max_model_len":32768}]}`,
+			expectBackend:  LocalBackendVLLM,
+			expectContext:  32768,
+			expectTemplate: true,
+		},
+		{
+			name:          "llama.cpp",
+			models:        `{"object":"list","data":[{"id":"qwen3.gguf","object":"model","owned_by":"llamacpp","meta":{"n_ctx_train":40960}}]}`,
+			expectBackend: LocalBackendLlamaCpp,
+			expectContext: 40960,
+		},
+		{
+			name:          "ollama",
+			models:        `{"object":"list","data":[{"id":"qwen3:8b","object":"model","owned_by":"library"}]}`,
+			ollamaVersion: true,
+			expectBackend: LocalBackendOllama,
+		},
+		{
+			name:          "unknown",
+			models:        `{"object":"list","data":[{"id":"some-model","object":"model","owned_by":"someone"}]}`,
+			expectBackend: LocalBackendUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v1/models":
+					fmt.Fprint(w, tt.models)
+				case "/api/version":
+					if tt.ollamaVersion {
+						fmt.Fprint(w, `{"version":"0.9.0"}`)
+						return
+					}
+					http.NotFound(w, r)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			models, err := DiscoverLocalModels(context.Background(), server.URL+"/v1", "", nil)
+			if err != nil {
+				t.Fatalf("discovery failed: %v", err)
+			}
+			if len(models) != 1 {
+				t.Fatalf("expected 1 model, got %d", len(models))
+			}
+
+			model := models[0]
+			if model.Backend != tt.expectBackend {
+				t.Errorf("expected backend %s, got %s", tt.expectBackend, model.Backend)
+			}
+			if model.ContextLength != tt.expectContext {
+				t.Errorf("expected context length %d, got %d", tt.expectContext, model.ContextLength)
+			}
+			if model.SupportsChatTemplateKwargs() != tt.expectTemplate {
+				t.Errorf("unexpected chat template kwargs support: %v", model.SupportsChatTemplateKwargs())
+			}
+		})
+	}
+}
+
+func TestDiscoverLocalModelsSendsAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"m","owned_by":"vllm"}]}`)
+	}))
+	defer server.Close()
+
+	if _, err := DiscoverLocalModels(context.Background(), server.URL, "", nil); err == nil {
+		t.Error("expected discovery without key to fail")
+	}
+	if _, err := DiscoverLocalModels(context.Background(), server.URL, "secret", nil); err != nil {
+		t.Errorf("expected discovery with key to succeed: %v", err)
+	}
+}
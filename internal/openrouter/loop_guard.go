@@ -0,0 +1,135 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorka/internal/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Stop reasons recorded on the agent session and run result
+const (
+	StopReasonCompleted         = "completed"
+	StopReasonMaxIterations     = "max_iterations"
+	StopReasonMaxToolCalls      = "max_tool_calls"
+	StopReasonTimeLimit         = "time_limit"
+	StopReasonRepeatedToolCalls = "repeated_tool_calls"
+	StopReasonBudgetExceeded    = "budget_exceeded"
+)
+
+// LoopLimits bounds a single agent conversation; a zero value leaves that limit off
+type LoopLimits struct {
+	// MaxIterations caps the model turns whose tool calls are run
+	MaxIterations int
+	// MaxToolCalls caps the tool calls run across the whole conversation
+	MaxToolCalls int
+	// MaxDuration caps the wall-clock time spent before the agent is asked to finish
+	MaxDuration time.Duration
+	// MaxRepeats caps how often the same tool may run with the same arguments
+	MaxRepeats int
+}
+
+// loopLimitsFromConfig reads the agent loop limits from configuration
+func loopLimitsFromConfig(config *utils.Config) LoopLimits {
+	return LoopLimits{
+		MaxIterations: config.MaxAgentIterations,
+		MaxToolCalls:  config.MaxAgentToolCalls,
+		MaxDuration:   time.Duration(config.MaxAgentDuration) * time.Second,
+		MaxRepeats:    config.MaxRepeatedToolCalls,
+	}
+}
+
+// loopGuard tracks one conversation against its limits. The first limit crossed earns the
+// agent a wrap-up turn without tools; tool calls after that stop the conversation.
+type loopGuard struct {
+	limits     LoopLimits
+	started    time.Time
+	iterations int
+	toolCalls  int
+	repeats    map[string]int
+	// wrapUp is the limit that triggered the wrap-up turn, once one has
+	wrapUp string
+}
+
+// newLoopGuard starts the clock on a conversation
+func newLoopGuard(limits LoopLimits) *loopGuard {
+	return &loopGuard{
+		limits:  limits,
+		started: time.Now(),
+		repeats: make(map[string]int),
+	}
+}
+
+// record counts a model turn and the tool calls it made. It returns the limit now exceeded, if
+// any; the time limit applies to turns without tool calls too.
+func (g *loopGuard) record(toolCalls []openai.ToolCall) string {
+	if len(toolCalls) > 0 {
+		g.iterations++
+	}
+	g.toolCalls += len(toolCalls)
+
+	repeated := false
+	for _, call := range toolCalls {
+		signature := toolCallSignature(call.Function.Name, call.Function.Arguments)
+		g.repeats[signature]++
+		if g.limits.MaxRepeats > 0 && g.repeats[signature] > g.limits.MaxRepeats {
+			repeated = true
+		}
+	}
+
+	switch {
+	case repeated:
+		return StopReasonRepeatedToolCalls
+	case g.limits.MaxIterations > 0 && g.iterations > g.limits.MaxIterations:
+		return StopReasonMaxIterations
+	case g.limits.MaxToolCalls > 0 && g.toolCalls > g.limits.MaxToolCalls:
+		return StopReasonMaxToolCalls
+	case g.limits.MaxDuration > 0 && time.Since(g.started) > g.limits.MaxDuration:
+		return StopReasonTimeLimit
+	}
+	return ""
+}
+
+// wrapUpPrompt asks the agent to finish with what it has
+func wrapUpPrompt(reason string) string {
+	var why string
+	switch reason {
+	case StopReasonRepeatedToolCalls:
+		why = "You are repeating a tool call with the same arguments, which will not produce anything new."
+	case StopReasonMaxIterations:
+		why = "You have used all the steps available for this task."
+	case StopReasonMaxToolCalls:
+		why = "You have used all the tool calls available for this task."
+	case StopReasonTimeLimit:
+		why = "You have used all the time available for this task."
+	}
+	return fmt.Sprintf("%s Do not call any more tools. Reply now with your final answer, based on the work done so far.", why)
+}
+
+// toolCallSignature identifies calls to the same tool with the same arguments, ignoring
+// argument order and formatting
+func toolCallSignature(name, arguments string) string {
+	var parsed interface{}
+	if json.Unmarshal([]byte(arguments), &parsed) == nil {
+		if canonical, err := json.Marshal(parsed); err == nil {
+			arguments = string(canonical)
+		}
+	}
+	return name + " " + arguments
+}
+
+type toolsDisabledKey struct{}
+
+// withToolsDisabled sets tool_choice to none for every completion made with ctx
+func withToolsDisabled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, toolsDisabledKey{}, true)
+}
+
+// toolsDisabledFromContext reports whether completions made with ctx may call tools
+func toolsDisabledFromContext(ctx context.Context) bool {
+	disabled, _ := ctx.Value(toolsDisabledKey{}).(bool)
+	return disabled
+}
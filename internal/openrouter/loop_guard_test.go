@@ -0,0 +1,209 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorka/internal/session"
+	"gorka/internal/tools"
+	"gorka/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestLoopGuardLimits(t *testing.T) {
+	read := func(args string) openai.ToolCall { return schedulerCall("read_file", args) }
+
+	tests := []struct {
+		name   string
+		limits LoopLimits
+		turns  [][]openai.ToolCall
+		want   string
+	}{
+		{
+			name:   "same call with reordered arguments",
+			limits: LoopLimits{MaxRepeats: 2},
+			turns: [][]openai.ToolCall{
+				{read(`{"file_path": "a.go", "start_line": 1}`)},
+				{read(`{"start_line":1,"file_path":"a.go"}`)},
+				{read(`{"file_path": "a.go", "start_line": 1}`)},
+			},
+			want: StopReasonRepeatedToolCalls,
+		},
+		{
+			name:   "different arguments are not repeats",
+			limits: LoopLimits{MaxRepeats: 1},
+			turns:  [][]openai.ToolCall{{read(`{"file_path": "a.go"}`)}, {read(`{"file_path": "b.go"}`)}},
+			want:   "",
+		},
+		{
+			name:   "iterations",
+			limits: LoopLimits{MaxIterations: 2},
+			turns:  [][]openai.ToolCall{{read(`{"file_path": "a.go"}`)}, {read(`{"file_path": "b.go"}`)}, {read(`{"file_path": "c.go"}`)}},
+			want:   StopReasonMaxIterations,
+		},
+		{
+			name:   "tool calls",
+			limits: LoopLimits{MaxToolCalls: 2},
+			turns:  [][]openai.ToolCall{{read(`{"file_path": "a.go"}`), read(`{"file_path": "b.go"}`), read(`{"file_path": "c.go"}`)}},
+			want:   StopReasonMaxToolCalls,
+		},
+		{
+			name:   "disabled limits",
+			limits: LoopLimits{},
+			turns:  [][]openai.ToolCall{{read(`{}`)}, {read(`{}`)}, {read(`{}`)}, {read(`{}`)}},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		guard := newLoopGuard(tt.limits)
+		got := ""
+		for _, calls := range tt.turns {
+			got = guard.record(calls)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	guard := newLoopGuard(LoopLimits{MaxDuration: time.Millisecond})
+	guard.started = time.Now().Add(-time.Second)
+	if got := guard.record([]openai.ToolCall{read(`{}`)}); got != StopReasonTimeLimit {
+		t.Errorf("time limit: expected %q, got %q", StopReasonTimeLimit, got)
+	}
+	if got := guard.record(nil); got != StopReasonTimeLimit {
+		t.Errorf("time limit on a turn without tools: expected %q, got %q", StopReasonTimeLimit, got)
+	}
+}
+
+// loopingServer always asks for the same file, and answers in prose once tools are disabled
+// unless ignoreToolChoice is set
+func loopingServer(t *testing.T, ignoreToolChoice bool, requests *[]openai.ChatCompletionRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		*requests = append(*requests, request)
+
+		if request.ToolChoice == "none" && !ignoreToolChoice {
+			replyContent(w, "test/model", "Final answer from what I read.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"gen-1","model":"test/model","choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_%d","type":"function","function":{"name":"read_file","arguments":"{\"file_path\":\"a.go\"}"}}]},"finish_reason":"tool_calls"}]}`, len(*requests))
+	}))
+}
+
+func loopTestSpawner(t *testing.T, serverURL string, limits LoopLimits) *AgentSpawner {
+	workspace := t.TempDir()
+	toolsManager := tools.NewToolsManager(workspace, filepath.Join(workspace, "storage"))
+
+	client := newTestClient(serverURL, false)
+	client.toolsManager = toolsManager
+	client.config.MaxAgentIterations = limits.MaxIterations
+	client.config.MaxRepeatedToolCalls = limits.MaxRepeats
+
+	return &AgentSpawner{
+		client:         client,
+		toolsManager:   toolsManager,
+		sessionManager: session.NewSessionManagerWithDir(filepath.Join(workspace, "sessions")),
+	}
+}
+
+func TestSpawnAgentWrapsUpRepeatedCalls(t *testing.T) {
+	var requests []openai.ChatCompletionRequest
+	server := loopingServer(t, false, &requests)
+	defer server.Close()
+
+	spawner := loopTestSpawner(t, server.URL, LoopLimits{MaxRepeats: 2})
+	result, err := spawner.SpawnAgentWithContext(context.Background(), &types.BehavioralMatrix{AgentID: "test_agent"}, "read a.go")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+
+	if result.StopReason != StopReasonRepeatedToolCalls {
+		t.Errorf("Expected stop reason %q, got %q", StopReasonRepeatedToolCalls, result.StopReason)
+	}
+	if !strings.Contains(result.Response.Choices[0].Message.Content, "Final answer") {
+		t.Errorf("Expected the wrap-up answer, got %q", result.Response.Choices[0].Message.Content)
+	}
+
+	last := requests[len(requests)-1]
+	if last.ToolChoice != "none" {
+		t.Errorf("Expected the wrap-up turn to disable tools, got tool_choice %v", last.ToolChoice)
+	}
+	nudge := last.Messages[len(last.Messages)-1]
+	if nudge.Role != openai.ChatMessageRoleUser || !strings.Contains(nudge.Content, "Do not call any more tools") {
+		t.Errorf("Expected a wrap-up prompt, got %+v", nudge)
+	}
+
+	agentSession, ok := spawner.sessionManager.GetSession(result.SessionID)
+	if !ok || agentSession.StopReason != StopReasonRepeatedToolCalls {
+		t.Errorf("Expected the session to record the stop reason, got %+v", agentSession)
+	}
+}
+
+func TestSpawnAgentStopsWhenWrapUpIgnored(t *testing.T) {
+	var requests []openai.ChatCompletionRequest
+	server := loopingServer(t, true, &requests)
+	defer server.Close()
+
+	spawner := loopTestSpawner(t, server.URL, LoopLimits{MaxIterations: 1})
+	result, err := spawner.SpawnAgentWithContext(context.Background(), &types.BehavioralMatrix{AgentID: "test_agent"}, "read a.go")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+
+	if result.StopReason != StopReasonMaxIterations {
+		t.Errorf("Expected stop reason %q, got %q", StopReasonMaxIterations, result.StopReason)
+	}
+	if calls := result.Response.Choices[0].Message.ToolCalls; len(calls) != 0 {
+		t.Errorf("Expected pending tool calls to be dropped, got %d", len(calls))
+	}
+	if len(requests) > 5 {
+		t.Errorf("Expected the loop to stop soon after the limit, got %d requests", len(requests))
+	}
+
+	// The dropped calls are answered, so the session can be continued
+	agentSession, _ := spawner.sessionManager.GetSession(result.SessionID)
+	if consistent := session.ConsistentLength(agentSession.Messages); consistent != len(agentSession.Messages) {
+		t.Errorf("Expected every saved tool call to have a result, only %d of %d messages are consistent", consistent, len(agentSession.Messages))
+	}
+	if last := agentSession.Messages[len(agentSession.Messages)-1]; last.Role != openai.ChatMessageRoleTool || !strings.Contains(last.Content, "Not executed") {
+		t.Errorf("Expected the last call to be answered as not executed, got %+v", last)
+	}
+}
+
+func TestSpawnAgentStopsOutputRepairsAtTimeLimit(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		time.Sleep(1100 * time.Millisecond)
+		replyContent(w, "test/model", "I implemented the change in main.go.")
+	}))
+	defer server.Close()
+
+	structured := true
+	matrix := outputTestMatrix()
+	matrix.LLM = &types.LLMSettings{StructuredOutputs: &structured}
+	spawner := loopTestSpawner(t, server.URL, LoopLimits{})
+	spawner.client.config.MaxAgentDuration = 1
+
+	result, err := spawner.SpawnAgentWithContext(context.Background(), matrix, "implement it")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected no repair after the time limit, got %d requests", requests)
+	}
+	if result.StopReason != StopReasonTimeLimit || result.OutputError == "" {
+		t.Errorf("Expected a time limit stop with the output error, got %q, %q", result.StopReason, result.OutputError)
+	}
+}
@@ -0,0 +1,94 @@
+package openrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorka/internal/utils"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Provider names accepted by SECONDBRAIN_PROVIDER
+const (
+	ProviderOpenRouter = "openrouter"
+	ProviderAnthropic  = "anthropic"
+	ProviderOpenAI     = "openai"
+	ProviderLocal      = "local"
+)
+
+// Provider sends chat completion requests to a specific LLM backend.
+// Requests and responses use the go-openai types so sessions, adapters and
+// the agent loop stay independent of the backend wire format.
+type Provider interface {
+	// Name returns the provider identifier
+	Name() string
+	// CreateChatCompletion performs a blocking completion request
+	CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+	// CreateChatCompletionStream performs a streaming request, reporting deltas to handler
+	// and returning the assembled response
+	CreateChatCompletionStream(ctx context.Context, request openai.ChatCompletionRequest, handler StreamHandler) (openai.ChatCompletionResponse, error)
+}
+
+// NewProvider creates the provider selected by the configuration
+func NewProvider(config *utils.Config) (Provider, error) {
+	httpClient := &http.Client{
+		Timeout:   time.Duration(config.RequestTimeout) * time.Second,
+		Transport: &retryAfterTransport{Transport: http.DefaultTransport},
+	}
+
+	switch config.Provider {
+	case ProviderAnthropic:
+		return NewAnthropicProvider(config.AnthropicAPIKey, config.AnthropicBaseURL, httpClient), nil
+	case ProviderOpenAI:
+		// Configure for direct OpenAI API
+		clientConfig := openai.DefaultConfig(config.OpenAIAPIKey)
+		clientConfig.HTTPClient = httpClient
+		return NewOpenAICompatibleProvider(ProviderOpenAI, clientConfig), nil
+	case ProviderLocal:
+		// Ollama, vLLM and llama.cpp all expose the OpenAI chat completions API
+		clientConfig := openai.DefaultConfig(config.LocalAPIKey)
+		clientConfig.BaseURL = config.LocalBaseURL
+		httpClient.Transport = &retryAfterTransport{Transport: &reasoningTransport{Transport: http.DefaultTransport}}
+		clientConfig.HTTPClient = httpClient
+		return NewOpenAICompatibleProvider(ProviderLocal, clientConfig), nil
+	case ProviderOpenRouter, "":
+		// Configure OpenAI client for OpenRouter
+		httpClient.Transport = &retryAfterTransport{Transport: &reasoningTransport{
+			Transport:    &customTransport{Transport: http.DefaultTransport},
+			effortObject: true,
+		}}
+		clientConfig := openai.DefaultConfig(config.OpenRouterAPIKey)
+		clientConfig.BaseURL = config.OpenRouterBaseURL
+		clientConfig.HTTPClient = httpClient
+		return NewOpenAICompatibleProvider(ProviderOpenRouter, clientConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", config.Provider)
+	}
+}
+
+// OpenAICompatibleProvider talks to any endpoint implementing the OpenAI chat completions API
+type OpenAICompatibleProvider struct {
+	name   string
+	client *openai.Client
+}
+
+// NewOpenAICompatibleProvider creates a provider backed by the go-openai SDK
+func NewOpenAICompatibleProvider(name string, clientConfig openai.ClientConfig) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		name:   name,
+		client: openai.NewClientWithConfig(clientConfig),
+	}
+}
+
+// Name returns the provider identifier
+func (p *OpenAICompatibleProvider) Name() string {
+	return p.name
+}
+
+// CreateChatCompletion performs a blocking completion request
+func (p *OpenAICompatibleProvider) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	return p.client.CreateChatCompletion(ctx, request)
+}
@@ -0,0 +1,210 @@
+package openrouter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorka/internal/tokenizer"
+	"github.com/sashabaranov/go-openai"
+)
+
+// reasoningTransport translates reasoning fields between go-openai and providers that name them
+// differently. OpenRouter takes the effort as a reasoning object, and OpenRouter and Ollama return
+// the trace as reasoning instead of reasoning_content.
+type reasoningTransport struct {
+	Transport http.RoundTripper
+	// effortObject sends reasoning_effort as {"reasoning": {"effort": ...}}
+	effortObject bool
+}
+
+// RoundTrip implements http.RoundTripper interface
+func (t *reasoningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.effortObject && req.Body != nil && req.Method == http.MethodPost {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = effortObject(body)
+
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, err
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Body = &reasoningEventReader{body: resp.Body, reader: bufio.NewReader(resp.Body)}
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = renameReasoning(body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return resp, nil
+}
+
+// effortObject moves a top-level reasoning_effort into OpenRouter's reasoning object
+func effortObject(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return body
+	}
+	effort, ok := fields["reasoning_effort"]
+	if !ok {
+		return body
+	}
+
+	delete(fields, "reasoning_effort")
+	fields["reasoning"] = json.RawMessage(`{"effort":` + string(effort) + `}`)
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return rewritten
+}
+
+// renameReasoning copies a string reasoning field into reasoning_content on every choice's
+// message or delta, which is where go-openai looks for it
+func renameReasoning(data []byte) []byte {
+	if !bytes.Contains(data, []byte(`"reasoning"`)) {
+		return data
+	}
+
+	var payload map[string]json.RawMessage
+	if json.Unmarshal(data, &payload) != nil {
+		return data
+	}
+	var choices []map[string]json.RawMessage
+	if json.Unmarshal(payload["choices"], &choices) != nil {
+		return data
+	}
+
+	changed := false
+	for _, choice := range choices {
+		for _, key := range []string{"message", "delta"} {
+			var message map[string]json.RawMessage
+			if json.Unmarshal(choice[key], &message) != nil || message == nil {
+				continue
+			}
+			reasoning, ok := message["reasoning"]
+			if !ok || !bytes.HasPrefix(reasoning, []byte(`"`)) {
+				continue
+			}
+			if existing := string(message["reasoning_content"]); existing == "" || existing == "null" || existing == `""` {
+				message["reasoning_content"] = reasoning
+			}
+			delete(message, "reasoning")
+			choice[key], _ = json.Marshal(message)
+			changed = true
+		}
+	}
+	if !changed {
+		return data
+	}
+
+	payload["choices"], _ = json.Marshal(choices)
+	rewritten, err := json.Marshal(payload)
+	if err != nil {
+		return data
+	}
+	return rewritten
+}
+
+// reasoningEventReader renames reasoning fields in a server-sent event stream line by line
+type reasoningEventReader struct {
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	pending []byte
+	err     error
+}
+
+// Read implements io.Reader
+func (r *reasoningEventReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		var line []byte
+		line, r.err = r.reader.ReadBytes('\n')
+		r.pending = renameReasoningEvent(line)
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Close implements io.Closer
+func (r *reasoningEventReader) Close() error {
+	return r.body.Close()
+}
+
+// renameReasoningEvent rewrites the JSON payload of one data line, keeping its line ending
+func renameReasoningEvent(line []byte) []byte {
+	content := bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(content, []byte("data:")) {
+		return line
+	}
+	data := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("data:")))
+	renamed := renameReasoning(data)
+	if bytes.Equal(renamed, data) {
+		return line
+	}
+	return append(append([]byte("data: "), renamed...), line[len(content):]...)
+}
+
+// reasoningUsage returns the response usage with reasoning tokens filled in. Providers that
+// return a trace without counting it get an estimate from the model's tokenizer.
+func reasoningUsage(model string, response openai.ChatCompletionResponse) openai.Usage {
+	usage := response.Usage
+	if usage.CompletionTokensDetails != nil && usage.CompletionTokensDetails.ReasoningTokens > 0 {
+		return usage
+	}
+
+	tk := tokenizer.ForModel(model)
+	reasoning := 0
+	for _, choice := range response.Choices {
+		if choice.Message.ReasoningContent != "" {
+			reasoning += tk.Count(choice.Message.ReasoningContent)
+		}
+	}
+	if reasoning == 0 {
+		return usage
+	}
+
+	// Reasoning is billed as completion tokens, so the estimate cannot exceed them
+	if usage.CompletionTokens > 0 {
+		reasoning = min(reasoning, usage.CompletionTokens)
+	}
+	details := &openai.CompletionTokensDetails{}
+	if usage.CompletionTokensDetails != nil {
+		*details = *usage.CompletionTokensDetails
+	}
+	details.ReasoningTokens = reasoning
+	usage.CompletionTokensDetails = details
+	return usage
+}
+
+// withoutReasoning drops the reasoning trace before a message is sent back to the model
+func withoutReasoning(message openai.ChatCompletionMessage) openai.ChatCompletionMessage {
+	message.ReasoningContent = ""
+	return message
+}
@@ -0,0 +1,99 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// withReasoningTransport routes the test client through the OpenRouter reasoning translation
+func withReasoningTransport(client *Client, baseURL string) {
+	clientConfig := openai.DefaultConfig("test-key")
+	clientConfig.BaseURL = baseURL
+	clientConfig.HTTPClient = &http.Client{Transport: &retryAfterTransport{Transport: &reasoningTransport{
+		Transport:    http.DefaultTransport,
+		effortObject: true,
+	}}}
+	client.provider = NewOpenAICompatibleProvider(ProviderOpenRouter, clientConfig)
+}
+
+func TestOpenRouterReasoningIsSentAndCaptured(t *testing.T) {
+	var body map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"gen-1","model":"test/model","choices":[{"index":0,"message":{"role":"assistant","content":"Use the cache.","reasoning":"The config loads twice per request."},"finish_reason":"stop"}],"usage":{"prompt_tokens":20,"completion_tokens":40,"total_tokens":60}}`)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, false)
+	client.config.ReasoningEffort = "high"
+	withReasoningTransport(client, server.URL)
+
+	result, err := client.CreateChatCompletionWithSettings(context.Background(), []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "why is it slow?"}}, nil)
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+
+	if _, sent := body["reasoning_effort"]; sent {
+		t.Error("expected reasoning_effort to be moved into the reasoning object")
+	}
+	if string(body["reasoning"]) != `{"effort":"high"}` {
+		t.Errorf("unexpected reasoning object: %s", body["reasoning"])
+	}
+
+	message := result.Response.Choices[0].Message
+	if message.ReasoningContent != "The config loads twice per request." {
+		t.Errorf("expected the reasoning trace, got %q", message.ReasoningContent)
+	}
+	details := result.Attempts[0].Usage.CompletionTokensDetails
+	if details == nil || details.ReasoningTokens <= 0 || details.ReasoningTokens > 40 {
+		t.Errorf("expected estimated reasoning tokens within the completion tokens, got %+v", details)
+	}
+}
+
+func TestOpenRouterReasoningIsCapturedFromStream(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"id":"gen-1","model":"test/model","choices":[{"index":0,"delta":{"role":"assistant","reasoning":"Check the "}}]}`,
+		`{"id":"gen-1","model":"test/model","choices":[{"index":0,"delta":{"reasoning":"tests first."}}]}`,
+		`{"id":"gen-1","model":"test/model","choices":[{"index":0,"delta":{"content":"Done"},"finish_reason":"stop"}]}`,
+		`{"id":"gen-1","model":"test/model","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":9,"total_tokens":16,"completion_tokens_details":{"reasoning_tokens":5}}}`,
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, true)
+	withReasoningTransport(client, server.URL)
+	ctx := WithStreamHandler(context.Background(), func(StreamEvent) {})
+
+	response, err := client.sendChatCompletion(ctx, openai.ChatCompletionRequest{Model: "test/model"})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if got := response.Choices[0].Message.ReasoningContent; got != "Check the tests first." {
+		t.Errorf("unexpected reasoning: %q", got)
+	}
+	if usage := reasoningUsage("test/model", response); usage.CompletionTokensDetails.ReasoningTokens != 5 {
+		t.Errorf("expected the reported reasoning tokens to be kept, got %+v", usage.CompletionTokensDetails)
+	}
+}
+
+func TestRenameReasoningKeepsReasoningContent(t *testing.T) {
+	data := []byte(`{"choices":[{"message":{"content":"ok","reasoning":"a","reasoning_content":"b"}}]}`)
+	var response openai.ChatCompletionResponse
+	if err := json.Unmarshal(renameReasoning(data), &response); err != nil {
+		t.Fatal(err)
+	}
+	if got := response.Choices[0].Message.ReasoningContent; got != "b" {
+		t.Errorf("expected the existing reasoning_content to win, got %q", got)
+	}
+
+	unchanged := []byte(`{"choices":[{"message":{"content":"no trace here"}}]}`)
+	if string(renameReasoning(unchanged)) != string(unchanged) {
+		t.Error("expected bodies without reasoning to pass through untouched")
+	}
+}
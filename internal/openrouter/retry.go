@@ -0,0 +1,246 @@
+package openrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultRetryBaseDelay = 1 * time.Second
+	defaultRetryMaxDelay  = 30 * time.Second
+	// maxRetryAfter caps server-requested waits so a bad header cannot stall an agent for hours
+	maxRetryAfter = 2 * time.Minute
+)
+
+// ErrorClass describes how a failed completion call should be handled
+type ErrorClass string
+
+const (
+	// ErrorClassRetryable covers rate limits, server errors and timeouts; retry with backoff
+	ErrorClassRetryable ErrorClass = "retryable"
+	// ErrorClassFatal covers auth failures and invalid requests; no model will succeed
+	ErrorClassFatal ErrorClass = "fatal"
+	// ErrorClassContextLength means the prompt is too large for this model; try the next one
+	ErrorClassContextLength ErrorClass = "context_length"
+	// ErrorClassModelUnavailable means this model cannot serve the request; try the next one
+	ErrorClassModelUnavailable ErrorClass = "model_unavailable"
+	// ErrorClassToolsUnsupported means the model cannot be sent tools; try the next one
+	ErrorClassToolsUnsupported ErrorClass = "tools_unsupported"
+	// ErrorClassEmptyResponse means the call succeeded but returned no usable choice
+	ErrorClassEmptyResponse ErrorClass = "empty_response"
+)
+
+// ErrToolsUnsupported is returned instead of sending tools to a model that does not support them
+var ErrToolsUnsupported = errors.New("model does not support tool calling")
+
+// errEmptyResponse marks a model that kept answering without content or tool calls
+var errEmptyResponse = errors.New("empty response")
+
+// ModelAttempt records one API call made while working through the fallback chain
+type ModelAttempt struct {
+	Model      string `json:"model"`
+	Attempt    int    `json:"attempt"`
+	Outcome    string `json:"outcome"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	BackoffMs  int64  `json:"backoff_ms,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	// Usage is set for every call that got a response, including empty ones, since those are billed too
+	Usage *openai.Usage `json:"usage,omitempty"`
+}
+
+// FallbackError is returned when every model in the fallback chain failed
+type FallbackError struct {
+	Attempts []ModelAttempt
+	Err      error
+}
+
+// Error implements the error interface
+func (e *FallbackError) Error() string {
+	var hops []string
+	for _, attempt := range e.Attempts {
+		hops = append(hops, fmt.Sprintf("%s#%d=%s", attempt.Model, attempt.Attempt, attempt.Outcome))
+	}
+	return fmt.Sprintf("all models failed [%s]: %v", strings.Join(hops, ", "), e.Err)
+}
+
+// Unwrap returns the last underlying error
+func (e *FallbackError) Unwrap() error {
+	return e.Err
+}
+
+// contextLengthMarkers are substrings providers use when a prompt exceeds the model window
+var contextLengthMarkers = []string{
+	"context length",
+	"context_length",
+	"maximum context",
+	"context window",
+	"too many tokens",
+	"prompt is too long",
+}
+
+// classifyError decides whether an error is worth retrying, falling back, or giving up on
+func classifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	statusCode, message := errorStatus(err)
+	lowerMessage := strings.ToLower(message + " " + err.Error())
+
+	if statusCode == http.StatusBadRequest || statusCode == http.StatusRequestEntityTooLarge {
+		for _, marker := range contextLengthMarkers {
+			if strings.Contains(lowerMessage, marker) {
+				return ErrorClassContextLength
+			}
+		}
+	}
+
+	switch {
+	case statusCode == http.StatusTooManyRequests, statusCode == http.StatusRequestTimeout,
+		statusCode == 529, statusCode >= 500:
+		return ErrorClassRetryable
+	case statusCode == http.StatusPaymentRequired, statusCode == http.StatusNotFound:
+		// OpenRouter uses these for exhausted credits and models without a matching endpoint
+		return ErrorClassModelUnavailable
+	case statusCode >= 400:
+		return ErrorClassFatal
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassRetryable
+	}
+	if errors.Is(err, ErrToolsUnsupported) {
+		return ErrorClassToolsUnsupported
+	}
+	if errors.Is(err, errEmptyResponse) {
+		return ErrorClassEmptyResponse
+	}
+	if errors.Is(err, errCassetteMiss) {
+		// Replaying again or on another model cannot produce a recording that does not exist
+		return ErrorClassFatal
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassFatal
+	}
+
+	// Transport failures without a status (resets, EOF mid-stream) are usually transient
+	return ErrorClassRetryable
+}
+
+// errorStatus extracts the HTTP status code and message from SDK errors
+func errorStatus(err error) (int, string) {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode, apiErr.Message
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode, string(reqErr.Body)
+	}
+	return 0, ""
+}
+
+// backoffDelay returns the wait before the given retry attempt (1-based), using
+// exponential backoff with jitter unless the server asked for a specific delay
+func backoffDelay(attempt int, retryAfter, baseDelay, maxDelay time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if retryAfter > maxRetryAfter {
+			return maxRetryAfter
+		}
+		return retryAfter
+	}
+
+	ceiling := baseDelay << uint(attempt-1)
+	if ceiling <= 0 || ceiling > maxDelay {
+		ceiling = maxDelay
+	}
+	// Jitter keeps parallel agents from retrying in lockstep
+	return time.Duration(rand.Int63n(int64(ceiling)/2+1)) + ceiling/2
+}
+
+// parseRetryAfter parses a Retry-After header given as seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if delay := when.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// sleepWithContext waits for d or until ctx is done
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryAfterHint receives the Retry-After value seen by the transport for one attempt
+type retryAfterHint struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+func (h *retryAfterHint) set(delay time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.delay = delay
+}
+
+func (h *retryAfterHint) get() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delay
+}
+
+type retryAfterKey struct{}
+
+// withRetryAfterHint returns a context whose HTTP responses report Retry-After into hint
+func withRetryAfterHint(ctx context.Context, hint *retryAfterHint) context.Context {
+	return context.WithValue(ctx, retryAfterKey{}, hint)
+}
+
+// retryAfterTransport captures Retry-After headers, which the SDK error types do not expose
+type retryAfterTransport struct {
+	Transport http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper interface
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if hint, ok := req.Context().Value(retryAfterKey{}).(*retryAfterHint); ok {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			hint.set(delay)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,220 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorka/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// modelServer answers chat completions per requested model and records the order of calls
+type modelServer struct {
+	mu       sync.Mutex
+	calls    []string
+	handlers map[string]func(w http.ResponseWriter, call int)
+	counts   map[string]int
+}
+
+func newModelServer(handlers map[string]func(w http.ResponseWriter, call int)) (*modelServer, *httptest.Server) {
+	ms := &modelServer{handlers: handlers, counts: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+
+		ms.mu.Lock()
+		ms.calls = append(ms.calls, request.Model)
+		ms.counts[request.Model]++
+		call := ms.counts[request.Model]
+		ms.mu.Unlock()
+
+		ms.handlers[request.Model](w, call)
+	}))
+	return ms, server
+}
+
+func replyError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":{"message":%q,"code":%d}}`, message, status)
+}
+
+func replyContent(w http.ResponseWriter, model, content string) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"id":"gen-1","model":%q,"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`, model, content)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"rate limit", &openai.APIError{HTTPStatusCode: 429, Message: "rate limited"}, ErrorClassRetryable},
+		{"server error", &openai.APIError{HTTPStatusCode: 502, Message: "bad gateway"}, ErrorClassRetryable},
+		{"unauthorized", &openai.APIError{HTTPStatusCode: 401, Message: "invalid key"}, ErrorClassFatal},
+		{"invalid tool schema", &openai.APIError{HTTPStatusCode: 400, Message: "invalid schema for function"}, ErrorClassFatal},
+		{"context length", &openai.APIError{HTTPStatusCode: 400, Message: "This model's maximum context length is 8192 tokens"}, ErrorClassContextLength},
+		{"no endpoint", &openai.APIError{HTTPStatusCode: 404, Message: "No endpoints found"}, ErrorClassModelUnavailable},
+		{"empty response", fmt.Errorf("all 3 attempts: %w", errEmptyResponse), ErrorClassEmptyResponse},
+		{"canceled", context.Canceled, ErrorClassFatal},
+	}
+
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	if got := backoffDelay(1, 3*time.Second, time.Second, 30*time.Second); got != 3*time.Second {
+		t.Errorf("expected Retry-After to be honored, got %v", got)
+	}
+	if got := backoffDelay(1, time.Hour, time.Second, 30*time.Second); got != maxRetryAfter {
+		t.Errorf("expected Retry-After to be capped at %v, got %v", maxRetryAfter, got)
+	}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		ceiling := time.Second << uint(attempt-1)
+		if ceiling > 30*time.Second {
+			ceiling = 30 * time.Second
+		}
+		got := backoffDelay(attempt, 0, time.Second, 30*time.Second)
+		if got < ceiling/2 || got > ceiling {
+			t.Errorf("attempt %d: delay %v outside [%v, %v]", attempt, got, ceiling/2, ceiling)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got, ok := parseRetryAfter("7", now); !ok || got != 7*time.Second {
+		t.Errorf("expected 7s, got %v (ok=%v)", got, ok)
+	}
+	if got, ok := parseRetryAfter("Wed, 01 Jan 2025 12:00:05 GMT", now); !ok || got != 5*time.Second {
+		t.Errorf("expected 5s from HTTP date, got %v (ok=%v)", got, ok)
+	}
+	if _, ok := parseRetryAfter("soon", now); ok {
+		t.Error("expected garbage header to be rejected")
+	}
+}
+
+func TestFallbackOnRateLimit(t *testing.T) {
+	ms, server := newModelServer(map[string]func(w http.ResponseWriter, call int){
+		"qwen/qwen3-32b:free": func(w http.ResponseWriter, call int) {
+			w.Header().Set("Retry-After", "0")
+			replyError(w, http.StatusTooManyRequests, "rate limited")
+		},
+		"backup/model": func(w http.ResponseWriter, call int) {
+			replyContent(w, "backup/model", "hello from backup")
+		},
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, false)
+	settings := &types.LLMSettings{Model: "qwen/qwen3-32b:free", FallbackModels: []string{"backup/model"}}
+
+	result, err := client.CreateChatCompletionWithSettings(context.Background(), []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	}, settings)
+	if err != nil {
+		t.Fatalf("expected fallback to succeed, got %v", err)
+	}
+
+	if got := result.Response.Choices[0].Message.Content; !strings.HasPrefix(got, "hello from backup") {
+		t.Errorf("unexpected content: %q", got)
+	}
+
+	want := []string{"qwen/qwen3-32b:free", "qwen/qwen3-32b:free", "qwen/qwen3-32b:free", "backup/model"}
+	if fmt.Sprint(ms.calls) != fmt.Sprint(want) {
+		t.Errorf("expected calls %v, got %v", want, ms.calls)
+	}
+
+	if len(result.Attempts) != 4 {
+		t.Fatalf("expected 4 recorded attempts, got %d", len(result.Attempts))
+	}
+	if result.Attempts[0].Outcome != string(ErrorClassRetryable) || result.Attempts[0].StatusCode != 429 {
+		t.Errorf("unexpected first attempt: %+v", result.Attempts[0])
+	}
+	if last := result.Attempts[3]; last.Model != "backup/model" || last.Outcome != "success" {
+		t.Errorf("unexpected last attempt: %+v", last)
+	}
+
+	run := &AgentRunResult{ModelAttempts: result.Attempts}
+	if !run.FallbackUsed() {
+		t.Error("expected FallbackUsed to report the backup model")
+	}
+}
+
+func TestFatalErrorStopsFallback(t *testing.T) {
+	ms, server := newModelServer(map[string]func(w http.ResponseWriter, call int){
+		"test/model": func(w http.ResponseWriter, call int) {
+			replyError(w, http.StatusUnauthorized, "invalid api key")
+		},
+		"backup/model": func(w http.ResponseWriter, call int) {
+			replyContent(w, "backup/model", "unreachable")
+		},
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, false)
+	client.config.FallbackModels = []string{"backup/model"}
+
+	_, err := client.CreateChatCompletion(context.Background(), []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	})
+	if err == nil {
+		t.Fatal("expected fatal error")
+	}
+
+	var fallbackErr *FallbackError
+	if !errors.As(err, &fallbackErr) {
+		t.Fatalf("expected *FallbackError, got %T", err)
+	}
+	if len(fallbackErr.Attempts) != 1 || fallbackErr.Attempts[0].Outcome != string(ErrorClassFatal) {
+		t.Errorf("expected a single fatal attempt, got %+v", fallbackErr.Attempts)
+	}
+	if len(ms.calls) != 1 {
+		t.Errorf("expected no retries or fallback after a fatal error, got calls %v", ms.calls)
+	}
+}
+
+func TestContextLengthFallsBackWithoutRetry(t *testing.T) {
+	ms, server := newModelServer(map[string]func(w http.ResponseWriter, call int){
+		"small/model": func(w http.ResponseWriter, call int) {
+			replyError(w, http.StatusBadRequest, "maximum context length is 4096 tokens")
+		},
+		"large/model": func(w http.ResponseWriter, call int) {
+			replyContent(w, "large/model", "fits")
+		},
+	})
+	defer server.Close()
+
+	client := newTestClient(server.URL, false)
+	settings := &types.LLMSettings{Model: "small/model", FallbackModels: []string{"large/model"}}
+
+	result, err := client.CreateChatCompletionWithSettings(context.Background(), []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "hi"},
+	}, settings)
+	if err != nil {
+		t.Fatalf("expected fallback to succeed, got %v", err)
+	}
+
+	if fmt.Sprint(ms.calls) != fmt.Sprint([]string{"small/model", "large/model"}) {
+		t.Errorf("expected one call per model, got %v", ms.calls)
+	}
+	if result.Attempts[0].Outcome != string(ErrorClassContextLength) {
+		t.Errorf("expected context_length outcome, got %+v", result.Attempts[0])
+	}
+}
@@ -0,0 +1,203 @@
+package openrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// StreamEventType identifies the kind of progress an agent run is reporting
+type StreamEventType string
+
+const (
+	// StreamEventContent carries a partial assistant text delta
+	StreamEventContent StreamEventType = "content"
+	// StreamEventToolCallDelta carries a fragment of streamed tool-call arguments
+	StreamEventToolCallDelta StreamEventType = "tool_call_delta"
+	// StreamEventToolCall is emitted right before a tool is executed
+	StreamEventToolCall StreamEventType = "tool_call"
+	// StreamEventToolResult is emitted after a tool has finished
+	StreamEventToolResult StreamEventType = "tool_result"
+)
+
+// StreamEvent describes a single piece of progress from a running agent
+type StreamEvent struct {
+	Type       StreamEventType
+	AgentID    string
+	Text       string
+	ToolName   string
+	ToolCallID string
+	Err        error
+}
+
+// StreamHandler receives progress events while a completion is being produced
+type StreamHandler func(event StreamEvent)
+
+type streamHandlerKey struct{}
+
+// WithStreamHandler returns a context that makes the client stream completions
+// and report deltas and tool activity to handler
+func WithStreamHandler(ctx context.Context, handler StreamHandler) context.Context {
+	return context.WithValue(ctx, streamHandlerKey{}, handler)
+}
+
+// streamHandlerFromContext returns the handler registered on ctx, if any
+func streamHandlerFromContext(ctx context.Context) StreamHandler {
+	if ctx == nil {
+		return nil
+	}
+	handler, _ := ctx.Value(streamHandlerKey{}).(StreamHandler)
+	return handler
+}
+
+// emitStreamEvent forwards event to the handler on ctx when one is registered
+func emitStreamEvent(ctx context.Context, event StreamEvent) {
+	if handler := streamHandlerFromContext(ctx); handler != nil {
+		handler(event)
+	}
+}
+
+// streamChoiceAccumulator rebuilds one choice from its streamed deltas
+type streamChoiceAccumulator struct {
+	role         string
+	content      strings.Builder
+	reasoning    strings.Builder
+	finishReason openai.FinishReason
+	toolCalls    map[int]*openai.ToolCall
+}
+
+// CreateChatCompletionStream performs a streaming request and assembles the deltas
+// into a regular response so callers can treat both paths the same way
+func (p *OpenAICompatibleProvider) CreateChatCompletionStream(ctx context.Context, request openai.ChatCompletionRequest, handler StreamHandler) (openai.ChatCompletionResponse, error) {
+	request.Stream = true
+	request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	stream, err := p.client.CreateChatCompletionStream(ctx, request)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	defer stream.Close()
+
+	response := openai.ChatCompletionResponse{Object: "chat.completion"}
+	choices := make(map[int]*streamChoiceAccumulator)
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return openai.ChatCompletionResponse{}, fmt.Errorf("stream receive failed: %w", err)
+		}
+
+		if response.ID == "" {
+			response.ID = chunk.ID
+			response.Created = chunk.Created
+			response.Model = chunk.Model
+			response.SystemFingerprint = chunk.SystemFingerprint
+		}
+		if chunk.Usage != nil {
+			response.Usage = *chunk.Usage
+		}
+
+		for _, delta := range chunk.Choices {
+			acc, exists := choices[delta.Index]
+			if !exists {
+				acc = &streamChoiceAccumulator{toolCalls: make(map[int]*openai.ToolCall)}
+				choices[delta.Index] = acc
+			}
+
+			if delta.Delta.Role != "" {
+				acc.role = delta.Delta.Role
+			}
+			if delta.FinishReason != "" {
+				acc.finishReason = delta.FinishReason
+			}
+			if delta.Delta.ReasoningContent != "" {
+				acc.reasoning.WriteString(delta.Delta.ReasoningContent)
+			}
+			if delta.Delta.Content != "" {
+				acc.content.WriteString(delta.Delta.Content)
+				handler(StreamEvent{Type: StreamEventContent, Text: delta.Delta.Content})
+			}
+
+			for position, toolDelta := range delta.Delta.ToolCalls {
+				// Some providers omit the index when only one call is streamed
+				index := position
+				if toolDelta.Index != nil {
+					index = *toolDelta.Index
+				}
+
+				call, exists := acc.toolCalls[index]
+				if !exists {
+					call = &openai.ToolCall{Type: openai.ToolTypeFunction}
+					acc.toolCalls[index] = call
+				}
+				if toolDelta.ID != "" {
+					call.ID = toolDelta.ID
+				}
+				if toolDelta.Type != "" {
+					call.Type = toolDelta.Type
+				}
+				if toolDelta.Function.Name != "" {
+					call.Function.Name += toolDelta.Function.Name
+				}
+				call.Function.Arguments += toolDelta.Function.Arguments
+
+				handler(StreamEvent{
+					Type:       StreamEventToolCallDelta,
+					Text:       toolDelta.Function.Arguments,
+					ToolName:   call.Function.Name,
+					ToolCallID: call.ID,
+				})
+			}
+		}
+	}
+
+	indexes := make([]int, 0, len(choices))
+	for index := range choices {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		acc := choices[index]
+		role := acc.role
+		if role == "" {
+			role = openai.ChatMessageRoleAssistant
+		}
+
+		message := openai.ChatCompletionMessage{
+			Role:             role,
+			Content:          acc.content.String(),
+			ReasoningContent: acc.reasoning.String(),
+		}
+
+		callIndexes := make([]int, 0, len(acc.toolCalls))
+		for callIndex := range acc.toolCalls {
+			callIndexes = append(callIndexes, callIndex)
+		}
+		sort.Ints(callIndexes)
+		for _, callIndex := range callIndexes {
+			call := *acc.toolCalls[callIndex]
+			if call.ID == "" {
+				call.ID = fmt.Sprintf("call_%d", callIndex)
+			}
+			message.ToolCalls = append(message.ToolCalls, call)
+		}
+
+		response.Choices = append(response.Choices, openai.ChatCompletionChoice{
+			Index:        index,
+			Message:      message,
+			FinishReason: acc.finishReason,
+		})
+	}
+
+	fmt.Printf("DEBUG: Stream assembled %d choices for model %s\n", len(response.Choices), response.Model)
+	return response, nil
+}
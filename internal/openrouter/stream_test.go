@@ -0,0 +1,146 @@
+package openrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorka/internal/openrouter/adapters"
+	"gorka/internal/utils"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newTestClient builds a client that talks to the given base URL
+func newTestClient(baseURL string, streaming bool) *Client {
+	clientConfig := openai.DefaultConfig("test-key")
+	clientConfig.BaseURL = baseURL
+	clientConfig.HTTPClient = &http.Client{Transport: &retryAfterTransport{Transport: http.DefaultTransport}}
+
+	return &Client{
+		provider: NewOpenAICompatibleProvider(ProviderOpenRouter, clientConfig),
+		config: &utils.Config{
+			Model:               "test/model",
+			MaxContextSize:      1024,
+			EnableStreaming:     streaming,
+			MaxAttemptsPerModel: 3,
+		},
+		adapterRegistry: adapters.NewAdapterRegistry(),
+		xmlAdapter:      adapters.NewXMLAdapter(nil),
+		retryBaseDelay:  time.Millisecond,
+		retryMaxDelay:   5 * time.Millisecond,
+	}
+}
+
+// sseServer replies to chat completion requests with the given SSE chunks
+func sseServer(t *testing.T, chunks []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestStreamAssemblesContentAndUsage(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"id":"gen-1","model":"test/model","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}`,
+		`{"id":"gen-1","model":"test/model","choices":[{"index":0,"delta":{"content":", world"},"finish_reason":"stop"}]}`,
+		`{"id":"gen-1","model":"test/model","choices":[],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`,
+	})
+	defer server.Close()
+
+	var deltas []string
+	ctx := WithStreamHandler(context.Background(), func(event StreamEvent) {
+		if event.Type == StreamEventContent {
+			deltas = append(deltas, event.Text)
+		}
+	})
+
+	client := newTestClient(server.URL, true)
+	response, err := client.sendChatCompletion(ctx, openai.ChatCompletionRequest{Model: "test/model"})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	if len(response.Choices) != 1 {
+		t.Fatalf("expected 1 choice, got %d", len(response.Choices))
+	}
+	if got := response.Choices[0].Message.Content; got != "Hello, world" {
+		t.Errorf("unexpected content: %q", got)
+	}
+	if response.Choices[0].FinishReason != openai.FinishReasonStop {
+		t.Errorf("unexpected finish reason: %s", response.Choices[0].FinishReason)
+	}
+	if response.Usage.TotalTokens != 10 {
+		t.Errorf("expected usage from final chunk, got %+v", response.Usage)
+	}
+	if strings.Join(deltas, "|") != "Hello|, world" {
+		t.Errorf("unexpected deltas: %v", deltas)
+	}
+}
+
+func TestStreamAssemblesToolCallArguments(t *testing.T) {
+	server := sseServer(t, []string{
+		`{"id":"gen-2","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"read_file","arguments":""}}]}}]}`,
+		`{"id":"gen-2","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":"}}]}}]}`,
+		`{"id":"gen-2","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"list_dir","arguments":"{}"}}]}}]}`,
+		`{"id":"gen-2","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"main.go\"}"}}]},"finish_reason":"tool_calls"}]}`,
+	})
+	defer server.Close()
+
+	toolEvents := 0
+	ctx := WithStreamHandler(context.Background(), func(event StreamEvent) {
+		if event.Type == StreamEventToolCallDelta {
+			toolEvents++
+		}
+	})
+
+	client := newTestClient(server.URL, true)
+	response, err := client.sendChatCompletion(ctx, openai.ChatCompletionRequest{Model: "test/model"})
+	if err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	calls := response.Choices[0].Message.ToolCalls
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 tool calls, got %d", len(calls))
+	}
+	if calls[0].ID != "call_a" || calls[0].Function.Name != "read_file" || calls[0].Function.Arguments != `{"path":"main.go"}` {
+		t.Errorf("unexpected first tool call: %+v", calls[0])
+	}
+	if calls[1].ID != "call_b" || calls[1].Function.Name != "list_dir" {
+		t.Errorf("unexpected second tool call: %+v", calls[1])
+	}
+	if toolEvents != 4 {
+		t.Errorf("expected 4 tool call delta events, got %d", toolEvents)
+	}
+}
+
+func TestStreamingDisabledUsesBlockingCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"gen-3","model":"test/model","choices":[{"index":0,"message":{"role":"assistant","content":"done"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	events := 0
+	ctx := WithStreamHandler(context.Background(), func(event StreamEvent) { events++ })
+
+	client := newTestClient(server.URL, false)
+	response, err := client.sendChatCompletion(ctx, openai.ChatCompletionRequest{Model: "test/model"})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if response.Choices[0].Message.Content != "done" {
+		t.Errorf("unexpected content: %q", response.Choices[0].Message.Content)
+	}
+	if events != 0 {
+		t.Errorf("expected no stream events when streaming is disabled, got %d", events)
+	}
+}
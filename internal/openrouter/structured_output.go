@@ -0,0 +1,134 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gorka/internal/types"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/sashabaranov/go-openai"
+)
+
+// maxOutputRepairs bounds the follow-up turns spent asking for output that matches the schema
+const maxOutputRepairs = 2
+
+// structuredOutputModelPrefixes lists models known to honor response_format json_schema.
+// Models without a vendor prefix are matched as OpenAI models.
+var structuredOutputModelPrefixes = []string{
+	"openai/gpt-4o",
+	"openai/gpt-4.1",
+	"openai/gpt-5",
+	"openai/o1",
+	"openai/o3",
+	"openai/o4",
+	"google/gemini-",
+	"x-ai/grok-",
+}
+
+// structuredOutput is the output schema a matrix declares for an agent's final answer
+type structuredOutput struct {
+	name     string
+	schema   *jsonschema.Schema
+	resolved *jsonschema.Resolved
+}
+
+// newStructuredOutput derives the output schema from a matrix, returning nil when it declares none
+func newStructuredOutput(matrix *types.BehavioralMatrix) (*structuredOutput, error) {
+	schema, err := types.ExtractOutputSchema(matrix)
+	if err != nil || schema == nil {
+		return nil, err
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output schema for %s: %w", matrix.AgentID, err)
+	}
+	return &structuredOutput{
+		name:     strings.ReplaceAll(matrix.AgentID, "-", "_") + "_output",
+		schema:   schema,
+		resolved: resolved,
+	}, nil
+}
+
+// responseFormat returns the json_schema response format requesting this output
+func (o *structuredOutput) responseFormat() *openai.ChatCompletionResponseFormat {
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   o.name,
+			Schema: o.schema,
+			// Strict mode rejects the free-form objects matrices declare
+			Strict: false,
+		},
+	}
+}
+
+// parse extracts the JSON object from a final message and validates it against the schema
+func (o *structuredOutput) parse(content string) (map[string]interface{}, error) {
+	text := strings.TrimSpace(content)
+
+	// Tolerate code fences and prose around the object
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	} else {
+		return nil, fmt.Errorf("no JSON object found in the final answer")
+	}
+
+	var output map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &output); err != nil {
+		return nil, fmt.Errorf("final answer is not valid JSON: %v", err)
+	}
+	if err := o.resolved.Validate(output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// repairPrompt asks the model to restate its final answer as a matching JSON object
+func (o *structuredOutput) repairPrompt(problem error) string {
+	schemaJSON, _ := json.MarshalIndent(o.schema, "", "  ")
+	return fmt.Sprintf("Your final answer does not match the required output schema: %v\n\n"+
+		"Reply with only a JSON object matching this schema, without code fences or commentary:\n%s",
+		problem, schemaJSON)
+}
+
+type structuredOutputKey struct{}
+
+// withStructuredOutput requests output from every completion made with ctx
+func withStructuredOutput(ctx context.Context, output *structuredOutput) context.Context {
+	return context.WithValue(ctx, structuredOutputKey{}, output)
+}
+
+// structuredOutputFromContext returns the output registered on ctx, if any
+func structuredOutputFromContext(ctx context.Context) *structuredOutput {
+	output, _ := ctx.Value(structuredOutputKey{}).(*structuredOutput)
+	return output
+}
+
+// supportsStructuredOutputs reports whether model honors response_format json_schema.
+// Agent settings win over detection; local servers are trusted per backend.
+func (c *Client) supportsStructuredOutputs(model string, settings *types.LLMSettings) bool {
+	if settings != nil && settings.StructuredOutputs != nil {
+		return *settings.StructuredOutputs
+	}
+
+	switch c.config.Provider {
+	case ProviderAnthropic:
+		return false
+	case ProviderLocal:
+		return c.supportsResponseFormat
+	}
+
+	name := strings.ToLower(model)
+	if !strings.Contains(name, "/") {
+		name = "openai/" + name
+	}
+	for _, prefix := range structuredOutputModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
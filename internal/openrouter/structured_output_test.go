@@ -0,0 +1,152 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorka/internal/session"
+	"gorka/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func outputTestMatrix() *types.BehavioralMatrix {
+	return &types.BehavioralMatrix{
+		AgentID: "software-engineer",
+		Algorithm: map[string]interface{}{
+			"output": map[string]interface{}{
+				"syntax_validation":     "object",
+				"implementation_result": "object",
+				"findings":              "array",
+			},
+		},
+	}
+}
+
+func TestOutputSchemaFromMatrix(t *testing.T) {
+	output, err := newStructuredOutput(outputTestMatrix())
+	if err != nil || output == nil {
+		t.Fatalf("Expected an output schema, got %v (err=%v)", output, err)
+	}
+
+	if output.name != "software_engineer_output" {
+		t.Errorf("Unexpected schema name %q", output.name)
+	}
+	if want := []string{"findings", "implementation_result", "syntax_validation"}; !reflect.DeepEqual(output.schema.Required, want) {
+		t.Errorf("Expected sorted required fields %v, got %v", want, output.schema.Required)
+	}
+	if output.schema.Properties["findings"].Items != nil {
+		t.Error("Expected output arrays to accept any items")
+	}
+
+	if none, err := newStructuredOutput(&types.BehavioralMatrix{AgentID: "plain", Algorithm: map[string]interface{}{}}); none != nil || err != nil {
+		t.Errorf("Expected no schema for a matrix without output, got %v (err=%v)", none, err)
+	}
+}
+
+func TestStructuredOutputParse(t *testing.T) {
+	output, _ := newStructuredOutput(outputTestMatrix())
+
+	valid := "```json\n" + `{"implementation_result": {"files": ["a.go"]}, "syntax_validation": {"ok": true}, "findings": [{"line": 3}]}` +
+		"\n```"
+	got, err := output.parse(valid)
+	if err != nil {
+		t.Fatalf("Expected fenced JSON to parse: %v", err)
+	}
+	if _, ok := got["implementation_result"].(map[string]interface{}); !ok {
+		t.Errorf("Expected typed implementation_result, got %#v", got["implementation_result"])
+	}
+
+	invalid := map[string]string{
+		"prose":            "I implemented the feature.",
+		"missing required": `{"implementation_result": {}, "findings": []}`,
+		"wrong type":       `{"implementation_result": "done", "syntax_validation": {}, "findings": []}`,
+	}
+	for name, content := range invalid {
+		if _, err := output.parse(content); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestSupportsStructuredOutputs(t *testing.T) {
+	client := newTestClient("http://unused", false)
+	on, off := true, false
+
+	tests := []struct {
+		provider string
+		model    string
+		settings *types.LLMSettings
+		want     bool
+	}{
+		{ProviderOpenRouter, "openai/gpt-4o-mini", nil, true},
+		{ProviderOpenRouter, "google/gemini-2.5-flash", nil, true},
+		{ProviderOpenRouter, "qwen/qwen3-32b:free", nil, false},
+		{ProviderOpenRouter, "qwen/qwen3-32b:free", &types.LLMSettings{StructuredOutputs: &on}, true},
+		{ProviderOpenAI, "gpt-4.1", nil, true},
+		{ProviderOpenAI, "gpt-4.1", &types.LLMSettings{StructuredOutputs: &off}, false},
+		{ProviderAnthropic, "claude-sonnet-4-20250514", nil, false},
+		{ProviderLocal, "Qwen/Qwen3-8B", nil, false},
+	}
+
+	for _, tt := range tests {
+		client.config.Provider = tt.provider
+		if got := client.supportsStructuredOutputs(tt.model, tt.settings); got != tt.want {
+			t.Errorf("%s %s: expected %v, got %v", tt.provider, tt.model, tt.want, got)
+		}
+	}
+}
+
+func TestSpawnAgentRepairsOutput(t *testing.T) {
+	replies := []string{
+		"I implemented the change in main.go.",
+		`{"implementation_result": {"files": ["main.go"]}, "syntax_validation": {"ok": true}, "findings": []}`,
+	}
+	var requests []openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+		replyContent(w, "test/model", replies[len(requests)-1])
+	}))
+	defer server.Close()
+
+	structured := true
+	matrix := outputTestMatrix()
+	matrix.LLM = &types.LLMSettings{StructuredOutputs: &structured}
+
+	spawner := &AgentSpawner{
+		client:         newTestClient(server.URL, false),
+		sessionManager: session.NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions")),
+	}
+
+	result, err := spawner.SpawnAgentWithContext(context.Background(), matrix, "implement it")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected one repair round-trip, got %d requests", len(requests))
+	}
+	format := requests[0].ResponseFormat
+	if format == nil || format.Type != openai.ChatCompletionResponseFormatTypeJSONSchema || format.JSONSchema.Name != "software_engineer_output" {
+		t.Errorf("Expected json_schema response format, got %+v", format)
+	}
+	repair := requests[1].Messages[len(requests[1].Messages)-1]
+	if repair.Role != openai.ChatMessageRoleUser || !strings.Contains(repair.Content, "does not match the required output schema") {
+		t.Errorf("Expected a repair prompt, got %+v", repair)
+	}
+
+	if result.OutputError != "" {
+		t.Errorf("Unexpected output error: %s", result.OutputError)
+	}
+	if files := result.StructuredOutput["implementation_result"]; files == nil {
+		t.Errorf("Expected structured output, got %#v", result.StructuredOutput)
+	}
+}
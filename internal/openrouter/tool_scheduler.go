@@ -0,0 +1,166 @@
+package openrouter
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// toolAccessMode says how a tool call may interfere with other calls in the same turn
+type toolAccessMode int
+
+const (
+	toolAccessRead toolAccessMode = iota
+	toolAccessWrite
+	// toolAccessExclusive waits for every earlier call and blocks every later one
+	toolAccessExclusive
+)
+
+// toolRule describes the state a known tool touches. pathParam names the argument holding
+// the path; when it is empty or missing, workspaceWide tools touch the whole workspace and
+// the rest touch no workspace files at all.
+type toolRule struct {
+	mode          toolAccessMode
+	pathParam     string
+	workspaceWide bool
+}
+
+// toolRules lists the tools that may run concurrently. Tools not listed here, such as the
+// knowledge-graph writers, thinking tools and spawned agents, run exclusively.
+var toolRules = map[string]toolRule{
+	"read_file":              {mode: toolAccessRead, pathParam: "file_path"},
+	"view_image":             {mode: toolAccessRead, pathParam: "file_path"},
+	"list_dir":               {mode: toolAccessRead, pathParam: "path", workspaceWide: true},
+	"grep_search":            {mode: toolAccessRead, workspaceWide: true},
+	"file_search":            {mode: toolAccessRead, workspaceWide: true},
+	"search_nodes":           {mode: toolAccessRead},
+	"read_graph":             {mode: toolAccessRead},
+	"fetch":                  {mode: toolAccessRead},
+	"get_system_info":        {mode: toolAccessRead},
+	"create_file":            {mode: toolAccessWrite, pathParam: "file_path", workspaceWide: true},
+	"replace_string_in_file": {mode: toolAccessWrite, pathParam: "file_path", workspaceWide: true},
+	// A shell command can reach any path whatever its work_dir, so it runs alone
+	"exec": {mode: toolAccessExclusive},
+}
+
+// toolAccess is what one tool call touches; path is absolute, or empty for no workspace files
+type toolAccess struct {
+	mode toolAccessMode
+	path string
+}
+
+// toolScheduler runs a turn's tool calls, overlapping those that cannot interfere:
+// reads run concurrently, writes are ordered against earlier calls on overlapping paths,
+// and unknown tools run alone
+type toolScheduler struct {
+	workspace   string
+	maxParallel int
+}
+
+// newToolScheduler creates a scheduler for tools rooted at workspace
+func newToolScheduler(workspace string, maxParallel int) *toolScheduler {
+	// Relative tool paths resolve against the workspace, so compare everything as absolute paths
+	if abs, err := filepath.Abs(workspace); err == nil {
+		workspace = abs
+	}
+	return &toolScheduler{workspace: workspace, maxParallel: maxParallel}
+}
+
+// run executes every call and returns once all have finished. execute receives the call's
+// index, so callers can collect results in call order no matter when each call completes.
+func (s *toolScheduler) run(calls []openai.ToolCall, execute func(index int, call openai.ToolCall)) {
+	if s.maxParallel <= 1 || len(calls) <= 1 {
+		for i, call := range calls {
+			execute(i, call)
+		}
+		return
+	}
+
+	accesses := make([]toolAccess, len(calls))
+	for i, call := range calls {
+		accesses[i] = s.access(call)
+	}
+
+	done := make([]chan struct{}, len(calls))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	slots := make(chan struct{}, s.maxParallel)
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		// Dependencies only point backwards, so waiting on them cannot deadlock
+		var deps []int
+		for j := 0; j < i; j++ {
+			if accesses[j].conflicts(accesses[i]) {
+				deps = append(deps, j)
+			}
+		}
+
+		wg.Add(1)
+		go func(i int, call openai.ToolCall, deps []int) {
+			defer wg.Done()
+			defer close(done[i])
+			for _, dep := range deps {
+				<-done[dep]
+			}
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			execute(i, call)
+		}(i, call, deps)
+	}
+	wg.Wait()
+}
+
+// access classifies a call by its tool rule and path argument
+func (s *toolScheduler) access(call openai.ToolCall) toolAccess {
+	rule, known := toolRules[call.Function.Name]
+	if !known {
+		return toolAccess{mode: toolAccessExclusive}
+	}
+
+	path := ""
+	if rule.pathParam != "" {
+		var params map[string]interface{}
+		if json.Unmarshal([]byte(call.Function.Arguments), &params) == nil {
+			path, _ = params[rule.pathParam].(string)
+		}
+	}
+
+	switch {
+	case path != "":
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.workspace, path)
+		}
+		path = filepath.Clean(path)
+	case rule.workspaceWide:
+		path = s.workspace
+	}
+	return toolAccess{mode: rule.mode, path: path}
+}
+
+// conflicts reports whether two calls must keep their relative order
+func (a toolAccess) conflicts(b toolAccess) bool {
+	if a.mode == toolAccessExclusive || b.mode == toolAccessExclusive {
+		return true
+	}
+	if a.mode == toolAccessRead && b.mode == toolAccessRead {
+		return false
+	}
+	return pathsOverlap(a.path, b.path)
+}
+
+// pathsOverlap reports whether one path is the other or contains it
+func pathsOverlap(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if a == b {
+		return true
+	}
+	return strings.HasPrefix(a, strings.TrimSuffix(b, string(filepath.Separator))+string(filepath.Separator)) ||
+		strings.HasPrefix(b, strings.TrimSuffix(a, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,147 @@
+package openrouter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func schedulerCall(name, arguments string) openai.ToolCall {
+	return openai.ToolCall{
+		Type:     openai.ToolTypeFunction,
+		Function: openai.FunctionCall{Name: name, Arguments: arguments},
+	}
+}
+
+func TestToolAccessConflicts(t *testing.T) {
+	scheduler := newToolScheduler("/work", 4)
+
+	readMain := schedulerCall("read_file", `{"file_path": "main.go"}`)
+	readAbsMain := schedulerCall("read_file", `{"file_path": "/work/main.go"}`)
+	readOther := schedulerCall("read_file", `{"file_path": "other.go"}`)
+	writeMain := schedulerCall("replace_string_in_file", `{"file_path": "./main.go", "old_string": "a", "new_string": "b"}`)
+	writeDoc := schedulerCall("create_file", `{"file_path": "docs/a.md", "content": "x"}`)
+	listDocs := schedulerCall("list_dir", `{"path": "docs"}`)
+	grep := schedulerCall("grep_search", `{"query": "TODO"}`)
+	fetch := schedulerCall("fetch", `{"url": "https://example.com"}`)
+	execDocs := schedulerCall("exec", `{"command": "ls", "work_dir": "docs"}`)
+	execSrc := schedulerCall("exec", `{"command": "go build", "work_dir": "src"}`)
+	viewImage := schedulerCall("view_image", `{"file_path": "docs/screenshot.png"}`)
+	think := schedulerCall("think_hard", `{}`)
+
+	tests := []struct {
+		name string
+		a, b openai.ToolCall
+		want bool
+	}{
+		{"reads overlap freely", readMain, readAbsMain, false},
+		{"read and workspace search", readMain, grep, false},
+		{"write and read of same file", writeMain, readAbsMain, true},
+		{"write and read of another file", writeMain, readOther, false},
+		{"write and workspace search", writeMain, grep, true},
+		{"write inside listed directory", writeDoc, listDocs, true},
+		{"write outside listed directory", writeMain, listDocs, false},
+		{"exec and file in its directory", execDocs, writeDoc, true},
+		{"exec and file elsewhere", execDocs, readMain, true},
+		{"execs in sibling directories", execDocs, execSrc, true},
+		{"image view and read", viewImage, readMain, false},
+		{"image view and write of another file", viewImage, writeMain, false},
+		{"image view and its directory's listing", viewImage, listDocs, false},
+		{"fetch and write", fetch, writeMain, false},
+		{"unknown tool and fetch", think, fetch, true},
+	}
+
+	for _, tt := range tests {
+		if got := scheduler.access(tt.a).conflicts(scheduler.access(tt.b)); got != tt.want {
+			t.Errorf("%s: expected conflict=%v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestSchedulerRunsReadsConcurrently(t *testing.T) {
+	calls := []openai.ToolCall{
+		schedulerCall("read_file", `{"file_path": "a.go"}`),
+		schedulerCall("grep_search", `{"query": "x"}`),
+		schedulerCall("fetch", `{"url": "https://example.com"}`),
+	}
+
+	// Each call waits until all of them have started, which only succeeds if they overlap
+	var started sync.WaitGroup
+	started.Add(len(calls))
+	results := make([]string, len(calls))
+	newToolScheduler(t.TempDir(), 4).run(calls, func(i int, call openai.ToolCall) {
+		started.Done()
+		waited := make(chan struct{})
+		go func() { started.Wait(); close(waited) }()
+		select {
+		case <-waited:
+			results[i] = call.Function.Name
+		case <-time.After(2 * time.Second):
+			results[i] = "timed out"
+		}
+	})
+
+	for i, call := range calls {
+		if results[i] != call.Function.Name {
+			t.Errorf("Call %d: expected %s, got %s", i, call.Function.Name, results[i])
+		}
+	}
+}
+
+func TestSchedulerOrdersConflictingCalls(t *testing.T) {
+	calls := []openai.ToolCall{
+		schedulerCall("create_file", `{"file_path": "a.go", "content": "x"}`),
+		schedulerCall("read_file", `{"file_path": "a.go"}`),
+		schedulerCall("read_file", `{"file_path": "b.go"}`),
+		schedulerCall("think_hard", `{}`),
+		schedulerCall("read_file", `{"file_path": "c.go"}`),
+	}
+
+	var mu sync.Mutex
+	var order []int
+	newToolScheduler(t.TempDir(), 4).run(calls, func(i int, call openai.ToolCall) {
+		if i == 0 {
+			// Give the dependent read every chance to jump ahead
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		order = append(order, i)
+		mu.Unlock()
+	})
+
+	position := make(map[int]int)
+	for at, i := range order {
+		position[i] = at
+	}
+	if len(position) != len(calls) {
+		t.Fatalf("Expected every call to run once, got order %v", order)
+	}
+	for _, edge := range [][2]int{{0, 1}, {0, 3}, {1, 3}, {2, 3}, {3, 4}} {
+		if position[edge[0]] > position[edge[1]] {
+			t.Errorf("Call %d ran before call %d: %v", edge[1], edge[0], order)
+		}
+	}
+	if position[2] > position[0] {
+		t.Errorf("Expected the unrelated read to overtake the slow write: %v", order)
+	}
+}
+
+func TestSchedulerSerialWhenDisabled(t *testing.T) {
+	var calls []openai.ToolCall
+	for i := 0; i < 5; i++ {
+		calls = append(calls, schedulerCall("read_file", fmt.Sprintf(`{"file_path": "%d.go"}`, i)))
+	}
+
+	var order []int
+	newToolScheduler(t.TempDir(), 1).run(calls, func(i int, call openai.ToolCall) {
+		order = append(order, i)
+	})
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("Expected calls in order, got %v", order)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package openrouter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorka/internal/models"
+	"gorka/internal/tools/media"
+	"gorka/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// visionModelPrefixes lists models known to accept image content parts, for models the
+// registry has no metadata for.
+// Models without a vendor prefix are matched as OpenAI models.
+var visionModelPrefixes = []string{
+	"openai/gpt-4o",
+	"openai/gpt-4.1",
+	"openai/gpt-5",
+	"openai/o1",
+	"openai/o3",
+	"openai/o4",
+	"anthropic/claude-3",
+	"anthropic/claude-sonnet-4",
+	"anthropic/claude-opus-4",
+	"google/gemini-",
+	"x-ai/grok-4",
+	"meta-llama/llama-4",
+	"meta-llama/llama-3.2-11b-vision",
+	"meta-llama/llama-3.2-90b-vision",
+	"qwen/qwen2.5-vl",
+	"qwen/qwen-vl",
+	"mistralai/pixtral",
+	"mistralai/mistral-medium-3",
+}
+
+// supportsVision reports whether model accepts images.
+// Agent settings win over detection; local servers are only trusted when told.
+func (c *Client) supportsVision(model string, settings *types.LLMSettings) bool {
+	if settings != nil && settings.Vision != nil {
+		return *settings.Vision
+	}
+
+	switch c.config.Provider {
+	case ProviderAnthropic:
+		return true
+	case ProviderLocal:
+		return false
+	}
+
+	// Listed input modalities beat the prefix table
+	if caps, ok := models.Lookup(model); ok {
+		return caps.SupportsImages
+	}
+
+	name := strings.ToLower(model)
+	if !strings.Contains(name, "/") {
+		name = "openai/" + name
+	}
+	for _, prefix := range visionModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageContext lets a tool call attach images when the model can see them; otherwise tools
+// report that the image cannot be shown
+func imageContext(ctx context.Context, vision bool) (context.Context, *media.Collector) {
+	if !vision {
+		return ctx, nil
+	}
+	return media.WithCollector(ctx)
+}
+
+// collectedImages returns the images attached through collector, which may be nil
+func collectedImages(collector *media.Collector) []*media.Image {
+	if collector == nil {
+		return nil
+	}
+	return collector.Images()
+}
+
+// imageParts captions the images one tool call returned with the tool that produced them
+func imageParts(toolName string, images []*media.Image) []openai.ChatMessagePart {
+	var parts []openai.ChatMessagePart
+	for _, img := range images {
+		parts = append(parts,
+			openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: fmt.Sprintf("Image from %s: %s (%dx%d)", toolName, img.Path, img.Width, img.Height),
+			},
+			openai.ChatMessagePart{
+				Type:     openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{URL: img.DataURL(), Detail: openai.ImageURLDetailAuto},
+			},
+		)
+	}
+	return parts
+}
+
+// imageMessage shows tool images to the model. Tool messages only carry text, so the images
+// follow the tool results as a user message.
+func imageMessage(parts []openai.ChatMessagePart) openai.ChatCompletionMessage {
+	return openai.ChatCompletionMessage{
+		Role:         openai.ChatMessageRoleUser,
+		MultiContent: parts,
+	}
+}
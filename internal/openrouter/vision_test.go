@@ -0,0 +1,112 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorka/internal/session"
+	"gorka/internal/tools"
+	"gorka/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// screenshotServer asks to view the image at path, then answers with whatever it was sent
+func screenshotServer(t *testing.T, path string, requests *[]openai.ChatCompletionRequest) *httptest.Server {
+	arguments, _ := json.Marshal(map[string]string{"file_path": path})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		*requests = append(*requests, request)
+
+		if len(*requests) > 1 {
+			replyContent(w, "test/model", "The button is blue.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"gen-1","model":"test/model","choices":[{"index":0,"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"view_image","arguments":%q}}]},"finish_reason":"tool_calls"}]}`, arguments)
+	}))
+}
+
+func TestToolImagesReachVisionModels(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		wantImage bool
+	}{
+		{name: "vision model", model: "openai/gpt-4o", wantImage: true},
+		{name: "text-only model", model: "test/model", wantImage: false},
+	}
+
+	for _, tt := range tests {
+		workspace := t.TempDir()
+		path := filepath.Join(workspace, "ui.png")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(file, image.NewRGBA(image.Rect(0, 0, 8, 4)))
+		file.Close()
+
+		var requests []openai.ChatCompletionRequest
+		server := screenshotServer(t, path, &requests)
+
+		toolsManager := tools.NewToolsManager(workspace, filepath.Join(workspace, "storage"))
+		client := newTestClient(server.URL, false)
+		client.toolsManager = toolsManager
+		client.config.Model = tt.model
+		client.RefreshTools()
+		spawner := &AgentSpawner{
+			client:         client,
+			toolsManager:   toolsManager,
+			sessionManager: session.NewSessionManagerWithDir(filepath.Join(workspace, "sessions")),
+		}
+
+		_, err = spawner.SpawnAgentWithContext(context.Background(), &types.BehavioralMatrix{AgentID: "test_agent"}, "check the screenshot")
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: spawn failed: %v", tt.name, err)
+		}
+		if len(requests) != 2 {
+			t.Fatalf("%s: expected a follow-up call, got %d requests", tt.name, len(requests))
+		}
+
+		followUp := requests[1].Messages
+		last := followUp[len(followUp)-1]
+		var toolResult openai.ChatCompletionMessage
+		for _, message := range followUp {
+			if message.Role == openai.ChatMessageRoleTool {
+				toolResult = message
+			}
+		}
+
+		if !tt.wantImage {
+			if len(last.MultiContent) > 0 {
+				t.Errorf("%s: expected no image message, got %+v", tt.name, last)
+			}
+			if !strings.Contains(toolResult.Content, "cannot be shown") {
+				t.Errorf("%s: expected the tool result to say the image cannot be shown, got %q", tt.name, toolResult.Content)
+			}
+			continue
+		}
+
+		if last.Role != openai.ChatMessageRoleUser || len(last.MultiContent) != 2 {
+			t.Fatalf("%s: expected a user message with a caption and an image, got %+v", tt.name, last)
+		}
+		imagePart := last.MultiContent[1]
+		if imagePart.ImageURL == nil || !strings.HasPrefix(imagePart.ImageURL.URL, "data:image/png;base64,") {
+			t.Errorf("%s: expected a PNG data URL, got %+v", tt.name, imagePart)
+		}
+		if !strings.Contains(toolResult.Content, `"width":8`) || !strings.Contains(toolResult.Content, "attached") {
+			t.Errorf("%s: unexpected tool result %q", tt.name, toolResult.Content)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorka/internal/session"
+	"gorka/internal/tools"
+	"gorka/internal/types"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestXMLToolCallsExecuteWithCoercedParameters(t *testing.T) {
+	replies := []string{
+		"<function_calls>\n<invoke name=\"repeat_word\">\n<parameter name=\"word\">go</parameter>\n<parameter name=\"times\">3</parameter>\n</invoke>\n</function_calls>",
+		"Repeated it.",
+	}
+	var requests []openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+		replyContent(w, "test/model", replies[(len(requests)-1)%len(replies)])
+	}))
+	defer server.Close()
+
+	workspace := t.TempDir()
+	toolsManager := tools.NewToolsManager(workspace, filepath.Join(workspace, "storage"))
+	var gotTimes interface{}
+	toolsManager.RegisterOpenAITool("repeat_word", "Repeat a word", &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"word":  {Type: "string"},
+			"times": {Type: "integer"},
+		},
+	}, func(ctx context.Context, params map[string]interface{}) (string, error) {
+		gotTimes = params["times"]
+		times, ok := params["times"].(float64)
+		if !ok {
+			return "", fmt.Errorf("times is %T", params["times"])
+		}
+		return strings.Repeat(params["word"].(string), int(times)), nil
+	})
+
+	client := newTestClient(server.URL, false)
+	client.toolsManager = toolsManager
+	client.RefreshTools()
+
+	// The client hands the calls back instead of running them
+	completion, err := client.CreateChatCompletionWithSettings(context.Background(), []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "repeat go three times"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Completion failed: %v", err)
+	}
+	if len(requests) != 1 || gotTimes != nil {
+		t.Fatalf("Expected the client to leave the calls to the caller, got %d requests", len(requests))
+	}
+	if completion.ToolCallMode != "xml_tools" || len(completion.XMLToolCalls) != 1 || completion.XMLToolCalls[0].Function != "repeat_word" {
+		t.Fatalf("Expected one parsed XML call, got %+v", completion.XMLToolCalls)
+	}
+
+	// The agent loop runs them and sends the results back
+	requests = nil
+	spawner := &AgentSpawner{
+		client:         client,
+		toolsManager:   toolsManager,
+		sessionManager: session.NewSessionManagerWithDir(filepath.Join(workspace, "sessions")),
+	}
+	result, err := spawner.SpawnAgentWithContext(context.Background(), &types.BehavioralMatrix{AgentID: "test_agent"}, "repeat go three times")
+	if err != nil {
+		t.Fatalf("Spawn failed: %v", err)
+	}
+
+	if gotTimes != float64(3) {
+		t.Errorf("Expected times coerced to 3, got %#v", gotTimes)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected a follow-up call, got %d requests", len(requests))
+	}
+	results := requests[1].Messages[len(requests[1].Messages)-1]
+	if results.Role != openai.ChatMessageRoleUser || !strings.Contains(results.Content, "<stdout>\ngogogo\n</stdout>") {
+		t.Errorf("Unexpected function results message: %+v", results)
+	}
+
+	if content := result.Response.Choices[0].Message.Content; content != "Repeated it." {
+		t.Errorf("Unexpected final content: %q", content)
+	}
+	if len(result.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call record, got %+v", result.ToolCalls)
+	}
+	record := result.ToolCalls[0]
+	if record.Name != "repeat_word" || record.Mode != "xml_tools" || record.Error != "" || record.ResultSize != len("gogogo") {
+		t.Errorf("Unexpected tool call record: %+v", record)
+	}
+
+	// The whole exchange is kept in the session
+	agentSession, _ := spawner.sessionManager.GetSession(result.SessionID)
+	var roles []string
+	for _, msg := range agentSession.Messages {
+		roles = append(roles, msg.Role)
+	}
+	if want := "system user assistant user assistant"; strings.Join(roles, " ") != want {
+		t.Errorf("Expected session roles %q, got %q", want, strings.Join(roles, " "))
+	}
+}
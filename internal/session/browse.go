@@ -0,0 +1,238 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Session statuses accepted by SessionFilter
+const (
+	StatusActive      = "active"
+	StatusCompleted   = "completed"
+	StatusInterrupted = "interrupted"
+)
+
+// SessionFilter selects stored sessions; zero fields match every session
+type SessionFilter struct {
+	AgentID string
+	// Status is StatusActive, StatusCompleted or StatusInterrupted
+	Status string
+	// Since and Until select sessions that were in use at some point between them
+	Since time.Time
+	Until time.Time
+	// Text matches message contents, tool calls and working memory, ignoring case
+	Text string
+}
+
+// SearchHit is a message that matched a text search
+type SearchHit struct {
+	SessionID string
+	AgentID   string
+	Index     int
+	Role      string
+	Snippet   string
+}
+
+// Status returns StatusActive, StatusCompleted or StatusInterrupted
+func (s *AgentSession) Status() string {
+	if s.Completed {
+		return StatusCompleted
+	}
+	if s.Interruption != nil {
+		return StatusInterrupted
+	}
+	return StatusActive
+}
+
+// ListSessions returns copies of the sessions matching filter, most recently updated first
+func (sm *SessionManager) ListSessions(filter SessionFilter) []*AgentSession {
+	sm.sessionMutex.RLock()
+	defer sm.sessionMutex.RUnlock()
+
+	ids, err := sm.store.List(filter.Status)
+	if err != nil {
+		fmt.Printf("WARNING: Failed to list sessions: %v\n", err)
+	}
+
+	var sessions []*AgentSession
+	for _, sessionID := range ids {
+		// Sessions in memory may be newer than the store; the others are read without keeping them
+		session, loaded := sm.sessions[sessionID]
+		if !loaded {
+			if session, err = sm.store.Load(sessionID); err != nil {
+				fmt.Printf("WARNING: Failed to load session %s: %v\n", sessionID, err)
+				continue
+			}
+		}
+		if filter.matches(session) {
+			sessionCopy := *session
+			sessions = append(sessions, &sessionCopy)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].UpdatedAt.Equal(sessions[j].UpdatedAt) {
+			return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// SearchSessions returns every message of the sessions matching filter that contains filter.Text
+func (sm *SessionManager) SearchSessions(filter SessionFilter) []SearchHit {
+	if filter.Text == "" {
+		return nil
+	}
+
+	var hits []SearchHit
+	for _, session := range sm.ListSessions(filter) {
+		for i, msg := range session.Messages {
+			text := searchableText(msg)
+			if at := indexFold(text, filter.Text); at >= 0 {
+				hits = append(hits, SearchHit{
+					SessionID: session.ID,
+					AgentID:   session.AgentID,
+					Index:     i,
+					Role:      msg.Role,
+					Snippet:   snippet(text, at, len(filter.Text)),
+				})
+			}
+		}
+		if at := indexFold(session.WorkingMemory, filter.Text); at >= 0 {
+			hits = append(hits, SearchHit{
+				SessionID: session.ID,
+				AgentID:   session.AgentID,
+				Index:     -1,
+				Role:      "working_memory",
+				Snippet:   snippet(session.WorkingMemory, at, len(filter.Text)),
+			})
+		}
+	}
+	return hits
+}
+
+// RemoveSession deletes a session from memory and from the store
+func (sm *SessionManager) RemoveSession(sessionID string) error {
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
+
+	delete(sm.sessions, sessionID)
+	if err := sm.store.Remove(sessionID); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("session %s not found", sessionID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Close flushes the session writes the store has not synced to disk yet
+func (sm *SessionManager) Close() error {
+	return sm.store.Close()
+}
+
+// CompactStorage rewrites a session's storage without the records later updates superseded.
+// The conversation and working memory are unchanged.
+func (sm *SessionManager) CompactStorage(sessionID string) error {
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
+
+	if err := sm.store.Compact(sessionID); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("session %s not found", sessionID)
+		}
+		return err
+	}
+	return nil
+}
+
+// matches reports whether a session passes every filter that is set
+func (f SessionFilter) matches(session *AgentSession) bool {
+	if f.AgentID != "" && session.AgentID != f.AgentID {
+		return false
+	}
+	if f.Status != "" && session.Status() != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && session.UpdatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && session.CreatedAt.After(f.Until) {
+		return false
+	}
+	if f.Text == "" || indexFold(session.WorkingMemory, f.Text) >= 0 {
+		return true
+	}
+	for _, msg := range session.Messages {
+		if indexFold(searchableText(msg), f.Text) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// searchableText joins the text of a message with its reasoning and tool calls
+func searchableText(msg openai.ChatCompletionMessage) string {
+	parts := []string{msg.Content}
+	for _, part := range msg.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			parts = append(parts, part.Text)
+		}
+	}
+	if msg.ReasoningContent != "" {
+		parts = append(parts, msg.ReasoningContent)
+	}
+	for _, call := range msg.ToolCalls {
+		parts = append(parts, call.Function.Name+" "+call.Function.Arguments)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// indexFold returns the byte offset of the first case-insensitive match of substr in s, or -1
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// snippetContext is how many bytes around a match a search snippet shows
+const snippetContext = 60
+
+// snippet returns the match at s[at:at+length] with some context, on a single line
+func snippet(s string, at, length int) string {
+	// Lowercasing can change byte lengths, so keep the match inside s
+	if at > len(s) {
+		at = len(s)
+	}
+	start := at - snippetContext
+	if start < 0 {
+		start = 0
+	}
+	end := at + length + snippetContext
+	if end > len(s) {
+		end = len(s)
+	}
+
+	// Avoid cutting a multi-byte character in half
+	for start > 0 && !utf8.RuneStart(s[start]) {
+		start--
+	}
+	for end < len(s) && !utf8.RuneStart(s[end]) {
+		end++
+	}
+
+	text := strings.Join(strings.Fields(s[start:end]), " ")
+	if start > 0 {
+		text = "..." + text
+	}
+	if end < len(s) {
+		text += "..."
+	}
+	return text
+}
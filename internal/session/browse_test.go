@@ -0,0 +1,142 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorka/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// reviewSession stores a completed session that read a file containing a code fence
+func reviewSession(t *testing.T, sm *SessionManager, agentID string) *AgentSession {
+	session, err := sm.CreateSession(agentID, &types.BehavioralMatrix{AgentID: agentID}, "")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "Review README.md"})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{
+		Role:             openai.ChatMessageRoleAssistant,
+		ReasoningContent: "Start with the <install> section",
+		ToolCalls:        []openai.ToolCall{{ID: "call_1", Function: openai.FunctionCall{Name: "read_file", Arguments: `{"file_path":"README.md"}`}}},
+	})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: "Install:\n```sh\nmake install\n```"})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "The install steps use Make."})
+	sm.SetStopReason(session.ID, "completed")
+	sm.CompleteSession(session.ID)
+	return session
+}
+
+func TestListAndSearchSessions(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	reviewed := reviewSession(t, sm, "reviewer")
+	running, _ := sm.CreateSession("engineer", &types.BehavioralMatrix{AgentID: "engineer"}, "")
+	sm.AddMessage(running.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "fix the MAKEFILE"})
+
+	// Browsing works on what a fresh manager loads from disk
+	sm = NewSessionManagerWithDir(storageDir)
+	tests := []struct {
+		name   string
+		filter SessionFilter
+		want   []string
+	}{
+		{"everything", SessionFilter{}, []string{running.ID, reviewed.ID}},
+		{"agent", SessionFilter{AgentID: "reviewer"}, []string{reviewed.ID}},
+		{"status", SessionFilter{Status: StatusActive}, []string{running.ID}},
+		{"text in a tool call", SessionFilter{Text: "readme.MD"}, []string{reviewed.ID}},
+		{"text in both", SessionFilter{Text: "make"}, []string{running.ID, reviewed.ID}},
+		{"since", SessionFilter{Since: time.Now().Add(time.Hour)}, nil},
+		{"until", SessionFilter{Until: time.Now().Add(-time.Hour)}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range sm.ListSessions(tt.filter) {
+			got = append(got, s.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	loaded, _ := sm.GetSession(reviewed.ID)
+	if loaded.StopReason != "completed" {
+		t.Errorf("Expected the stop reason to be stored, got %q", loaded.StopReason)
+	}
+
+	hits := sm.SearchSessions(SessionFilter{AgentID: "reviewer", Text: "make install"})
+	if len(hits) != 1 || hits[0].Index != 3 || hits[0].Role != openai.ChatMessageRoleTool || !strings.Contains(hits[0].Snippet, "```sh make install ```") {
+		t.Errorf("Expected the tool result on one line, got %+v", hits)
+	}
+}
+
+func TestRemoveSession(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	session := reviewSession(t, sm, "reviewer")
+
+	if err := sm.RemoveSession(session.ID); err != nil {
+		t.Fatalf("Failed to remove session: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storageDir, StatusCompleted, session.ID+".jsonl")); !os.IsNotExist(err) {
+		t.Errorf("Expected the session file to be removed, got %v", err)
+	}
+	if _, exists := NewSessionManagerWithDir(storageDir).GetSession(session.ID); exists {
+		t.Error("Expected the session to stay removed after a restart")
+	}
+	if err := sm.RemoveSession(session.ID); err == nil {
+		t.Error("Expected removing an unknown session to fail")
+	}
+}
+
+func TestExportTranscript(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+	session := reviewSession(t, sm, "reviewer")
+
+	var markdown bytes.Buffer
+	if err := Export(&markdown, session, FormatMarkdown); err != nil {
+		t.Fatalf("Markdown export failed: %v", err)
+	}
+	for _, want := range []string{
+		"- **Status:** completed (completed)",
+		"## 4. Tool result: read_file",
+		"**Tool call** `read_file` *(call_1)*\n\n```json\n{\n  \"file_path\": \"README.md\"\n}\n```",
+		"> **Reasoning**",
+		// The file's own fence must not close the block around it
+		"````\nInstall:\n```sh\nmake install\n```\n````",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("Expected %q in the Markdown transcript:\n%s", want, markdown.String())
+		}
+	}
+
+	var page bytes.Buffer
+	if err := Export(&page, session, FormatHTML); err != nil {
+		t.Fatalf("HTML export failed: %v", err)
+	}
+	if !strings.Contains(page.String(), "Start with the &lt;install&gt; section") || strings.Contains(page.String(), "<install>") {
+		t.Error("Expected message text to be escaped in the HTML transcript")
+	}
+
+	var lines bytes.Buffer
+	if err := Export(&lines, session, FormatJSONL); err != nil {
+		t.Fatalf("JSONL export failed: %v", err)
+	}
+	entries := strings.Split(strings.TrimSpace(lines.String()), "\n")
+	if len(entries) != len(session.Messages) {
+		t.Fatalf("Expected one line per message, got %d", len(entries))
+	}
+	var result transcriptEntry
+	if err := json.Unmarshal([]byte(entries[3]), &result); err != nil || result.ToolName != "read_file" || result.SessionID != session.ID {
+		t.Errorf("Expected a tool result naming its tool, got %+v, %v", result, err)
+	}
+
+	if err := Export(&lines, session, "pdf"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"gorka/internal/tokenizer"
+	"github.com/sashabaranov/go-openai"
+)
+
+// WorkingMemoryHeader opens the message that stands in for compacted turns
+const WorkingMemoryHeader = "Working memory from earlier in this task:"
+
+// CompactionStrategy folds older turns of a conversation into a working memory summary
+type CompactionStrategy interface {
+	// Compact returns memory updated with what the turns found, decided and left open; memory is
+	// empty on a session's first compaction
+	Compact(ctx context.Context, memory string, turns []openai.ChatCompletionMessage) (string, error)
+}
+
+// CompactionFunc adapts a function to a CompactionStrategy
+type CompactionFunc func(ctx context.Context, memory string, turns []openai.ChatCompletionMessage) (string, error)
+
+// Compact calls f
+func (f CompactionFunc) Compact(ctx context.Context, memory string, turns []openai.ChatCompletionMessage) (string, error) {
+	return f(ctx, memory, turns)
+}
+
+// contextMessages returns the messages the model sees: the system prompt, the working memory
+// and the turns after it, or every message before the first compaction
+func (s *AgentSession) contextMessages() []openai.ChatCompletionMessage {
+	if s.CompactedMessages == 0 || s.CompactedMessages > len(s.Messages) {
+		return s.Messages
+	}
+
+	messages := []openai.ChatCompletionMessage{
+		s.Messages[0],
+		{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: WorkingMemoryHeader + "\n\n" + s.WorkingMemory,
+		},
+	}
+	return append(messages, s.Messages[s.CompactedMessages:]...)
+}
+
+// CompactSession folds the session's older turns into its working memory once its context passes
+// budget.CompactAt of the window. The newest turns are kept as they are and the full history stays
+// on disk. It reports whether the session was compacted.
+func (sm *SessionManager) CompactSession(ctx context.Context, sessionID string, budget ContextBudget) (bool, error) {
+	if budget.Compaction == nil || budget.CompactAt <= 0 {
+		return false, nil
+	}
+
+	sm.sessionMutex.Lock()
+	session := sm.loadSession(sessionID)
+	if session == nil {
+		sm.sessionMutex.Unlock()
+		return false, fmt.Errorf("session %s not found", sessionID)
+	}
+	messages := append([]openai.ChatCompletionMessage(nil), session.Messages...)
+	memory := session.WorkingMemory
+	start := session.CompactedMessages
+	view := session.contextMessages()
+	sm.sessionMutex.Unlock()
+
+	tk := tokenizer.ForModel(budget.Model)
+	threshold := int(float64(budget.limit()) * budget.CompactAt)
+	if tokenizer.CountMessages(tk, view) <= threshold {
+		return false, nil
+	}
+
+	// The system prompt is never folded
+	if start < 1 {
+		start = 1
+	}
+	cut := compactionCut(messages, start, tk, threshold/2)
+	if cut <= start {
+		// Only the newest turns are left; the heuristic filter has to make them fit
+		return false, nil
+	}
+
+	// stdout carries the MCP protocol when the server runs over stdio
+	fmt.Fprintf(os.Stderr, "DEBUG: Compacting messages %d-%d of session %s\n", start, cut-1, sessionID)
+	updated, err := budget.Compaction.Compact(ctx, memory, messages[start:cut])
+	if err != nil {
+		return false, fmt.Errorf("failed to compact session %s: %w", sessionID, err)
+	}
+
+	sm.sessionMutex.Lock()
+	session.WorkingMemory = updated
+	session.CompactedMessages = cut
+	session.UpdatedAt = time.Now()
+	stored := session.snapshot()
+	sm.sessionMutex.Unlock()
+
+	if err := sm.store.Update(stored); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// compactionCut returns the index of the first message to keep: the newest messages fitting in
+// keepTokens, moved back so tool results are never separated from the call that produced them
+func compactionCut(messages []openai.ChatCompletionMessage, start int, tk tokenizer.Tokenizer, keepTokens int) int {
+	// Always keep the newest message so the model knows what it is answering
+	cut := len(messages) - 1
+	used := tokenizer.CountMessage(tk, messages[cut])
+	for i := cut - 1; i > start; i-- {
+		used += tokenizer.CountMessage(tk, messages[i])
+		if used > keepTokens {
+			break
+		}
+		cut = i
+	}
+
+	for cut > start && messages[cut].Role == openai.ChatMessageRoleTool {
+		cut--
+	}
+	return cut
+}
@@ -0,0 +1,126 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorka/internal/models"
+	"gorka/internal/tokenizer"
+	"gorka/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// addReads appends n read_file calls, each answered with a file of about 100 tokens
+func addReads(sm *SessionManager, sessionID string, from, n int) {
+	for i := from; i < from+n; i++ {
+		id := fmt.Sprintf("call_%d", i)
+		sm.AddMessage(sessionID, openai.ChatCompletionMessage{
+			Role:      openai.ChatMessageRoleAssistant,
+			ToolCalls: []openai.ToolCall{{ID: id, Function: openai.FunctionCall{Name: "read_file", Arguments: fmt.Sprintf(`{"path":"file%d.go"}`, i)}}},
+		})
+		sm.AddMessage(sessionID, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			ToolCallID: id,
+			Content:    fmt.Sprintf("package main // file%d\n", i) + strings.Repeat("func helper() int { return 42 }\n", 10),
+		})
+	}
+}
+
+func TestCompactSessionFoldsOlderTurns(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	session, err := sm.CreateSession("compact_agent", &types.BehavioralMatrix{AgentID: "compact_agent"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "review the helpers"})
+
+	var calls [][]openai.ChatCompletionMessage
+	var memories []string
+	budget := ContextBudget{
+		Model: "vendor/compact-test-model",
+		Compaction: CompactionFunc(func(ctx context.Context, memory string, turns []openai.ChatCompletionMessage) (string, error) {
+			calls = append(calls, turns)
+			memories = append(memories, memory)
+			return fmt.Sprintf("memory %d", len(calls)), nil
+		}),
+		CompactAt: 0.5,
+	}
+	models.Register(models.Capabilities{ID: "vendor/compact-test-model", ContextLength: 4000, SupportsTools: true})
+
+	// Under the threshold nothing is folded
+	addReads(sm, session.ID, 0, 2)
+	if compacted, err := sm.CompactSession(context.Background(), session.ID, budget); compacted || err != nil {
+		t.Fatalf("Expected no compaction under the threshold, got %v, %v", compacted, err)
+	}
+
+	addReads(sm, session.ID, 2, 20)
+	compacted, err := sm.CompactSession(context.Background(), session.ID, budget)
+	if !compacted || err != nil {
+		t.Fatalf("Expected the session to be compacted, got %v, %v", compacted, err)
+	}
+	if len(calls) != 1 || calls[0][0].Content != "review the helpers" || memories[0] != "" {
+		t.Fatalf("Expected the turns after the system prompt to be folded into empty memory, got %d calls", len(calls))
+	}
+
+	messages, err := sm.GetFilteredSessionMessages(session.ID, budget)
+	if err != nil {
+		t.Fatalf("Failed to get filtered messages: %v", err)
+	}
+	if messages[1].Role != openai.ChatMessageRoleSystem || messages[1].Content != WorkingMemoryHeader+"\n\nmemory 1" {
+		t.Errorf("Expected the working memory after the system prompt, got %+v", messages[1])
+	}
+	if messages[2].Role == openai.ChatMessageRoleTool {
+		t.Error("Expected no tool result without its call after the working memory")
+	}
+	if last := messages[len(messages)-1]; !strings.Contains(last.Content, "func helper() int { return 42 }") {
+		t.Errorf("Expected recent tool results to be sent in full, got %q", last.Content)
+	}
+
+	// The working memory survives a restart, and the next compaction builds on it
+	restarted := NewSessionManagerWithDir(storageDir)
+	reloaded, _ := restarted.GetSession(session.ID)
+	if reloaded.WorkingMemory != "memory 1" || reloaded.CompactedMessages == 0 || len(reloaded.Messages) != len(session.Messages) {
+		t.Fatalf("Expected the working memory and full history on disk, got %q after %d messages", reloaded.WorkingMemory, reloaded.CompactedMessages)
+	}
+	addReads(restarted, session.ID, 22, 20)
+	if compacted, err := restarted.CompactSession(context.Background(), session.ID, budget); !compacted || err != nil {
+		t.Fatalf("Expected a second compaction, got %v, %v", compacted, err)
+	}
+	if memories[1] != "memory 1" || calls[1][0].Role == openai.ChatMessageRoleSystem {
+		t.Errorf("Expected the second compaction to fold only newer turns into the first memory, got %q", memories[1])
+	}
+}
+
+func TestCompactSessionFailureKeepsHistory(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+	session, _ := sm.CreateSession("compact_agent", &types.BehavioralMatrix{AgentID: "compact_agent"}, "")
+	addReads(sm, session.ID, 0, 20)
+
+	budget := ContextBudget{
+		Model: "vendor/compact-failure-model",
+		Compaction: CompactionFunc(func(ctx context.Context, memory string, turns []openai.ChatCompletionMessage) (string, error) {
+			return "", errors.New("model unavailable")
+		}),
+		CompactAt: 0.5,
+	}
+	models.Register(models.Capabilities{ID: "vendor/compact-failure-model", ContextLength: 2000, SupportsTools: true})
+
+	if _, err := sm.CompactSession(context.Background(), session.ID, budget); err == nil {
+		t.Fatal("Expected the compaction error to be returned")
+	}
+	if session.CompactedMessages != 0 || session.WorkingMemory != "" {
+		t.Errorf("Expected no working memory after a failed compaction, got %q", session.WorkingMemory)
+	}
+
+	// Without compaction the heuristic filter summarizes file reads to fit the window
+	budget.Compaction = nil
+	messages, _ := sm.GetFilteredSessionMessages(session.ID, budget)
+	if used := tokenizer.CountMessages(tokenizer.ForModel(budget.Model), messages); used > 2000 {
+		t.Errorf("Expected the heuristic filter to fit the window, got %d tokens", used)
+	}
+}
@@ -0,0 +1,274 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Transcript formats accepted by Export
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSONL    = "jsonl"
+)
+
+// transcriptEntry is one message of a session as it is exported
+type transcriptEntry struct {
+	SessionID  string           `json:"session_id"`
+	AgentID    string           `json:"agent_id"`
+	Index      int              `json:"index"`
+	Role       string           `json:"role"`
+	Content    string           `json:"content,omitempty"`
+	Reasoning  string           `json:"reasoning,omitempty"`
+	ToolCalls  []transcriptCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+	// ToolName is the tool that produced a tool result
+	ToolName string   `json:"tool_name,omitempty"`
+	Images   []string `json:"images,omitempty"`
+}
+
+// transcriptCall is a tool call with its arguments indented for reading
+type transcriptCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Export writes the transcript of a session to w as Markdown, HTML or JSON Lines
+func Export(w io.Writer, s *AgentSession, format string) error {
+	entries := transcript(s)
+	switch format {
+	case FormatMarkdown:
+		return exportMarkdown(w, s, entries)
+	case FormatHTML:
+		return exportHTML(w, s, entries)
+	case FormatJSONL:
+		return exportJSONL(w, entries)
+	default:
+		return fmt.Errorf("unknown transcript format %q (must be markdown, html or jsonl)", format)
+	}
+}
+
+// transcript converts the messages of a session, naming the tool behind each tool result
+func transcript(s *AgentSession) []transcriptEntry {
+	toolNames := make(map[string]string)
+	entries := make([]transcriptEntry, 0, len(s.Messages))
+	for i, msg := range s.Messages {
+		entry := transcriptEntry{
+			SessionID:  s.ID,
+			AgentID:    s.AgentID,
+			Index:      i,
+			Role:       msg.Role,
+			Content:    msg.Content,
+			Reasoning:  msg.ReasoningContent,
+			ToolCallID: msg.ToolCallID,
+			ToolName:   toolNames[msg.ToolCallID],
+		}
+		for _, part := range msg.MultiContent {
+			switch {
+			case part.Type == openai.ChatMessagePartTypeText:
+				entry.Content += part.Text
+			case part.ImageURL != nil:
+				entry.Images = append(entry.Images, part.ImageURL.URL)
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			toolNames[call.ID] = call.Function.Name
+			entry.ToolCalls = append(entry.ToolCalls, transcriptCall{
+				ID:        call.ID,
+				Name:      call.Function.Name,
+				Arguments: indentJSON(call.Function.Arguments),
+			})
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// indentJSON pretty-prints JSON arguments, leaving anything else as it is
+func indentJSON(arguments string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(arguments), "", "  "); err != nil {
+		return arguments
+	}
+	return out.String()
+}
+
+// title names a transcript entry, e.g. "Tool result: read_file"
+func (e transcriptEntry) title() string {
+	switch e.Role {
+	case openai.ChatMessageRoleTool:
+		if e.ToolName != "" {
+			return "Tool result: " + e.ToolName
+		}
+		return "Tool result"
+	case "":
+		return "Message"
+	default:
+		return strings.ToUpper(e.Role[:1]) + e.Role[1:]
+	}
+}
+
+// header lists the facts shown at the top of every transcript
+func header(s *AgentSession) [][2]string {
+	status := s.Status()
+	switch {
+	case s.Interruption != nil:
+		status += " (" + s.Interruption.Reason + ")"
+	case s.StopReason != "":
+		status += " (" + s.StopReason + ")"
+	}
+	fields := [][2]string{
+		{"Agent", s.AgentID},
+		{"Status", status},
+		{"Created", s.CreatedAt.Format(time.RFC3339)},
+		{"Updated", s.UpdatedAt.Format(time.RFC3339)},
+	}
+	if s.Interruption != nil {
+		fields = append(fields, [2]string{"Last activity", s.Interruption.LastActivity.Format(time.RFC3339)})
+	}
+	return append(fields, [2]string{"Messages", fmt.Sprint(len(s.Messages))})
+}
+
+// fence returns a code fence longer than any run of backticks in content
+func fence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// codeBlock renders content as a fenced Markdown code block
+func codeBlock(language, content string) string {
+	f := fence(content)
+	return f + language + "\n" + strings.TrimRight(content, "\n") + "\n" + f + "\n"
+}
+
+func exportMarkdown(w io.Writer, s *AgentSession, entries []transcriptEntry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", s.ID)
+	for _, field := range header(s) {
+		fmt.Fprintf(&b, "- **%s:** %s\n", field[0], field[1])
+	}
+
+	if s.WorkingMemory != "" {
+		fmt.Fprintf(&b, "\n## Working memory\n\n*Summarizes messages 1-%d.*\n\n%s\n", s.CompactedMessages, s.WorkingMemory)
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", e.Index+1, e.title())
+		if e.Reasoning != "" {
+			b.WriteString("> **Reasoning**\n>\n")
+			for _, line := range strings.Split(strings.TrimRight(e.Reasoning, "\n"), "\n") {
+				b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+			}
+			b.WriteString("\n")
+		}
+
+		if e.Role == openai.ChatMessageRoleTool {
+			if e.ToolCallID != "" {
+				fmt.Fprintf(&b, "*Call `%s`*\n\n", e.ToolCallID)
+			}
+			b.WriteString(codeBlock("", e.Content))
+		} else if e.Content != "" {
+			b.WriteString(strings.TrimRight(e.Content, "\n") + "\n")
+		}
+		for range e.Images {
+			b.WriteString("\n*[image]*\n")
+		}
+
+		for _, call := range e.ToolCalls {
+			fmt.Fprintf(&b, "\n**Tool call** `%s` *(%s)*\n\n", call.Name, call.ID)
+			b.WriteString(codeBlock("json", call.Arguments))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// htmlStyle keeps exported pages readable without any external assets
+const htmlStyle = `body{font-family:system-ui,sans-serif;max-width:960px;margin:2em auto;padding:0 1em;color:#222}
+section{border-left:4px solid #ccc;margin:1.5em 0;padding:0 1em}
+section.user{border-color:#2b6cb0}section.assistant{border-color:#2f855a}section.tool{border-color:#b7791f}
+pre{background:#f6f6f6;padding:.75em;overflow-x:auto;white-space:pre-wrap}
+.content{white-space:pre-wrap}summary{cursor:pointer;color:#555}img{max-width:100%}`
+
+func exportHTML(w io.Writer, s *AgentSession, entries []transcriptEntry) error {
+	var b strings.Builder
+	esc := html.EscapeString
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Session %s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n", esc(s.ID), htmlStyle)
+	fmt.Fprintf(&b, "<h1>Session %s</h1>\n<ul>\n", esc(s.ID))
+	for _, field := range header(s) {
+		fmt.Fprintf(&b, "<li><strong>%s:</strong> %s</li>\n", field[0], esc(field[1]))
+	}
+	b.WriteString("</ul>\n")
+
+	if s.WorkingMemory != "" {
+		fmt.Fprintf(&b, "<h2>Working memory</h2>\n<p><em>Summarizes messages 1-%d.</em></p>\n<div class=\"content\">%s</div>\n", s.CompactedMessages, esc(s.WorkingMemory))
+	}
+
+	for _, e := range entries {
+		fmt.Fprintf(&b, "<section class=\"%s\">\n<h2>%d. %s</h2>\n", esc(e.Role), e.Index+1, esc(e.title()))
+		if e.Reasoning != "" {
+			fmt.Fprintf(&b, "<details><summary>Reasoning</summary><div class=\"content\">%s</div></details>\n", esc(e.Reasoning))
+		}
+
+		switch {
+		case e.Role == openai.ChatMessageRoleTool:
+			if e.ToolCallID != "" {
+				fmt.Fprintf(&b, "<p><em>Call %s</em></p>\n", esc(e.ToolCallID))
+			}
+			fmt.Fprintf(&b, "<pre>%s</pre>\n", esc(e.Content))
+		case e.Role == openai.ChatMessageRoleSystem:
+			// System prompts are long and rarely what a reviewer is looking for
+			fmt.Fprintf(&b, "<details><summary>System prompt</summary><div class=\"content\">%s</div></details>\n", esc(e.Content))
+		case e.Content != "":
+			fmt.Fprintf(&b, "<div class=\"content\">%s</div>\n", esc(e.Content))
+		}
+		for _, image := range e.Images {
+			if strings.HasPrefix(image, "data:image/") {
+				fmt.Fprintf(&b, "<img src=\"%s\" alt=\"image\">\n", esc(image))
+			} else {
+				b.WriteString("<p><em>[image]</em></p>\n")
+			}
+		}
+
+		for _, call := range e.ToolCalls {
+			fmt.Fprintf(&b, "<p><strong>Tool call</strong> <code>%s</code> <em>(%s)</em></p>\n<pre>%s</pre>\n", esc(call.Name), esc(call.ID), esc(call.Arguments))
+		}
+		b.WriteString("</section>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func exportJSONL(w io.Writer, entries []transcriptEntry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
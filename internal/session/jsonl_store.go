@@ -0,0 +1,700 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// JSONLStore keeps each session as an append-only log of JSON records, one per line, in
+// active/<id>.jsonl, completed/<id>.jsonl or interrupted/<id>.jsonl. Adding a message appends one line, so writes do
+// not get slower as a session grows. Archived logs are gzipped into archive/<id>.jsonl.gz.
+type JSONLStore struct {
+	dir      string
+	policy   SyncPolicy
+	readOnly bool
+
+	// mu guards the maps below. Each log has its own lock, so writes and fsyncs of one session
+	// never hold up the others.
+	mu       sync.Mutex
+	locks    map[string]*sync.Mutex
+	lastSync map[string]time.Time
+	// dirty holds the logs written since their last fsync, which the flusher syncs later
+	dirty map[string]bool
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// errReadOnly is returned by every write to a store opened with NewReadOnlyJSONLStore
+var errReadOnly = errors.New("session store is read-only")
+
+// sessionStatuses are the directories a session log can be in
+var sessionStatuses = []string{StatusActive, StatusCompleted, StatusInterrupted}
+
+// Record types of a session log
+const (
+	recordMeta     = "meta"
+	recordMessage  = "message"
+	recordTruncate = "truncate"
+)
+
+// sessionRecord is one line of a session log. Each meta record replaces the metadata before it,
+// message records are added to the conversation in order, and truncate records drop the messages
+// after the first Keep.
+type sessionRecord struct {
+	Type    string                        `json:"type"`
+	Time    time.Time                     `json:"time"`
+	Meta    *sessionMeta                  `json:"meta,omitempty"`
+	Message *openai.ChatCompletionMessage `json:"message,omitempty"`
+	Keep    int                           `json:"keep,omitempty"`
+}
+
+// legacySession is the whole-file JSON format sessions were saved in before session logs
+type legacySession struct {
+	sessionMeta
+	Messages []openai.ChatCompletionMessage `json:"messages"`
+}
+
+// NewJSONLStore opens the session logs under dir, importing sessions saved as whole JSON files
+func NewJSONLStore(dir string, policy SyncPolicy) *JSONLStore {
+	st := &JSONLStore{
+		dir:      dir,
+		policy:   policy,
+		locks:    make(map[string]*sync.Mutex),
+		lastSync: make(map[string]time.Time),
+		dirty:    make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+	for _, status := range sessionStatuses {
+		os.MkdirAll(filepath.Join(dir, status), 0755)
+	}
+	st.importLegacy()
+	if policy == SyncInterval {
+		go st.flushLoop()
+	}
+	return st
+}
+
+// NewReadOnlyJSONLStore opens the session logs under dir for browsing. It creates no directories,
+// reads sessions still saved as whole JSON files where they are, and refuses every write.
+func NewReadOnlyJSONLStore(dir string) *JSONLStore {
+	return &JSONLStore{
+		dir:      dir,
+		policy:   SyncNever,
+		readOnly: true,
+		locks:    make(map[string]*sync.Mutex),
+		lastSync: make(map[string]time.Time),
+		dirty:    make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Create writes the log of a new session
+func (st *JSONLStore) Create(session *AgentSession) error {
+	if st.readOnly {
+		return errReadOnly
+	}
+	if !validSessionID(session.ID) {
+		return fmt.Errorf("invalid session ID %q", session.ID)
+	}
+
+	unlock := st.lock(session.ID)
+	defer unlock()
+	return st.writeLog(st.path(session.Status(), session.ID), metaOf(session), session.Messages)
+}
+
+// Append adds one record per message to the session log
+func (st *JSONLStore) Append(session *AgentSession, messages ...openai.ChatCompletionMessage) error {
+	if st.readOnly {
+		return errReadOnly
+	}
+	unlock := st.lock(session.ID)
+	defer unlock()
+
+	path, _, err := st.locate(session.ID)
+	if err != nil {
+		return err
+	}
+	records := make([]sessionRecord, len(messages))
+	for i := range messages {
+		records[i] = sessionRecord{Type: recordMessage, Time: session.UpdatedAt, Message: &messages[i]}
+	}
+	return st.appendRecords(session.ID, path, records, false)
+}
+
+// Update appends the session's metadata and moves its log when the status changed
+func (st *JSONLStore) Update(session *AgentSession) error {
+	if st.readOnly {
+		return errReadOnly
+	}
+	unlock := st.lock(session.ID)
+	defer unlock()
+
+	path, status, err := st.locate(session.ID)
+	if err != nil {
+		return err
+	}
+	meta := metaOf(session)
+	if err := st.appendRecords(session.ID, path, []sessionRecord{{Type: recordMeta, Time: meta.UpdatedAt, Meta: &meta}}, true); err != nil {
+		return err
+	}
+
+	// The directory is what decides the status, so the log moves last
+	if status != session.Status() {
+		if err := os.Rename(path, st.path(session.Status(), session.ID)); err != nil {
+			return fmt.Errorf("failed to move session log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Load replays a session log
+func (st *JSONLStore) Load(sessionID string) (*AgentSession, error) {
+	unlock := st.lock(sessionID)
+	defer unlock()
+
+	path, status, err := st.locate(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	readSession := readLog
+	if strings.HasSuffix(path, ".json") {
+		readSession = readLegacy
+	}
+	session, err := readSession(path)
+	if err != nil {
+		return nil, err
+	}
+	applyStatus(session, status)
+	return session, nil
+}
+
+// List returns the IDs of the session logs with a status, or of all logs when status is empty
+func (st *JSONLStore) List(status string) ([]string, error) {
+	statuses := sessionStatuses
+	if status != "" {
+		statuses = []string{status}
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, subDir := range statuses {
+		entries, err := os.ReadDir(filepath.Join(st.dir, subDir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		for _, entry := range entries {
+			id, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+			if !ok && st.readOnly {
+				id, ok = strings.CutSuffix(entry.Name(), ".json")
+			}
+			if ok && !entry.IsDir() && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Remove deletes a session log
+func (st *JSONLStore) Remove(sessionID string) error {
+	if st.readOnly {
+		return errReadOnly
+	}
+	unlock := st.lock(sessionID)
+	defer unlock()
+
+	path, _, err := st.locate(sessionID)
+	if err != nil {
+		return err
+	}
+	st.forget(sessionID)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove session log: %w", err)
+	}
+	return nil
+}
+
+// Compact rewrites a session log as a single meta record followed by the messages, dropping the
+// metadata earlier updates left behind
+func (st *JSONLStore) Compact(sessionID string) error {
+	if st.readOnly {
+		return errReadOnly
+	}
+	unlock := st.lock(sessionID)
+	defer unlock()
+
+	path, status, err := st.locate(sessionID)
+	if err != nil {
+		return err
+	}
+	session, err := readLog(path)
+	if err != nil {
+		return err
+	}
+	applyStatus(session, status)
+	return st.writeLog(path, metaOf(session), session.Messages)
+}
+
+// Truncate appends a record dropping the messages after the first keep
+func (st *JSONLStore) Truncate(session *AgentSession, keep int) error {
+	if st.readOnly {
+		return errReadOnly
+	}
+	unlock := st.lock(session.ID)
+	defer unlock()
+
+	path, _, err := st.locate(session.ID)
+	if err != nil {
+		return err
+	}
+	return st.appendRecords(session.ID, path, []sessionRecord{{Type: recordTruncate, Time: session.UpdatedAt, Keep: keep}}, true)
+}
+
+// applyStatus sets the status of a loaded session from the directory its log is in, which wins
+// over metadata written just before a crash interrupted a move
+func applyStatus(session *AgentSession, status string) {
+	session.Completed = status == StatusCompleted
+	if status != StatusInterrupted {
+		session.Interruption = nil
+	} else if session.Interruption == nil {
+		session.Interruption = &Interruption{Reason: "unknown", LastActivity: session.UpdatedAt}
+	}
+}
+
+// Stat returns the size of a session log and its modification time, which is the session's last update
+func (st *JSONLStore) Stat(sessionID string) (SessionInfo, error) {
+	unlock := st.lock(sessionID)
+	defer unlock()
+
+	path, _, err := st.locate(sessionID)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return SessionInfo{}, fmt.Errorf("failed to stat session log: %w", err)
+	}
+	return SessionInfo{ID: sessionID, UpdatedAt: info.ModTime(), Size: info.Size()}, nil
+}
+
+// Archive gzips a session log into the archive directory and removes the log
+func (st *JSONLStore) Archive(sessionID string) error {
+	if st.readOnly {
+		return errReadOnly
+	}
+	unlock := st.lock(sessionID)
+	defer unlock()
+
+	path, _, err := st.locate(sessionID)
+	if err != nil {
+		return err
+	}
+	archiveDir := filepath.Join(st.dir, "archive")
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create session archive: %w", err)
+	}
+
+	archivePath := filepath.Join(archiveDir, sessionID+".jsonl.gz")
+	if err := st.compress(path, archivePath+".tmp"); err != nil {
+		os.Remove(archivePath + ".tmp")
+		return err
+	}
+	if err := os.Rename(archivePath+".tmp", archivePath); err != nil {
+		os.Remove(archivePath + ".tmp")
+		return fmt.Errorf("failed to move session archive: %w", err)
+	}
+
+	st.forget(sessionID)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove archived session log: %w", err)
+	}
+	return nil
+}
+
+// compress writes a gzipped copy of a session log; the copy is flushed before the log is removed
+func (st *JSONLStore) compress(path, archivePath string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open session log: %w", err)
+	}
+	defer source.Close()
+
+	file, err := os.OpenFile(archivePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write session archive: %w", err)
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	writer.Name = filepath.Base(path)
+	if _, err := io.Copy(writer, source); err != nil {
+		return fmt.Errorf("failed to compress session log: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to compress session log: %w", err)
+	}
+	if st.policy != SyncNever {
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync session archive: %w", err)
+		}
+	}
+	return file.Close()
+}
+
+// validSessionID rejects IDs that would point outside the session directories
+func validSessionID(sessionID string) bool {
+	return sessionID != "" && sessionID != "." && sessionID != ".." && !strings.ContainsAny(sessionID, `/\`)
+}
+
+// path returns where the log of a session with a status is kept
+func (st *JSONLStore) path(status, sessionID string) string {
+	return filepath.Join(st.dir, status, sessionID+".jsonl")
+}
+
+// locate finds the log of a session and returns it with the session's status
+func (st *JSONLStore) locate(sessionID string) (string, string, error) {
+	if validSessionID(sessionID) {
+		for _, status := range sessionStatuses {
+			path := st.path(status, sessionID)
+			if _, err := os.Stat(path); err == nil {
+				return path, status, nil
+			}
+		}
+		// A read-only store cannot import legacy files, so it reads them in place
+		if st.readOnly {
+			for _, status := range []string{StatusCompleted, StatusActive} {
+				path := filepath.Join(st.dir, status, sessionID+".json")
+				if _, err := os.Stat(path); err == nil {
+					return path, status, nil
+				}
+			}
+		}
+	}
+	return "", "", fmt.Errorf("session %s: %w", sessionID, os.ErrNotExist)
+}
+
+// shouldSync applies the sync policy to a write; status changes are always flushed unless the policy
+// is SyncNever. A write left unsynced under SyncInterval is flushed by the flusher or on Close.
+func (st *JSONLStore) shouldSync(sessionID string, statusChange bool) bool {
+	switch st.policy {
+	case SyncAlways:
+		return true
+	case SyncNever:
+		return false
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !statusChange && time.Since(st.lastSync[sessionID]) < syncInterval {
+		st.dirty[sessionID] = true
+		return false
+	}
+	st.lastSync[sessionID] = time.Now()
+	delete(st.dirty, sessionID)
+	return true
+}
+
+// lock takes the lock of one session log and returns its unlock
+func (st *JSONLStore) lock(sessionID string) func() {
+	st.mu.Lock()
+	logLock, ok := st.locks[sessionID]
+	if !ok {
+		logLock = &sync.Mutex{}
+		st.locks[sessionID] = logLock
+	}
+	st.mu.Unlock()
+
+	logLock.Lock()
+	return logLock.Unlock
+}
+
+// forget drops the sync state of a log that no longer exists
+func (st *JSONLStore) forget(sessionID string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.lastSync, sessionID)
+	delete(st.dirty, sessionID)
+}
+
+// flushLoop syncs the dirty logs every syncInterval until the store is closed
+func (st *JSONLStore) flushLoop() {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-st.stop:
+			return
+		case <-ticker.C:
+			if err := st.Flush(); err != nil {
+				fmt.Printf("WARNING: Failed to flush session logs: %v\n", err)
+			}
+		}
+	}
+}
+
+// Flush syncs every log written since its last fsync
+func (st *JSONLStore) Flush() error {
+	st.mu.Lock()
+	var ids []string
+	for sessionID := range st.dirty {
+		ids = append(ids, sessionID)
+	}
+	st.mu.Unlock()
+
+	var firstErr error
+	for _, sessionID := range ids {
+		if err := st.flush(sessionID); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// flush syncs one dirty log
+func (st *JSONLStore) flush(sessionID string) error {
+	unlock := st.lock(sessionID)
+	defer unlock()
+
+	st.mu.Lock()
+	dirty := st.dirty[sessionID]
+	delete(st.dirty, sessionID)
+	st.lastSync[sessionID] = time.Now()
+	st.mu.Unlock()
+	if !dirty {
+		return nil
+	}
+
+	path, _, err := st.locate(sessionID)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open session log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync session log: %w", err)
+	}
+	return file.Close()
+}
+
+// Close stops the flusher and syncs the logs it had not flushed yet
+func (st *JSONLStore) Close() error {
+	st.closeOnce.Do(func() { close(st.stop) })
+	return st.Flush()
+}
+
+// encodeRecords renders records as JSON lines
+func encodeRecords(records []sessionRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal session record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// appendRecords adds records to the end of an existing log
+func (st *JSONLStore) appendRecords(sessionID, path string, records []sessionRecord, statusChange bool) error {
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open session log: %w", err)
+	}
+	// A crash can cut the last line short; the next record must not continue it
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to session log: %w", err)
+	}
+	if st.shouldSync(sessionID, statusChange) {
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to sync session log: %w", err)
+		}
+	}
+	return file.Close()
+}
+
+// writeLog replaces a log through a temporary file, so a crash never leaves half of it behind
+func (st *JSONLStore) writeLog(path string, meta sessionMeta, messages []openai.ChatCompletionMessage) error {
+	records := []sessionRecord{{Type: recordMeta, Time: meta.UpdatedAt, Meta: &meta}}
+	for i := range messages {
+		records = append(records, sessionRecord{Type: recordMessage, Time: meta.UpdatedAt, Message: &messages[i]})
+	}
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	tempPath := path + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write session log: %w", err)
+	}
+	_, err = file.Write(data)
+	if err == nil && st.shouldSync(meta.ID, true) {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write session log: %w", err)
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath) // Clean up temp file on error
+		return fmt.Errorf("failed to move session log: %w", err)
+	}
+
+	// Stat reads the last update from the modification time, which a rewrite must not reset
+	if !meta.UpdatedAt.IsZero() {
+		os.Chtimes(path, meta.UpdatedAt, meta.UpdatedAt)
+	}
+	return nil
+}
+
+// readLog replays a session log. Lines that cannot be parsed, such as one cut short by a crash,
+// are skipped.
+func readLog(path string) (*AgentSession, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session log: %w", err)
+	}
+	defer file.Close()
+
+	session := &AgentSession{}
+	hasMeta := false
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		// Lines holding images can be megabytes long, too long for a bufio.Scanner
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record sessionRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				fmt.Printf("WARNING: Skipping unreadable line %d of session log %s: %v\n", lineNumber, path, err)
+			} else {
+				switch {
+				case record.Type == recordMeta && record.Meta != nil:
+					record.Meta.apply(session)
+					hasMeta = true
+				case record.Type == recordMessage && record.Message != nil:
+					session.Messages = append(session.Messages, *record.Message)
+				case record.Type == recordTruncate && record.Keep < len(session.Messages):
+					session.Messages = session.Messages[:record.Keep]
+				}
+				if record.Time.After(session.UpdatedAt) {
+					session.UpdatedAt = record.Time
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read session log: %w", readErr)
+		}
+	}
+
+	if !hasMeta {
+		return nil, fmt.Errorf("session log %s has no metadata", path)
+	}
+	return session, nil
+}
+
+// readLegacy reads a session saved as a whole JSON file
+func readLegacy(path string) (*AgentSession, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session file: %w", err)
+	}
+	var legacy legacySession
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse session file %s: %w", path, err)
+	}
+	legacy.ID = strings.TrimSuffix(filepath.Base(path), ".json")
+
+	session := &AgentSession{Messages: legacy.Messages}
+	legacy.sessionMeta.apply(session)
+	return session, nil
+}
+
+// importLegacy converts sessions saved as whole JSON files into session logs
+func (st *JSONLStore) importLegacy() {
+	imported := 0
+	// A crash while completing a session could leave it in both directories; the completed copy wins
+	for _, status := range []string{StatusCompleted, StatusActive} {
+		paths, _ := filepath.Glob(filepath.Join(st.dir, status, "*.json"))
+		for _, legacyPath := range paths {
+			if err := st.importFile(legacyPath, status); err != nil {
+				fmt.Printf("WARNING: Failed to import session file %s: %v\n", legacyPath, err)
+				continue
+			}
+			imported++
+		}
+	}
+
+	if imported > 0 {
+		fmt.Printf("DEBUG: Imported %d session files into session logs\n", imported)
+	}
+}
+
+// importFile writes the log of one legacy session file and removes the file
+func (st *JSONLStore) importFile(legacyPath, status string) error {
+	sessionID := strings.TrimSuffix(filepath.Base(legacyPath), ".json")
+	if _, _, err := st.locate(sessionID); err == nil {
+		// The session was imported from its other directory already
+		return os.Remove(legacyPath)
+	}
+
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		return err
+	}
+	var legacy legacySession
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	legacy.ID = sessionID
+	legacy.Completed = status == StatusCompleted
+
+	if err := st.writeLog(st.path(status, sessionID), legacy.sessionMeta, legacy.Messages); err != nil {
+		return err
+	}
+	return os.Remove(legacyPath)
+}
@@ -0,0 +1,210 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorka/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// countLines returns the number of records in a session log
+func countLines(t *testing.T, path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read session log: %v", err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestSessionLogAppendsMessages(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithStore(NewJSONLStore(storageDir, SyncAlways))
+	session, _ := sm.CreateSession("log_agent", &types.BehavioralMatrix{AgentID: "log_agent"}, "")
+	logPath := filepath.Join(storageDir, StatusActive, session.ID+".jsonl")
+
+	// The meta record and the system prompt
+	if lines := countLines(t, logPath); lines != 2 {
+		t.Fatalf("Expected 2 records after creating a session, got %d", lines)
+	}
+	for i := 0; i < 3; i++ {
+		sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "next"})
+	}
+	if lines := countLines(t, logPath); lines != 5 {
+		t.Errorf("Expected one record per message, got %d records", lines)
+	}
+
+	// A crash in the middle of a write leaves a partial line behind
+	file, _ := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(`{"type":"message","message":{"role":"us`)
+	file.Close()
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "done"})
+
+	restarted := NewSessionManagerWithDir(storageDir)
+	if restarted.GetSessionCount() != 0 {
+		t.Error("Expected sessions to be loaded only when used")
+	}
+	loaded, exists := restarted.GetSession(session.ID)
+	if !exists || len(loaded.Messages) != 5 || loaded.Messages[4].Content != "done" {
+		t.Fatalf("Expected the partial line to be skipped and later messages kept, got %+v", loaded)
+	}
+	if !loaded.UpdatedAt.Equal(session.UpdatedAt) {
+		t.Errorf("Expected the time of the last message, got %v instead of %v", loaded.UpdatedAt, session.UpdatedAt)
+	}
+
+	if _, exists := restarted.GetSession("../" + StatusActive + "/" + session.ID); exists {
+		t.Error("Expected session IDs with path separators to be rejected")
+	}
+}
+
+func TestCompactStorageDropsSupersededMetadata(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	session := reviewSession(t, sm, "reviewer")
+	for _, reason := range []string{"max_iterations", "budget_exceeded", "completed"} {
+		sm.SetStopReason(session.ID, reason)
+	}
+	logPath := filepath.Join(storageDir, StatusCompleted, session.ID+".jsonl")
+	before := countLines(t, logPath)
+
+	if err := sm.CompactStorage(session.ID); err != nil {
+		t.Fatalf("Failed to compact session log: %v", err)
+	}
+	if lines := countLines(t, logPath); lines != 1+len(session.Messages) || lines >= before {
+		t.Errorf("Expected one meta record and the messages, got %d records (%d before)", lines, before)
+	}
+
+	loaded, _ := NewSessionManagerWithDir(storageDir).GetSession(session.ID)
+	if !loaded.Completed || loaded.StopReason != "completed" || len(loaded.Messages) != len(session.Messages) {
+		t.Errorf("Expected compaction to keep the session as it was, got %+v", loaded)
+	}
+	if err := sm.CompactStorage("missing"); err == nil {
+		t.Error("Expected compacting an unknown session to fail")
+	}
+}
+
+func TestLegacySessionFilesAreImported(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	updated := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	legacy := legacySession{
+		sessionMeta: sessionMeta{
+			ID:                "old_agent_1_1",
+			AgentID:           "old_agent",
+			CreatedAt:         updated.Add(-time.Hour),
+			UpdatedAt:         updated,
+			Completed:         true,
+			StopReason:        "completed",
+			WorkingMemory:     "read a.go",
+			CompactedMessages: 1,
+		},
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "prompt"},
+			{Role: openai.ChatMessageRoleUser, Content: "review a.go"},
+		},
+	}
+	data, _ := json.MarshalIndent(legacy, "", "  ")
+	// A crash while completing the session left a stale active copy too
+	for _, status := range []string{StatusActive, StatusCompleted} {
+		os.MkdirAll(filepath.Join(storageDir, status), 0755)
+		os.WriteFile(filepath.Join(storageDir, status, legacy.ID+".json"), data, 0644)
+	}
+
+	sm := NewSessionManagerWithDir(storageDir)
+	for _, status := range []string{StatusActive, StatusCompleted} {
+		if _, err := os.Stat(filepath.Join(storageDir, status, legacy.ID+".json")); !os.IsNotExist(err) {
+			t.Errorf("Expected the %s session file to be replaced, got %v", status, err)
+		}
+	}
+	if active := sm.GetActiveSessions(); len(active) != 0 {
+		t.Errorf("Expected the completed copy to win, got %d active sessions", len(active))
+	}
+
+	loaded, exists := sm.GetSession(legacy.ID)
+	if !exists {
+		t.Fatal("Expected the imported session to load")
+	}
+	if !loaded.Completed || loaded.AgentID != "old_agent" || loaded.WorkingMemory != "read a.go" || loaded.CompactedMessages != 1 ||
+		!loaded.UpdatedAt.Equal(updated) || len(loaded.Messages) != 2 || loaded.Messages[1].Content != "review a.go" {
+		t.Errorf("Expected the session as it was saved, got %+v", loaded)
+	}
+}
+
+func TestReadOnlyStoreLeavesSessionsUntouched(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	current, _ := sm.CreateSession("engineer", &types.BehavioralMatrix{AgentID: "engineer"}, "")
+
+	legacy := legacySession{
+		sessionMeta: sessionMeta{ID: "old_agent_1_1", AgentID: "old_agent", UpdatedAt: time.Now(), Completed: true},
+		Messages:    []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "review a.go"}},
+	}
+	data, _ := json.Marshal(legacy)
+	legacyPath := filepath.Join(storageDir, StatusCompleted, legacy.ID+".json")
+	os.WriteFile(legacyPath, data, 0644)
+	os.RemoveAll(filepath.Join(storageDir, StatusInterrupted))
+
+	browser := NewSessionManagerWithStore(NewReadOnlyJSONLStore(storageDir))
+	if sessions := browser.ListSessions(SessionFilter{}); len(sessions) != 2 {
+		t.Fatalf("Expected the session log and the legacy file, got %d sessions", len(sessions))
+	}
+	loaded, exists := browser.GetSession(legacy.ID)
+	if !exists || !loaded.Completed || len(loaded.Messages) != 1 {
+		t.Errorf("Expected the legacy session read in place, got %+v", loaded)
+	}
+	if err := browser.RemoveSession(current.ID); err == nil {
+		t.Error("Expected the read-only store to refuse a removal")
+	}
+
+	if _, err := os.Stat(legacyPath); err != nil {
+		t.Errorf("Expected the legacy file to stay: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storageDir, StatusInterrupted)); !os.IsNotExist(err) {
+		t.Errorf("Expected no directories to be created, got %v", err)
+	}
+	if _, exists := NewSessionManagerWithDir(storageDir).GetSession(current.ID); !exists {
+		t.Error("Expected the session to survive the refused removal")
+	}
+}
+
+// isDirty reports whether a session log has writes waiting for an fsync
+func isDirty(st *JSONLStore, sessionID string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.dirty[sessionID]
+}
+
+func TestIntervalSyncFlushesSkippedWrites(t *testing.T) {
+	st := NewJSONLStore(filepath.Join(t.TempDir(), "sessions"), SyncInterval)
+	sm := NewSessionManagerWithStore(st)
+	session, _ := sm.CreateSession("engineer", &types.BehavioralMatrix{AgentID: "engineer"}, "")
+
+	// Creating the session synced it, so a message right after is left for later
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "one"})
+	if !isDirty(st, session.ID) {
+		t.Fatal("Expected the write inside the interval to wait for a flush")
+	}
+	if err := sm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if isDirty(st, session.ID) {
+		t.Error("Expected Close to flush the pending write")
+	}
+
+	// The background flusher catches up without a Close
+	st = NewJSONLStore(filepath.Join(t.TempDir(), "sessions"), SyncInterval)
+	defer st.Close()
+	sm = NewSessionManagerWithStore(st)
+	session, _ = sm.CreateSession("engineer", &types.BehavioralMatrix{AgentID: "engineer"}, "")
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "two"})
+	deadline := time.Now().Add(3 * syncInterval)
+	for isDirty(st, session.ID) && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if isDirty(st, session.ID) {
+		t.Error("Expected the flusher to sync the pending write")
+	}
+}
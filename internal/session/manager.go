@@ -2,6 +2,7 @@ package session
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,7 +10,10 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
+	"gorka/internal/models"
+	"gorka/internal/tokenizer"
 	"gorka/internal/types"
 	"gorka/internal/utils"
 	"github.com/sashabaranov/go-openai"
@@ -24,14 +28,51 @@ type AgentSession struct {
 	UpdatedAt time.Time                       `json:"updated_at"`
 	Matrix    *types.BehavioralMatrix         `json:"-"` // Don't serialize the full matrix
 	Completed bool                            `json:"completed"` // Track if session is complete
-}
-
-// SessionManager handles agent session lifecycle and persistence
+	// StopReason records why the conversation ended, e.g. completed or max_iterations
+	StopReason string `json:"stop_reason,omitempty"`
+	// WorkingMemory summarizes the first CompactedMessages messages, which are no longer sent to the model
+	WorkingMemory     string `json:"working_memory,omitempty"`
+	CompactedMessages int    `json:"compacted_messages,omitempty"`
+	// OwnerHost and OwnerPID identify the process that last ran the session
+	OwnerHost string `json:"owner_host,omitempty"`
+	OwnerPID  int    `json:"owner_pid,omitempty"`
+	// Interruption is set when a run stopped without completing, until the session is resumed
+	Interruption *Interruption `json:"interruption,omitempty"`
+}
+
+// ContextBudget describes the context window session messages are fitted into
+type ContextBudget struct {
+	// Model selects the tokenizer and the context window
+	Model string
+	// Reserved holds back tokens for the tool schemas and the reply
+	Reserved int
+	// Compaction folds older turns into working memory; nil leaves only the heuristic filter
+	Compaction CompactionStrategy
+	// CompactAt is the share of the context window at which older turns are compacted
+	CompactAt float64
+}
+
+// limit returns the tokens left for messages
+func (b ContextBudget) limit() int {
+	if limit := models.ContextWindow(b.Model) - b.Reserved; limit > 0 {
+		return limit
+	}
+	return 0
+}
+
+// SessionManager handles agent session lifecycle and persistence. Sessions are loaded from the
+// store when first used and stay in memory after that.
 type SessionManager struct {
 	sessions       map[string]*AgentSession
 	sessionMutex   sync.RWMutex
 	sessionCounter int64
-	storageDir     string
+	store          SessionStore
+	// collecting holds the sessions being archived or removed, which cannot be loaded meanwhile
+	collecting map[string]bool
+
+	// ownerHost and ownerPID are recorded on the sessions this process runs
+	ownerHost string
+	ownerPID  int
 }
 
 // NewSessionManager creates a new session manager with .gorka storage
@@ -49,8 +90,12 @@ func NewSessionManager() *SessionManager {
 
 // NewSessionManagerWithConfig creates a new session manager using provided config
 func NewSessionManagerWithConfig(config *utils.Config) *SessionManager {
+	if err := tokenizer.LoadDir(config.TokenizerDir); err != nil {
+		fmt.Printf("WARNING: Using the built-in token encodings: %v\n", err)
+	}
+	
 	sessionsDir := filepath.Join(config.Workspace, ".gorka", "sessions")
-	return NewSessionManagerWithDir(sessionsDir)
+	return NewSessionManagerWithStore(NewJSONLStore(sessionsDir, SyncPolicy(config.SessionSync)))
 }
 
 // NewSessionManagerWithWorkspace creates a new session manager with specified workspace
@@ -61,20 +106,21 @@ func NewSessionManagerWithWorkspace(workspacePath string) *SessionManager {
 
 // NewSessionManagerWithDir creates a new session manager with custom storage directory
 func NewSessionManagerWithDir(storageDir string) *SessionManager {
-	sm := &SessionManager{
+	return NewSessionManagerWithStore(NewJSONLStore(storageDir, SyncInterval))
+}
+
+// NewSessionManagerWithStore creates a new session manager keeping sessions in store
+func NewSessionManagerWithStore(store SessionStore) *SessionManager {
+	host, _ := os.Hostname()
+	return &SessionManager{
 		sessions:       make(map[string]*AgentSession),
 		sessionMutex:   sync.RWMutex{},
 		sessionCounter: 0,
-		storageDir:     storageDir,
+		store:          store,
+		collecting:     make(map[string]bool),
+		ownerHost:      host,
+		ownerPID:       os.Getpid(),
 	}
-	
-	// Create storage directories
-	sm.ensureStorageDirectories()
-	
-	// Load existing sessions from disk
-	sm.loadSessionsFromDisk()
-	
-	return sm
 }
 
 // CreateSession creates a new session with the given parameters
@@ -85,59 +131,156 @@ func (sm *SessionManager) CreateSession(agentID string, matrix *types.Behavioral
 
 // GetSession retrieves a session by ID
 func (sm *SessionManager) GetSession(sessionID string) (*AgentSession, bool) {
-	sm.sessionMutex.RLock()
-	defer sm.sessionMutex.RUnlock()
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
 	
-	session, exists := sm.sessions[sessionID]
-	return session, exists
+	session := sm.loadSession(sessionID)
+	return session, session != nil
+}
+
+// loadSession returns a session from memory, loading it from the store on first use. The caller
+// must hold sessionMutex for writing.
+func (sm *SessionManager) loadSession(sessionID string) *AgentSession {
+	if session, exists := sm.sessions[sessionID]; exists {
+		return session
+	}
+	if sm.collecting[sessionID] {
+		return nil
+	}
+	
+	session, err := sm.store.Load(sessionID)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("WARNING: Failed to load session %s: %v\n", sessionID, err)
+		}
+		return nil
+	}
+	sm.sessions[sessionID] = session
+	return session
+}
+
+// snapshot copies the metadata of a session for the store to write once sessionMutex is released;
+// the messages are left out, since later changes may reuse their backing array
+func (s *AgentSession) snapshot() *AgentSession {
+	stored := *s
+	stored.Messages = nil
+	return &stored
 }
 
 // AddMessage adds a message to an existing session
 func (sm *SessionManager) AddMessage(sessionID string, message openai.ChatCompletionMessage) error {
 	sm.sessionMutex.Lock()
-	defer sm.sessionMutex.Unlock()
-	
-	session, exists := sm.sessions[sessionID]
-	if !exists {
+	session := sm.loadSession(sessionID)
+	if session == nil {
+		sm.sessionMutex.Unlock()
 		return fmt.Errorf("session %s not found", sessionID)
 	}
 	
 	session.Messages = append(session.Messages, message)
 	session.UpdatedAt = time.Now()
+	stored := session.snapshot()
+	sm.sessionMutex.Unlock()
 	
-	// Save updated session to disk
-	sm.saveSessionToDisk(session, session.Completed)
+	// Append only the new message to the session's storage; the store orders writes to each log
+	if err := sm.store.Append(stored, message); err != nil {
+		fmt.Printf("WARNING: Failed to save message of session %s: %v\n", sessionID, err)
+	}
 	
 	return nil
 }
 
+// SetStopReason records why a session's conversation ended
+func (sm *SessionManager) SetStopReason(sessionID, reason string) error {
+	sm.sessionMutex.Lock()
+	session := sm.loadSession(sessionID)
+	if session == nil {
+		sm.sessionMutex.Unlock()
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+
+	session.StopReason = reason
+	session.UpdatedAt = time.Now()
+	stored := session.snapshot()
+	sm.sessionMutex.Unlock()
+
+	if err := sm.store.Update(stored); err != nil {
+		fmt.Printf("WARNING: Failed to save stop reason of session %s: %v\n", sessionID, err)
+	}
+
+	return nil
+}
+
 // CompleteSession marks a session as completed and moves it to completed storage
 func (sm *SessionManager) CompleteSession(sessionID string) {
 	sm.sessionMutex.Lock()
-	defer sm.sessionMutex.Unlock()
+	session := sm.loadSession(sessionID)
+	if session == nil {
+		sm.sessionMutex.Unlock()
+		return
+	}
+	session.Completed = true
+	session.UpdatedAt = time.Now()
+	stored := session.snapshot()
+	sm.sessionMutex.Unlock()
 	
-	if session, exists := sm.sessions[sessionID]; exists {
-		session.Completed = true
-		session.UpdatedAt = time.Now()
-		
-		// Save completed session to disk immediately
-		sm.saveSessionToDisk(session, true)
-		
-		// Remove from active sessions directory
-		activeFilePath := filepath.Join(sm.storageDir, "active", sessionID+".json")
-		os.Remove(activeFilePath)
+	// Move the session to completed storage immediately
+	if err := sm.store.Update(stored); err != nil {
+		fmt.Printf("WARNING: Failed to complete session %s: %v\n", sessionID, err)
 	}
 }
 
+// ReopenSession prepares an existing session for another run. It restores the matrix, which is
+// not persisted, and moves a completed or interrupted session back to active storage. The session
+// is first cut back to its last consistent point.
+func (sm *SessionManager) ReopenSession(sessionID string, matrix *types.BehavioralMatrix) (*AgentSession, error) {
+	sm.sessionMutex.Lock()
+	session := sm.loadSession(sessionID)
+	if session == nil {
+		sm.sessionMutex.Unlock()
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if matrix.AgentID != session.AgentID {
+		sm.sessionMutex.Unlock()
+		return nil, fmt.Errorf("session %s belongs to agent %s, not %s", sessionID, session.AgentID, matrix.AgentID)
+	}
+
+	// Tool calls left without results, by a crash or a run stopped after its wrap-up turn, would
+	// have the next message rejected
+	keep, cut := sm.truncateToConsistent(session)
+	session.Interruption = nil
+
+	session.Matrix = matrix
+	session.Completed = false
+	session.StopReason = ""
+	session.OwnerHost = sm.ownerHost
+	session.OwnerPID = sm.ownerPID
+	session.UpdatedAt = time.Now()
+	stored := session.snapshot()
+	sm.sessionMutex.Unlock()
+
+	if cut {
+		if err := sm.store.Truncate(stored, keep); err != nil {
+			return nil, err
+		}
+	}
+	if err := sm.store.Update(stored); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
 // GetContextStats provides context budget monitoring and compression analytics
-func (sm *SessionManager) GetContextStats(sessionID string) (map[string]interface{}, error) {
+func (sm *SessionManager) GetContextStats(sessionID string, budget ContextBudget) (map[string]interface{}, error) {
 	session, exists := sm.GetSession(sessionID)
 	if !exists {
 		return nil, fmt.Errorf("session not found")
 	}
 	
-	unfiltered := sm.estimateTokens(session.Messages)
-	filtered := sm.estimateTokens(sm.filterMessagesForAPI(session.Messages))
+	tk := tokenizer.ForModel(budget.Model)
+	filteredMessages := sm.filterMessagesForAPI(session.contextMessages(), budget)
+	unfiltered := tokenizer.CountMessages(tk, session.Messages)
+	filtered := tokenizer.CountMessages(tk, filteredMessages)
 	
 	compressionRatio := float64(1)
 	if filtered > 0 {
@@ -146,108 +289,122 @@ func (sm *SessionManager) GetContextStats(sessionID string) (map[string]interfac
 	
 	return map[string]interface{}{
 		"session_id":         sessionID,
+		"model":              budget.Model,
+		"tokenizer":          tk.Name(),
+		"context_window":     models.ContextWindow(budget.Model),
+		"reserved_tokens":    budget.Reserved,
 		"total_messages":     len(session.Messages),
-		"filtered_messages":  len(sm.filterMessagesForAPI(session.Messages)),
+		"filtered_messages":  len(filteredMessages),
 		"unfiltered_tokens":  unfiltered,
 		"filtered_tokens":    filtered,
 		"compression_ratio":  compressionRatio,
-		"will_fit":          filtered < 100000,
+		"will_fit":          filtered <= budget.limit(),
 		"tokens_saved":      unfiltered - filtered,
 		"reduction_percent": float64(unfiltered-filtered) / float64(unfiltered) * 100,
+
+		// Messages folded into working memory and no longer sent to the model
+		"compacted_messages": session.CompactedMessages,
 	}, nil
 }
 
 // GetSessionMessages returns the messages for a session
 func (sm *SessionManager) GetSessionMessages(sessionID string) ([]openai.ChatCompletionMessage, error) {
-	sm.sessionMutex.RLock()
-	defer sm.sessionMutex.RUnlock()
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
 	
-	session, exists := sm.sessions[sessionID]
-	if !exists {
+	session := sm.loadSession(sessionID)
+	if session == nil {
 		return nil, fmt.Errorf("session %s not found", sessionID)
 	}
 	
 	return session.Messages, nil
 }
 
-// GetFilteredSessionMessages returns context-filtered messages for API calls to prevent token limits
-func (sm *SessionManager) GetFilteredSessionMessages(sessionID string) ([]openai.ChatCompletionMessage, error) {
-	sm.sessionMutex.RLock()
-	defer sm.sessionMutex.RUnlock()
+// GetFilteredSessionMessages returns the session messages filtered to fit the budget's context window
+func (sm *SessionManager) GetFilteredSessionMessages(sessionID string, budget ContextBudget) ([]openai.ChatCompletionMessage, error) {
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
 	
-	session, exists := sm.sessions[sessionID]
-	if !exists {
+	session := sm.loadSession(sessionID)
+	if session == nil {
 		return nil, fmt.Errorf("session %s not found", sessionID)
 	}
 	
-	return sm.filterMessagesForAPI(session.Messages), nil
+	return sm.filterMessagesForAPI(session.contextMessages(), budget), nil
 }
 
 // filterMessagesForAPI reduces context size while preserving conversation flow
-func (sm *SessionManager) filterMessagesForAPI(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
-	const maxTokens = 100000 // Leave room for response (well under 128K limit)
-	const maxMessages = 20   // Hard limit on message count
-	
+func (sm *SessionManager) filterMessagesForAPI(messages []openai.ChatCompletionMessage, budget ContextBudget) []openai.ChatCompletionMessage {
 	if len(messages) == 0 {
 		return messages
 	}
 	
-	// Step 1: Prune old messages first (keep system + recent exchanges)
-	pruned := sm.pruneOldMessages(messages, maxMessages)
-	
-	// Step 2: Smart filtering based on message content
+	// Step 1: Smart filtering based on message content. With working memory keeping the context
+	// small, turns are only summarized this way when they do not fit as they are.
+	tk := tokenizer.ForModel(budget.Model)
+	maxTokens := budget.limit()
+	heuristic := budget.Compaction == nil || tokenizer.CountMessages(tk, messages) > maxTokens
+	// The newest turn is what the model is acting on, so its tool results are never summarized
+	current := latestTurn(messages)
 	var filtered []openai.ChatCompletionMessage
-	for _, msg := range pruned {
-		filteredMsg := sm.smartFilterMessage(msg)
+	for i, msg := range messages {
+		filteredMsg := msg
+		if heuristic && i < current {
+			filteredMsg = sm.smartFilterMessage(msg)
+		} else {
+			// The reasoning trace stays in the session for auditing but is never replayed
+			filteredMsg.ReasoningContent = ""
+		}
 		filtered = append(filtered, filteredMsg)
 	}
 	
-	// Step 3: Final token check and emergency pruning
-	if sm.estimateTokens(filtered) > maxTokens {
-		filtered = sm.emergencyPrune(filtered, maxTokens)
-	}
+	// Step 2: Only the most recent images stay in context; older ones cost tokens on every call
+	filtered = sm.dropOldImages(filtered, maxContextImages)
+	
+	// Step 3: Drop the oldest exchanges until the conversation fits the model's context window,
+	// along with any tool results that lost their call
+	filtered = sm.emergencyPrune(filtered, tk, maxTokens)
 	
 	return filtered
 }
 
-// pruneOldMessages keeps system message + recent N exchanges
-func (sm *SessionManager) pruneOldMessages(messages []openai.ChatCompletionMessage, maxMessages int) []openai.ChatCompletionMessage {
-	if len(messages) <= maxMessages {
-		return messages
-	}
-	
-	// Always keep the system message (assumed to be first)
-	var systemMsg *openai.ChatCompletionMessage
-	var nonSystemMessages []openai.ChatCompletionMessage
-	
-	for i, msg := range messages {
-		if i == 0 && msg.Role == openai.ChatMessageRoleSystem {
-			systemMsg = &msg
-		} else {
-			nonSystemMessages = append(nonSystemMessages, msg)
+// latestTurn returns the index of the last assistant message, where the newest turn starts
+func latestTurn(messages []openai.ChatCompletionMessage) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == openai.ChatMessageRoleAssistant {
+			return i
 		}
 	}
-	
-	// Keep most recent messages within limit
-	keepCount := maxMessages - 1 // Reserve space for system message
-	if keepCount <= 0 {
-		keepCount = maxMessages
-	}
-	
-	var result []openai.ChatCompletionMessage
-	if systemMsg != nil {
-		result = append(result, *systemMsg)
-	}
-	
-	if len(nonSystemMessages) > keepCount {
-		// Keep the most recent messages
-		start := len(nonSystemMessages) - keepCount
-		result = append(result, nonSystemMessages[start:]...)
-	} else {
-		result = append(result, nonSystemMessages...)
+	return len(messages)
+}
+
+// maxContextImages is how many of the newest images are kept when filtering messages for the API
+const maxContextImages = 4
+
+// dropOldImages replaces all but the newest maxImages image parts with a short placeholder
+func (sm *SessionManager) dropOldImages(messages []openai.ChatCompletionMessage, maxImages int) []openai.ChatCompletionMessage {
+	// Copy the messages and parts so the stored session keeps its images
+	messages = append([]openai.ChatCompletionMessage(nil), messages...)
+	kept := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if len(messages[i].MultiContent) == 0 {
+			continue
+		}
+		
+		parts := make([]openai.ChatMessagePart, len(messages[i].MultiContent))
+		for j, part := range messages[i].MultiContent {
+			if part.ImageURL != nil {
+				if kept >= maxImages {
+					part = openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: "[image omitted from earlier in the conversation]"}
+				} else {
+					kept++
+				}
+			}
+			parts[j] = part
+		}
+		messages[i].MultiContent = parts
 	}
-	
-	return result
+	return messages
 }
 
 // smartFilterMessage applies intelligent filtering based on message type and content
@@ -262,10 +419,19 @@ func (sm *SessionManager) smartFilterMessage(msg openai.ChatCompletionMessage) o
 		} else {
 			filteredMsg.Content = sm.summarizeToolResponse(msg.Content)
 		}
+		// Plain-text results have no fields to summarize, so they are only truncated
+		if filteredMsg.Content == "" {
+			filteredMsg.Content = msg.Content
+			if len(msg.Content) > 3000 {
+				filteredMsg.Content = msg.Content[:3000] + "...[tool output truncated]"
+			}
+		}
 		
 	case openai.ChatMessageRoleAssistant:
 		// Keep assistant reasoning but trim code blocks
 		filteredMsg.Content = sm.trimCodeBlocks(msg.Content, 1000)
+		// The reasoning trace stays in the session for auditing but is never replayed
+		filteredMsg.ReasoningContent = ""
 		
 	case openai.ChatMessageRoleUser:
 		// User messages usually short, but check for large code pastes
@@ -281,74 +447,214 @@ func (sm *SessionManager) smartFilterMessage(msg openai.ChatCompletionMessage) o
 	return filteredMsg
 }
 
-// estimateTokens provides more accurate token count estimation with code awareness
-func (sm *SessionManager) estimateTokens(messages []openai.ChatCompletionMessage) int {
-	total := 0
-	for _, msg := range messages {
-		content := msg.Content
-		
-		// Code content has different token density
-		if sm.containsCode(content) {
-			total += len(content) / 3 // Code is denser
-		} else {
-			total += len(content) / 4 // Regular text
+// emergencyPrune keeps the system messages and as many of the newest messages as fit in maxTokens.
+// An assistant message with tool calls is kept or dropped together with its results. Tool results
+// whose call is not in the history, and tool calls missing results, are always dropped, since
+// providers reject them. The
+// newest unit and the latest user message are always kept, so the model has a turn to answer; when
+// they do not fit, the newest unit's tool results are cut short.
+func (sm *SessionManager) emergencyPrune(messages []openai.ChatCompletionMessage, tk tokenizer.Tokenizer, maxTokens int) []openai.ChatCompletionMessage {
+	// The leading system messages, the prompt and any working memory, are always kept
+	leading := 0
+	for leading < len(messages) && messages[leading].Role == openai.ChatMessageRoleSystem {
+		leading++
+	}
+	result := append([]openai.ChatCompletionMessage(nil), messages[:leading]...)
+	units := messageUnits(messages[leading:])
+	tokensUsed := tokenizer.CountMessages(tk, result)
+	
+	keep := make([]bool, len(units))
+	pinned := make([]bool, len(units))
+	last := len(units) - 1
+	for last >= 0 && units[last].orphaned {
+		last--
+	}
+	if last >= 0 {
+		pinned[last] = true
+		for i := last; i >= 0; i-- {
+			if !units[i].orphaned && units[i].messages[0].Role == openai.ChatMessageRoleUser {
+				pinned[i] = true
+				break
+			}
 		}
-		
-		// Tool calls are expensive
-		if len(msg.ToolCalls) > 0 {
-			total += len(msg.ToolCalls) * 50 // More realistic overhead
+		for i := range units {
+			if pinned[i] {
+				keep[i] = true
+				tokensUsed += units[i].tokens(tk)
+			}
+		}
+		if tokensUsed > maxTokens {
+			lastTokens := units[last].tokens(tk)
+			units[last] = units[last].truncated(tk, maxTokens-(tokensUsed-lastTokens))
+			tokensUsed += units[last].tokens(tk) - lastTokens
 		}
 	}
+	
+	// Add units from newest backwards until we hit limit
+	for i := last - 1; i >= 0; i-- {
+		if units[i].orphaned || pinned[i] {
+			continue
+		}
+		unitTokens := units[i].tokens(tk)
+		if tokensUsed+unitTokens > maxTokens {
+			break
+		}
+		keep[i] = true
+		tokensUsed += unitTokens
+	}
+	
+	// Dropped tool exchanges leave a note in their place, making room for it if needed
+	elided := 0
+	for i, unit := range units {
+		if unit.exchange && !unit.orphaned && !keep[i] {
+			elided++
+		}
+	}
+	for next := 0; elided > 0; {
+		note := elisionNote(elided)
+		if tokensUsed+tokenizer.CountMessage(tk, note) <= maxTokens {
+			result = append(result, note)
+			break
+		}
+		for next < len(units) && (!keep[next] || pinned[next]) {
+			next++
+		}
+		if next == len(units) {
+			break
+		}
+		keep[next] = false
+		tokensUsed -= units[next].tokens(tk)
+		if units[next].exchange {
+			elided++
+		}
+	}
+	
+	for i, unit := range units {
+		if keep[i] {
+			result = append(result, unit.messages...)
+		}
+	}
+	return result
+}
+
+// messageUnit is a run of messages pruned as a whole
+type messageUnit struct {
+	messages []openai.ChatCompletionMessage
+	// exchange marks an assistant message with tool calls, followed by its results
+	exchange bool
+	// orphaned marks tool results whose call is not right before them, and tool calls missing
+	// some of their results; providers reject both
+	orphaned bool
+}
+
+// tokens returns what the unit's messages cost
+func (u messageUnit) tokens(tk tokenizer.Tokenizer) int {
+	total := 0
+	for _, msg := range u.messages {
+		total += tokenizer.CountMessage(tk, msg)
+	}
 	return total
 }
 
-// containsCode detects if content contains code patterns
-func (sm *SessionManager) containsCode(content string) bool {
-	codeIndicators := []string{"```", "func ", "class ", "def ", "import ", "{", "}"}
-	for _, indicator := range codeIndicators {
-		if strings.Contains(content, indicator) {
-			return true
+// truncationNote ends a tool result cut short to fit the context window
+const truncationNote = "\n...[truncated to fit the context window]"
+
+// truncated returns the unit with its tool results cut short so that, where possible, it fits in
+// maxTokens. The room left by the other messages is shared evenly among the results.
+func (u messageUnit) truncated(tk tokenizer.Tokenizer, maxTokens int) messageUnit {
+	results, overhead := 0, 0
+	for _, msg := range u.messages {
+		if msg.Role == openai.ChatMessageRoleTool {
+			results++
+			msg.Content = ""
 		}
+		overhead += tokenizer.CountMessage(tk, msg)
 	}
-	return false
+	if results == 0 {
+		return u
+	}
+	share := (maxTokens - overhead) / results
+	
+	fitted := messageUnit{exchange: u.exchange, orphaned: u.orphaned}
+	for _, msg := range u.messages {
+		if msg.Role == openai.ChatMessageRoleTool {
+			msg.Content = truncateToTokens(tk, msg.Content, share)
+		}
+		fitted.messages = append(fitted.messages, msg)
+	}
+	return fitted
 }
 
-// emergencyPrune aggressively reduces context when approaching token limits
-func (sm *SessionManager) emergencyPrune(messages []openai.ChatCompletionMessage, maxTokens int) []openai.ChatCompletionMessage {
-	var systemMsg *openai.ChatCompletionMessage
-	var otherMessages []openai.ChatCompletionMessage
-	
-	for i, msg := range messages {
-		if i == 0 && msg.Role == openai.ChatMessageRoleSystem {
-			systemMsg = &msg
+// truncateToTokens cuts content short, ending it with truncationNote, so that it takes at most limit tokens
+func truncateToTokens(tk tokenizer.Tokenizer, content string, limit int) string {
+	if tk.Count(content) <= limit {
+		return content
+	}
+	// Find the longest prefix that fits together with the note
+	low, high := 0, len(content)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if tk.Count(content[:mid]+truncationNote) <= limit {
+			low = mid
 		} else {
-			otherMessages = append(otherMessages, msg)
+			high = mid - 1
 		}
 	}
-	
-	var result []openai.ChatCompletionMessage
-	if systemMsg != nil {
-		result = append(result, *systemMsg)
+	for low > 0 && low < len(content) && !utf8.RuneStart(content[low]) {
+		low--
 	}
-	
-	// Add messages from newest backwards until we hit limit
-	tokensUsed := 0
-	if systemMsg != nil {
-		tokensUsed = len(systemMsg.Content) / 4
-	}
-	
-	var selectedMessages []openai.ChatCompletionMessage
-	for i := len(otherMessages) - 1; i >= 0; i-- {
-		msgTokens := len(otherMessages[i].Content) / 4
-		if tokensUsed+msgTokens > maxTokens {
-			break
+	return content[:low] + truncationNote
+}
+
+// messageUnits groups each assistant message with tool calls together with the tool results that
+// follow it, marking the group orphaned unless every call has its result; every other message is
+// a unit of its own
+func messageUnits(messages []openai.ChatCompletionMessage) []messageUnit {
+	var units []messageUnit
+	open := -1
+	pending := make(map[string]bool)
+	for _, msg := range messages {
+		if msg.Role == openai.ChatMessageRoleTool {
+			if open >= 0 && pending[msg.ToolCallID] {
+				delete(pending, msg.ToolCallID)
+				units[open].messages = append(units[open].messages, msg)
+			} else {
+				units = append(units, messageUnit{messages: []openai.ChatCompletionMessage{msg}, orphaned: true})
+			}
+			continue
+		}
+		
+		if open >= 0 && len(pending) > 0 {
+			units[open].orphaned = true
 		}
-		selectedMessages = append([]openai.ChatCompletionMessage{otherMessages[i]}, selectedMessages...)
-		tokensUsed += msgTokens
+		open = -1
+		pending = make(map[string]bool)
+		unit := messageUnit{messages: []openai.ChatCompletionMessage{msg}}
+		if msg.Role == openai.ChatMessageRoleAssistant && len(msg.ToolCalls) > 0 {
+			unit.exchange = true
+			open = len(units)
+			for _, call := range msg.ToolCalls {
+				pending[call.ID] = true
+			}
+		}
+		units = append(units, unit)
+	}
+	if open >= 0 && len(pending) > 0 {
+		units[open].orphaned = true
+	}
+	return units
+}
+
+// elisionNote stands in for the tool exchanges pruned from the start of the history
+func elisionNote(count int) openai.ChatCompletionMessage {
+	exchanges := "exchanges"
+	if count == 1 {
+		exchanges = "exchange"
+	}
+	return openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: fmt.Sprintf("[%d earlier tool %s elided to fit the context window]", count, exchanges),
 	}
-	
-	result = append(result, selectedMessages...)
-	return result
 }
 
 // isFileContent detects if content appears to be file content
@@ -583,7 +889,8 @@ func (sm *SessionManager) summarizeToolResponse(content string) string {
 	return string(summaryBytes)
 }
 
-// CleanupOldSessions removes sessions older than the specified duration
+// CleanupOldSessions unloads sessions older than the specified duration from memory; they stay
+// in the store and are loaded again when used
 func (sm *SessionManager) CleanupOldSessions(maxAge time.Duration) {
 	sm.sessionMutex.Lock()
 	defer sm.sessionMutex.Unlock()
@@ -596,7 +903,7 @@ func (sm *SessionManager) CleanupOldSessions(maxAge time.Duration) {
 	}
 }
 
-// GetSessionCount returns the number of active sessions
+// GetSessionCount returns the number of sessions loaded in memory
 func (sm *SessionManager) GetSessionCount() int {
 	sm.sessionMutex.RLock()
 	defer sm.sessionMutex.RUnlock()
@@ -606,8 +913,17 @@ func (sm *SessionManager) GetSessionCount() int {
 
 // GetActiveSessions returns a map of all active (non-completed) sessions
 func (sm *SessionManager) GetActiveSessions() map[string]*AgentSession {
-	sm.sessionMutex.RLock()
-	defer sm.sessionMutex.RUnlock()
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
+	
+	// Load the active sessions that have not been used since the start
+	ids, err := sm.store.List(StatusActive)
+	if err != nil {
+		fmt.Printf("WARNING: Failed to list active sessions: %v\n", err)
+	}
+	for _, sessionID := range ids {
+		sm.loadSession(sessionID)
+	}
 	
 	activeSessions := make(map[string]*AgentSession)
 	for sessionID, session := range sm.sessions {
@@ -636,7 +952,7 @@ func (sm *SessionManager) getOrCreateSession(sessionID, agentID string, matrix *
 	defer sm.sessionMutex.Unlock()
 	
 	// Check if session exists
-	if session, exists := sm.sessions[sessionID]; exists {
+	if session := sm.loadSession(sessionID); session != nil {
 		session.UpdatedAt = time.Now()
 		return session
 	}
@@ -666,12 +982,17 @@ func (sm *SessionManager) getOrCreateSession(sessionID, agentID string, matrix *
 		UpdatedAt: time.Now(),
 		Matrix:    matrix,
 		Completed: false,
+
+		OwnerHost: sm.ownerHost,
+		OwnerPID:  sm.ownerPID,
 	}
 	
 	sm.sessions[sessionID] = session
 	
 	// Save new session to disk
-	sm.saveSessionToDisk(session, false)
+	if err := sm.store.Create(session); err != nil {
+		fmt.Printf("WARNING: Failed to save session %s: %v\n", sessionID, err)
+	}
 	
 	return session
 }
@@ -685,113 +1006,3 @@ func (sm *SessionManager) cleanupCompletedSession(sessionID string) {
 		delete(sm.sessions, sessionID)
 	}
 }
-
-// ensureStorageDirectories creates the .gorka session storage directories
-func (sm *SessionManager) ensureStorageDirectories() {
-	activeDir := filepath.Join(sm.storageDir, "active")
-	completedDir := filepath.Join(sm.storageDir, "completed")
-	
-	os.MkdirAll(activeDir, 0755)
-	os.MkdirAll(completedDir, 0755)
-}
-
-// saveSessionToDisk saves a session to a JSON file
-func (sm *SessionManager) saveSessionToDisk(session *AgentSession, completed bool) error {
-	subDir := "active"
-	if completed {
-		subDir = "completed"
-	}
-	
-	filePath := filepath.Join(sm.storageDir, subDir, session.ID+".json")
-	
-	// Create a copy of the session for serialization (excluding Matrix to avoid circular refs)
-	sessionData := struct {
-		ID        string                          `json:"id"`
-		AgentID   string                          `json:"agent_id"`
-		Messages  []openai.ChatCompletionMessage `json:"messages"`
-		CreatedAt time.Time                       `json:"created_at"`
-		UpdatedAt time.Time                       `json:"updated_at"`
-		Completed bool                            `json:"completed"`
-	}{
-		ID:        session.ID,
-		AgentID:   session.AgentID,
-		Messages:  session.Messages,
-		CreatedAt: session.CreatedAt,
-		UpdatedAt: session.UpdatedAt,
-		Completed: session.Completed,
-	}
-	
-	data, err := json.MarshalIndent(sessionData, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal session: %w", err)
-	}
-	
-	// Write atomically by writing to temp file then renaming
-	tempPath := filePath + ".tmp"
-	if err := os.WriteFile(tempPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write session file: %w", err)
-	}
-	
-	if err := os.Rename(tempPath, filePath); err != nil {
-		os.Remove(tempPath) // Clean up temp file on error
-		return fmt.Errorf("failed to move session file: %w", err)
-	}
-	
-	return nil
-}
-
-// loadSessionsFromDisk loads existing sessions from JSON files
-func (sm *SessionManager) loadSessionsFromDisk() {
-	sm.loadSessionsFromDirectory("active", false)
-	sm.loadSessionsFromDirectory("completed", true)
-}
-
-// loadSessionsFromDirectory loads sessions from a specific directory
-func (sm *SessionManager) loadSessionsFromDirectory(subDir string, completed bool) {
-	dirPath := filepath.Join(sm.storageDir, subDir)
-	
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		// Directory might not exist yet, that's OK
-		return
-	}
-	
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
-			filePath := filepath.Join(dirPath, entry.Name())
-			
-			data, err := os.ReadFile(filePath)
-			if err != nil {
-				fmt.Printf("Warning: failed to read session file %s: %v\n", filePath, err)
-				continue
-			}
-			
-			var sessionData struct {
-				ID        string                          `json:"id"`
-				AgentID   string                          `json:"agent_id"`
-				Messages  []openai.ChatCompletionMessage `json:"messages"`
-				CreatedAt time.Time                       `json:"created_at"`
-				UpdatedAt time.Time                       `json:"updated_at"`
-				Completed bool                            `json:"completed"`
-			}
-			
-			if err := json.Unmarshal(data, &sessionData); err != nil {
-				fmt.Printf("Warning: failed to unmarshal session file %s: %v\n", filePath, err)
-				continue
-			}
-			
-			// Create session object (without Matrix since it's not persisted)
-			session := &AgentSession{
-				ID:        sessionData.ID,
-				AgentID:   sessionData.AgentID,
-				Messages:  sessionData.Messages,
-				CreatedAt: sessionData.CreatedAt,
-				UpdatedAt: sessionData.UpdatedAt,
-				Completed: sessionData.Completed,
-				Matrix:    nil, // Will be set when session is actively used
-			}
-			
-			sm.sessions[session.ID] = session
-		}
-	}
-}
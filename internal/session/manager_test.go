@@ -1,10 +1,18 @@
 package session
 
 import (
+	"fmt"
+	"math/rand"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/quick"
 	"time"
 
+	"gorka/internal/models"
+	"gorka/internal/tokenizer"
 	"gorka/internal/types"
 	"github.com/sashabaranov/go-openai"
 )
@@ -155,6 +163,103 @@ func TestSessionManagerCompletion(t *testing.T) {
 	}
 }
 
+// slowAppendStore holds up appends to one session until released, as a slow disk sync would
+type slowAppendStore struct {
+	SessionStore
+	slowID    string
+	appending chan struct{}
+	release   chan struct{}
+}
+
+func (st *slowAppendStore) Append(session *AgentSession, messages ...openai.ChatCompletionMessage) error {
+	if session.ID == st.slowID {
+		st.appending <- struct{}{}
+		<-st.release
+	}
+	return st.SessionStore.Append(session, messages...)
+}
+
+func TestSlowWriteDoesNotBlockOtherSessions(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	store := &slowAppendStore{SessionStore: NewJSONLStore(storageDir, SyncAlways), appending: make(chan struct{}), release: make(chan struct{})}
+	sm := NewSessionManagerWithStore(store)
+	slow, _ := sm.CreateSession("engineer", &types.BehavioralMatrix{AgentID: "engineer"}, "")
+	other, _ := sm.CreateSession("reviewer", &types.BehavioralMatrix{AgentID: "reviewer"}, "")
+	store.slowID = slow.ID
+
+	done := make(chan error)
+	go func() {
+		done <- sm.AddMessage(slow.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "slow"})
+	}()
+	<-store.appending
+
+	// The other session is read and written while the slow write is still going
+	if err := sm.AddMessage(other.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "fast"}); err != nil {
+		t.Errorf("Failed to add message: %v", err)
+	}
+	sm.SetStopReason(other.ID, "completed")
+	sm.CompleteSession(other.ID)
+	if session, _ := sm.GetSession(slow.ID); len(session.Messages) != 2 {
+		t.Errorf("Expected the slow session's message in memory, got %d messages", len(session.Messages))
+	}
+
+	close(store.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Slow write failed: %v", err)
+	}
+	sm.Close()
+
+	sm = NewSessionManagerWithDir(storageDir)
+	for _, id := range []string{slow.ID, other.ID} {
+		if session, _ := sm.GetSession(id); len(session.Messages) != 2 {
+			t.Errorf("Expected session %s to be stored with 2 messages, got %d", id, len(session.Messages))
+		}
+	}
+	if session, _ := sm.GetSession(other.ID); session.Status() != StatusCompleted || session.StopReason != "completed" {
+		t.Errorf("Expected the other session to be stored as completed, got %s", session.Status())
+	}
+}
+
+func TestReopenSessionAfterRestart(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+
+	matrix := &types.BehavioralMatrix{AgentID: "reopen_agent"}
+	session, err := sm.CreateSession("reopen_agent", matrix, "")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "read a.go"})
+	sm.CompleteSession(session.ID)
+
+	// A new manager loads the session from disk without its matrix
+	restarted := NewSessionManagerWithDir(storageDir)
+	loaded, exists := restarted.GetSession(session.ID)
+	if !exists || loaded.Matrix != nil || !loaded.Completed {
+		t.Fatalf("Expected a completed session without a matrix, got %+v", loaded)
+	}
+
+	if _, err := restarted.ReopenSession(session.ID, &types.BehavioralMatrix{AgentID: "other_agent"}); err == nil {
+		t.Error("Expected another agent's matrix to be rejected")
+	}
+
+	reopened, err := restarted.ReopenSession(session.ID, matrix)
+	if err != nil {
+		t.Fatalf("Failed to reopen session: %v", err)
+	}
+	if reopened.Completed || reopened.Matrix != matrix || len(reopened.Messages) != 2 {
+		t.Errorf("Expected an active session with its history and matrix, got %+v", reopened)
+	}
+
+	// The session is active again on disk, so another restart sees it as active
+	if _, err := os.Stat(filepath.Join(storageDir, "completed", session.ID+".jsonl")); !os.IsNotExist(err) {
+		t.Errorf("Expected the completed file to be removed, got %v", err)
+	}
+	if active := NewSessionManagerWithDir(storageDir).GetActiveSessions(); active[session.ID] == nil {
+		t.Error("Expected the reopened session to be stored as active")
+	}
+}
+
 func TestSessionManagerCleanup(t *testing.T) {
 	// Create temporary directory for testing
 	tempDir := t.TempDir()
@@ -185,12 +290,12 @@ func TestSessionManagerCleanup(t *testing.T) {
 		t.Errorf("Expected 0 sessions after aggressive cleanup, got %d", sm.GetSessionCount())
 	}
 
-	// Verify sessions no longer exist
+	// Cleanup only unloads sessions; they are read from the store again when used
 	_, exists1 := sm.GetSession(session1.ID)
 	_, exists2 := sm.GetSession(session2.ID)
 
-	if exists1 || exists2 {
-		t.Error("Sessions should not exist after cleanup")
+	if !exists1 || !exists2 || sm.GetSessionCount() != 2 {
+		t.Error("Sessions should be loaded from the store after cleanup")
 	}
 }
 
@@ -259,3 +364,363 @@ func TestSessionManagerUniqueIDs(t *testing.T) {
 		}
 	}
 }
+
+func TestFilteredMessagesFitContextWindow(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+
+	session, err := sm.CreateSession("pruned_agent", &types.BehavioralMatrix{AgentID: "pruned_agent"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// Leave room for the system prompt and about 300 tokens of conversation
+	budget := ContextBudget{Model: "vendor/tiny-test-model", Reserved: 100}
+	tk := tokenizer.ForModel(budget.Model)
+	limit := tokenizer.CountMessages(tk, session.Messages) + 300
+	models.Register(models.Capabilities{ID: "vendor/tiny-test-model", ContextLength: limit+budget.Reserved, SupportsTools: true})
+	for i := 0; i < 30; i++ {
+		sm.AddMessage(session.ID, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: strings.Repeat("more words about the task ", 5),
+		})
+	}
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{
+		Role:      openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{ID: "call_1", Function: openai.FunctionCall{Name: "read_file", Arguments: `{"path":"main.go"}`}}},
+	})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: "package main"})
+
+	messages, err := sm.GetFilteredSessionMessages(session.ID, budget)
+	if err != nil {
+		t.Fatalf("Failed to get filtered messages: %v", err)
+	}
+
+	if used := tokenizer.CountMessages(tk, messages); used > limit {
+		t.Errorf("Filtered messages use %d tokens, over the %d left in the window", used, limit)
+	}
+	if len(messages) < 4 || len(messages) > 20 {
+		t.Fatalf("Expected the newest exchanges that fit, got %d messages", len(messages))
+	}
+	if messages[0].Role != openai.ChatMessageRoleSystem {
+		t.Errorf("Expected the system message to be kept, got %s", messages[0].Role)
+	}
+	if messages[1].Role == openai.ChatMessageRoleTool {
+		t.Error("Expected no tool result without its call at the start of the history")
+	}
+	if last := messages[len(messages)-1]; last.ToolCallID != "call_1" {
+		t.Errorf("Expected the newest message to be kept, got %+v", last)
+	}
+
+	stats, err := sm.GetContextStats(session.ID, budget)
+	if err != nil {
+		t.Fatalf("Failed to get context stats: %v", err)
+	}
+	if stats["context_window"] != limit+budget.Reserved || stats["will_fit"] != true {
+		t.Errorf("Unexpected context stats: %+v", stats)
+	}
+}
+
+// randomHistory is a conversation of user turns, replies and tool exchanges, with some tool
+// results whose call is missing and some calls missing a result, pruned to a random budget
+type randomHistory struct {
+	messages []openai.ChatCompletionMessage
+	// orphaned marks the tool results whose call is missing and the exchanges missing a result
+	orphaned  []bool
+	leading   int
+	maxTokens int
+}
+
+// Generate implements quick.Generator
+func (randomHistory) Generate(r *rand.Rand, size int) reflect.Value {
+	h := randomHistory{}
+	add := func(msg openai.ChatCompletionMessage, orphaned bool) {
+		msg.Content = fmt.Sprintf("message %d: %s", len(h.messages), strings.Repeat("words ", r.Intn(40)))
+		h.messages = append(h.messages, msg)
+		h.orphaned = append(h.orphaned, orphaned)
+	}
+
+	h.leading = 1 + r.Intn(2)
+	for i := 0; i < h.leading; i++ {
+		add(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem}, false)
+	}
+	for i := r.Intn(size + 1); i >= 0; i-- {
+		switch r.Intn(5) {
+		case 0:
+			add(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser}, false)
+		case 1:
+			add(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}, false)
+		case 2:
+			add(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: fmt.Sprintf("lost_%d", i)}, true)
+		default:
+			call := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+			for j := r.Intn(3); j >= 0; j-- {
+				call.ToolCalls = append(call.ToolCalls, openai.ToolCall{ID: fmt.Sprintf("call_%d_%d", i, j)})
+			}
+			// Results may come back in any order, and some exchanges miss one
+			results := r.Perm(len(call.ToolCalls))
+			incomplete := r.Intn(8) == 0
+			if incomplete {
+				results = results[1:]
+			}
+			add(call, incomplete)
+			for _, j := range results {
+				add(openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: call.ToolCalls[j].ID}, incomplete)
+			}
+		}
+	}
+
+	tk := tokenizer.ForModel("")
+	h.maxTokens = r.Intn(tokenizer.CountMessages(tk, h.messages) + 50)
+	return reflect.ValueOf(h)
+}
+
+func TestEmergencyPruneKeepsToolExchangesWhole(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+	tk := tokenizer.ForModel("")
+
+	property := func(h randomHistory) bool {
+		pruned := sm.emergencyPrune(h.messages, tk, h.maxTokens)
+		if !reflect.DeepEqual(pruned[:h.leading], h.messages[:h.leading]) {
+			t.Logf("Expected the leading system messages to be kept")
+			return false
+		}
+
+		// What is kept is the newest part of the history, less the orphaned results, and the latest
+		// user message
+		var usable []openai.ChatCompletionMessage
+		for i, msg := range h.messages[h.leading:] {
+			if !h.orphaned[h.leading+i] {
+				usable = append(usable, msg)
+			}
+		}
+		latestUser, newest := -1, len(usable)
+		for i, msg := range usable {
+			if msg.Role == openai.ChatMessageRoleUser {
+				latestUser = i
+			}
+			if msg.Role != openai.ChatMessageRoleTool {
+				newest = i
+			}
+		}
+		kept := pruned[h.leading:]
+		note := ""
+		if len(kept) > 0 && kept[0].Role == openai.ChatMessageRoleSystem {
+			note, kept = kept[0].Content, kept[1:]
+		}
+		from, ok := keptSuffix(kept, usable)
+		if !ok && len(kept) > 0 && latestUser >= 0 && reflect.DeepEqual(kept[0], usable[latestUser]) {
+			from, ok = keptSuffix(kept[1:], usable)
+			ok = ok && from > latestUser
+		}
+		if !ok {
+			t.Logf("Expected a suffix of the history, got %d messages", len(kept))
+			return false
+		}
+		if from > newest || (latestUser >= 0 && from > latestUser && !reflect.DeepEqual(kept[0], usable[latestUser])) {
+			t.Logf("Expected the newest unit and the latest user message to be kept, got %d messages", len(kept))
+			return false
+		}
+
+		// Only the newest unit and the latest user message may go over the budget
+		pinnedOnly := from >= newest || (from == latestUser && latestUser+1 == newest)
+		if tokenizer.CountMessages(tk, pruned) > h.maxTokens && (!pinnedOnly || note != "") {
+			t.Logf("Pruned messages use %d tokens, over %d", tokenizer.CountMessages(tk, pruned), h.maxTokens)
+			return false
+		}
+
+		// Every tool result follows its call, and every kept call keeps its results
+		pending := map[string]bool{}
+		for _, msg := range kept {
+			if msg.Role == openai.ChatMessageRoleTool {
+				if !pending[msg.ToolCallID] {
+					t.Logf("Tool result %s has no call before it", msg.ToolCallID)
+					return false
+				}
+				delete(pending, msg.ToolCallID)
+				continue
+			}
+			if len(pending) > 0 {
+				t.Logf("Tool calls %v lost their results", pending)
+				return false
+			}
+			for _, call := range msg.ToolCalls {
+				pending[call.ID] = true
+			}
+		}
+		if len(pending) > 0 {
+			t.Logf("Tool calls %v lost their results", pending)
+			return false
+		}
+
+		// A note counts the dropped exchanges, when it fits
+		elided := 0
+		for _, msg := range usable[:from] {
+			if len(msg.ToolCalls) > 0 {
+				elided++
+			}
+		}
+		if note != "" && note != elisionNote(elided).Content {
+			t.Logf("Expected a note for %d exchanges, got %q", elided, note)
+			return false
+		}
+		if note == "" && elided > 0 && !pinnedOnly {
+			t.Logf("Expected a note for %d dropped exchanges", elided)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// keptSuffix reports where in usable the kept messages start, if they are its suffix. Tool results
+// may have been cut short.
+func keptSuffix(kept, usable []openai.ChatCompletionMessage) (int, bool) {
+	from := len(usable) - len(kept)
+	if from < 0 {
+		return 0, false
+	}
+	for i, msg := range kept {
+		want := usable[from+i]
+		if msg.Role == openai.ChatMessageRoleTool && strings.HasSuffix(msg.Content, truncationNote) {
+			if !strings.HasPrefix(want.Content, strings.TrimSuffix(msg.Content, truncationNote)) {
+				return 0, false
+			}
+			msg.Content = want.Content
+		}
+		if !reflect.DeepEqual(msg, want) {
+			return 0, false
+		}
+	}
+	return from, true
+}
+
+func TestEmergencyPruneTruncatesNewestToolResults(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+	tk := tokenizer.ForModel("")
+	output := strings.Repeat("line of build output\n", 2000)
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You fix builds."},
+		{Role: openai.ChatMessageRoleUser, Content: "Why does the build fail?"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "Looking into it"},
+		{Role: openai.ChatMessageRoleUser, Content: "Run it and see"},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{ID: "call_1", Function: openai.FunctionCall{Name: "exec", Arguments: `{"command":"go build ./..."}`}}}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: output},
+	}
+
+	maxTokens := 300
+	pruned := sm.emergencyPrune(messages, tk, maxTokens)
+	if used := tokenizer.CountMessages(tk, pruned); used > maxTokens {
+		t.Errorf("Pruned messages use %d tokens, over %d", used, maxTokens)
+	}
+	if len(pruned) != 4 {
+		t.Fatalf("Expected the system prompt, the latest user message and the exchange, got %+v", pruned)
+	}
+	if pruned[1].Content != "Run it and see" || pruned[2].ToolCalls[0].ID != "call_1" {
+		t.Errorf("Expected the latest user message and the tool call, got %+v", pruned[1:3])
+	}
+	result := pruned[3].Content
+	if pruned[3].ToolCallID != "call_1" || !strings.HasSuffix(result, truncationNote) || !strings.HasPrefix(output, strings.TrimSuffix(result, truncationNote)) {
+		t.Errorf("Expected the tool result to be cut short, got %q", result)
+	}
+
+	// A user message too large to fit is still sent whole
+	question := strings.Repeat("why ", 1000)
+	pruned = sm.emergencyPrune([]openai.ChatCompletionMessage{messages[0], {Role: openai.ChatMessageRoleUser, Content: question}}, tk, 50)
+	if len(pruned) != 2 || pruned[1].Content != question {
+		t.Errorf("Expected the user message to be kept, got %d messages", len(pruned))
+	}
+}
+
+func TestEmergencyPruneDropsHalfAnsweredExchange(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You fix builds."},
+		{Role: openai.ChatMessageRoleUser, Content: "Fix the build"},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{ID: "call_1", Function: openai.FunctionCall{Name: "read_file"}}}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: "package main"},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{
+			{ID: "call_2", Function: openai.FunctionCall{Name: "edit_file"}},
+			{ID: "call_3", Function: openai.FunctionCall{Name: "run_tests"}},
+		}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_2", Content: "edited"},
+	}
+
+	pruned := sm.emergencyPrune(messages, tokenizer.ForModel(""), 100000)
+	if !reflect.DeepEqual(pruned, messages[:4]) {
+		t.Errorf("Expected the half-answered exchange to be dropped, got %+v", pruned)
+	}
+}
+
+func TestFilteredMessagesDropOrphanedToolResults(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You review code."},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_0", Content: "result of a compacted call"},
+		{Role: openai.ChatMessageRoleUser, Content: "Review main.go"},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{{ID: "call_1", Function: openai.FunctionCall{Name: "read_file"}}}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: "package main"},
+	}
+
+	filtered := sm.filterMessagesForAPI(messages, ContextBudget{Model: "openai/gpt-4o"})
+	if len(filtered) != 4 || filtered[1].Role != openai.ChatMessageRoleUser || filtered[3].ToolCallID != "call_1" {
+		t.Errorf("Expected only the orphaned tool result to be dropped, got %+v", filtered)
+	}
+}
+
+func TestDropOldImagesKeepsNewest(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+
+	var messages []openai.ChatCompletionMessage
+	for i := 0; i < 3; i++ {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role: openai.ChatMessageRoleUser,
+			MultiContent: []openai.ChatMessagePart{
+				{Type: openai.ChatMessagePartTypeText, Text: "Image from view_image"},
+				{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: "data:image/png;base64,AA=="}},
+			},
+		})
+	}
+
+	filtered := sm.dropOldImages(messages, 2)
+	if filtered[0].MultiContent[1].ImageURL != nil {
+		t.Error("Expected the oldest image to be dropped")
+	}
+	for _, message := range filtered[1:] {
+		if message.MultiContent[1].ImageURL == nil {
+			t.Error("Expected the newest images to be kept")
+		}
+	}
+	if messages[0].MultiContent[1].ImageURL == nil {
+		t.Error("Expected the stored messages to be left unchanged")
+	}
+}
+
+func TestReasoningIsStoredButNotReplayed(t *testing.T) {
+	sm := NewSessionManagerWithDir(filepath.Join(t.TempDir(), "sessions"))
+
+	session, err := sm.CreateSession("thinking_agent", &types.BehavioralMatrix{AgentID: "thinking_agent"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{
+		Role:             openai.ChatMessageRoleAssistant,
+		Content:          "Renaming the config loader.",
+		ReasoningContent: "The loader name hides that it also validates.",
+	})
+
+	stored, _ := sm.GetSessionMessages(session.ID)
+	if stored[len(stored)-1].ReasoningContent == "" {
+		t.Error("Expected the reasoning trace to be kept in the session")
+	}
+
+	filtered, err := sm.GetFilteredSessionMessages(session.ID, ContextBudget{Model: "openai/o3"})
+	if err != nil {
+		t.Fatalf("Failed to get filtered messages: %v", err)
+	}
+	last := filtered[len(filtered)-1]
+	if last.ReasoningContent != "" || last.Content != "Renaming the config loader." {
+		t.Errorf("Expected the reasoning to be stripped from replayed context, got %+v", last)
+	}
+}
@@ -0,0 +1,137 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Interruption records why a session stopped without completing
+type Interruption struct {
+	Reason string `json:"reason"`
+	// LastActivity is when the session was last written to before it stopped
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// processAlive reports whether a process with pid is running on this host
+var processAlive = func(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows FindProcess only succeeds for running processes
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	// Signal 0 checks for the process without affecting it; EPERM still means it exists
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || !errors.Is(err, os.ErrProcessDone)
+}
+
+// InterruptSession marks an active session as interrupted, keeping its messages for a resume
+func (sm *SessionManager) InterruptSession(sessionID, reason string) error {
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
+
+	session := sm.loadSession(sessionID)
+	if session == nil {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	if session.Status() != StatusActive {
+		return nil
+	}
+	return sm.interrupt(session, reason)
+}
+
+// interrupt records the interruption and moves the session to interrupted storage. The caller
+// must hold sessionMutex for writing.
+func (sm *SessionManager) interrupt(session *AgentSession, reason string) error {
+	session.Interruption = &Interruption{Reason: reason, LastActivity: session.UpdatedAt}
+	session.StopReason = StatusInterrupted
+	session.UpdatedAt = time.Now()
+	return sm.store.Update(session)
+}
+
+// RecoverSessions marks active sessions whose process is gone as interrupted and returns copies of
+// them. Sessions run by this process, or by a live process on this host, are left alone, and so
+// are sessions run on other hosts, whose processes cannot be checked.
+func (sm *SessionManager) RecoverSessions() ([]*AgentSession, error) {
+	ids, err := sm.store.List(StatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
+
+	var recovered []*AgentSession
+	for _, sessionID := range ids {
+		session := sm.loadSession(sessionID)
+		if session == nil || session.Status() != StatusActive {
+			continue
+		}
+
+		var reason string
+		switch {
+		case session.OwnerPID == 0:
+			reason = "no process was recorded as running the session"
+		case session.OwnerHost != sm.ownerHost:
+			continue
+		case session.OwnerPID == sm.ownerPID || processAlive(session.OwnerPID):
+			continue
+		default:
+			reason = fmt.Sprintf("process %d exited while running the session", session.OwnerPID)
+		}
+
+		if err := sm.interrupt(session, reason); err != nil {
+			return recovered, fmt.Errorf("failed to mark session %s as interrupted: %w", sessionID, err)
+		}
+		sessionCopy := *session
+		recovered = append(recovered, &sessionCopy)
+	}
+	return recovered, nil
+}
+
+// truncateToConsistent cuts a session back to its last consistent point, dropping tool calls
+// whose results were never recorded. It returns the messages kept and whether any were cut; the
+// caller must hold sessionMutex for writing and store the cut.
+func (sm *SessionManager) truncateToConsistent(session *AgentSession) (int, bool) {
+	keep := ConsistentLength(session.Messages)
+	if keep == len(session.Messages) {
+		return keep, false
+	}
+
+	fmt.Printf("DEBUG: Resuming session %s from message %d of %d\n", session.ID, keep, len(session.Messages))
+	session.Messages = session.Messages[:keep]
+	if session.CompactedMessages > keep {
+		session.CompactedMessages = keep
+	}
+	session.UpdatedAt = time.Now()
+	return keep, true
+}
+
+// ConsistentLength returns how many leading messages form a consistent history, one in which
+// every tool call is followed by its result, so the conversation can go on from there
+func ConsistentLength(messages []openai.ChatCompletionMessage) int {
+	consistent := 0
+	pending := make(map[string]bool)
+	for i, msg := range messages {
+		switch msg.Role {
+		case openai.ChatMessageRoleAssistant:
+			for _, call := range msg.ToolCalls {
+				pending[call.ID] = true
+			}
+		case openai.ChatMessageRoleTool:
+			delete(pending, msg.ToolCallID)
+		}
+		if len(pending) == 0 {
+			consistent = i + 1
+		}
+	}
+	return consistent
+}
@@ -0,0 +1,166 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorka/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// crashedSession stores an active session whose last assistant turn has one of its two tool
+// results missing, as if the process had died in the middle of running the tools
+func crashedSession(t *testing.T, sm *SessionManager) *AgentSession {
+	session, err := sm.CreateSession("engineer", &types.BehavioralMatrix{AgentID: "engineer"}, "")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "Fix the build"})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{
+		Role:      openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{{ID: "call_1", Function: openai.FunctionCall{Name: "read_file"}}},
+	})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: "package main"})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleAssistant,
+		ToolCalls: []openai.ToolCall{
+			{ID: "call_2", Function: openai.FunctionCall{Name: "edit_file"}},
+			{ID: "call_3", Function: openai.FunctionCall{Name: "run_tests"}},
+		},
+	})
+	sm.AddMessage(session.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: "call_2", Content: "edited"})
+	return session
+}
+
+// ownedBy runs create as if the process pid on host had created the session
+func ownedBy(sm *SessionManager, host string, pid int, create func() *AgentSession) *AgentSession {
+	ownerHost, ownerPID := sm.ownerHost, sm.ownerPID
+	defer func() { sm.ownerHost, sm.ownerPID = ownerHost, ownerPID }()
+	sm.ownerHost, sm.ownerPID = host, pid
+	return create()
+}
+
+func TestRecoverSessions(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	host := sm.ownerHost
+
+	alive := processAlive
+	defer func() { processAlive = alive }()
+	processAlive = func(pid int) bool { return pid == 4343 }
+
+	crashed := ownedBy(sm, host, 4242, func() *AgentSession { return crashedSession(t, sm) })
+	running := ownedBy(sm, host, 4343, func() *AgentSession { return crashedSession(t, sm) })
+	remote := ownedBy(sm, "elsewhere", 4242, func() *AgentSession { return crashedSession(t, sm) })
+	own := crashedSession(t, sm)
+	lastActivity := crashed.UpdatedAt
+
+	// A restarted server only marks the session whose process is gone
+	sm = NewSessionManagerWithDir(storageDir)
+	recovered, err := sm.RecoverSessions()
+	if err != nil {
+		t.Fatalf("Failed to recover sessions: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].ID != crashed.ID {
+		t.Fatalf("Expected only %s to be interrupted, got %+v", crashed.ID, recovered)
+	}
+	if !strings.Contains(recovered[0].Interruption.Reason, "4242") || !recovered[0].Interruption.LastActivity.Equal(lastActivity) {
+		t.Errorf("Expected the reason and last activity to be recorded, got %+v", recovered[0].Interruption)
+	}
+	if _, err := os.Stat(filepath.Join(storageDir, StatusInterrupted, crashed.ID+".jsonl")); err != nil {
+		t.Errorf("Expected the session log to move to interrupted storage: %v", err)
+	}
+
+	sm = NewSessionManagerWithDir(storageDir)
+	interrupted := sm.ListSessions(SessionFilter{Status: StatusInterrupted})
+	if len(interrupted) != 1 || interrupted[0].ID != crashed.ID || interrupted[0].Interruption == nil {
+		t.Fatalf("Expected the interruption to survive a reload, got %+v", interrupted)
+	}
+	for _, id := range []string{running.ID, remote.ID, own.ID} {
+		if session, _ := sm.GetSession(id); session.Status() != StatusActive {
+			t.Errorf("Expected session %s to stay active, got %s", id, session.Status())
+		}
+	}
+}
+
+func TestResumeInterruptedSession(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	crashed := crashedSession(t, sm)
+	if err := sm.InterruptSession(crashed.ID, "run failed: connection reset"); err != nil {
+		t.Fatalf("Failed to interrupt session: %v", err)
+	}
+
+	// Resuming drops the tool calls whose results were never recorded
+	sm = NewSessionManagerWithDir(storageDir)
+	resumed, err := sm.ReopenSession(crashed.ID, &types.BehavioralMatrix{AgentID: "engineer"})
+	if err != nil {
+		t.Fatalf("Failed to reopen session: %v", err)
+	}
+	if len(resumed.Messages) != 4 || resumed.Interruption != nil || resumed.Status() != StatusActive {
+		t.Fatalf("Expected an active session cut back to 4 messages, got %d messages, status %s", len(resumed.Messages), resumed.Status())
+	}
+	sm.AddMessage(crashed.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "Carry on"})
+
+	sm = NewSessionManagerWithDir(storageDir)
+	reloaded, _ := sm.GetSession(crashed.ID)
+	if len(reloaded.Messages) != 5 || reloaded.Messages[4].Content != "Carry on" || reloaded.Status() != StatusActive {
+		t.Errorf("Expected the truncated session and its new message after a reload, got %+v", reloaded.Messages)
+	}
+}
+
+func TestReopenCompletedSessionDropsUnansweredCalls(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	completed := crashedSession(t, sm)
+	sm.CompleteSession(completed.ID)
+
+	sm = NewSessionManagerWithDir(storageDir)
+	reopened, err := sm.ReopenSession(completed.ID, &types.BehavioralMatrix{AgentID: "engineer"})
+	if err != nil {
+		t.Fatalf("Failed to reopen session: %v", err)
+	}
+	if len(reopened.Messages) != 4 || reopened.Status() != StatusActive {
+		t.Fatalf("Expected an active session cut back to 4 messages, got %d messages, status %s", len(reopened.Messages), reopened.Status())
+	}
+	sm.AddMessage(completed.ID, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "Carry on"})
+
+	sm = NewSessionManagerWithDir(storageDir)
+	reloaded, _ := sm.GetSession(completed.ID)
+	if len(reloaded.Messages) != 5 || ConsistentLength(reloaded.Messages) != 5 {
+		t.Errorf("Expected the follow-up right after the last answered exchange, got %+v", reloaded.Messages)
+	}
+}
+
+func TestConsistentLength(t *testing.T) {
+	call := func(ids ...string) openai.ChatCompletionMessage {
+		msg := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+		for _, id := range ids {
+			msg.ToolCalls = append(msg.ToolCalls, openai.ToolCall{ID: id})
+		}
+		return msg
+	}
+	result := func(id string) openai.ChatCompletionMessage {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleTool, ToolCallID: id}
+	}
+	user := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser}
+
+	tests := []struct {
+		name     string
+		messages []openai.ChatCompletionMessage
+		want     int
+	}{
+		{"empty", nil, 0},
+		{"complete", []openai.ChatCompletionMessage{user, call("a"), result("a"), call()}, 4},
+		{"missing result", []openai.ChatCompletionMessage{user, call("a"), result("a"), call("b")}, 3},
+		{"partial results", []openai.ChatCompletionMessage{user, call("a", "b"), result("b")}, 1},
+	}
+	for _, tt := range tests {
+		if got := ConsistentLength(tt.messages); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
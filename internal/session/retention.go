@@ -0,0 +1,224 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gorka/internal/utils"
+)
+
+// Reasons a retention policy gives for collecting a session
+const (
+	ExpiredByAge   = "age"
+	ExpiredByCount = "count"
+	ExpiredBySize  = "size"
+)
+
+// RetentionPolicy decides which completed sessions are kept, newest first; zero limits are
+// unlimited. Interrupted sessions are only subject to MaxAge, and active sessions are never collected.
+type RetentionPolicy struct {
+	MaxAge   time.Duration
+	MaxCount int
+	// MaxBytes caps the total size of the kept sessions
+	MaxBytes int64
+	// Archive gzips expired sessions instead of deleting them
+	Archive bool
+}
+
+// RetentionFromConfig converts the configured retention limits into a policy
+func RetentionFromConfig(retention utils.SessionRetention) RetentionPolicy {
+	return RetentionPolicy{
+		MaxAge:   time.Duration(retention.MaxAgeDays) * 24 * time.Hour,
+		MaxCount: retention.MaxCount,
+		MaxBytes: int64(retention.MaxSizeMB) << 20,
+		Archive:  retention.Archive,
+	}
+}
+
+// Enabled reports whether the policy limits anything
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxCount > 0 || p.MaxBytes > 0
+}
+
+// ExpiredSession is a completed or interrupted session a retention policy no longer keeps
+type ExpiredSession struct {
+	SessionInfo
+	// Reason is the limit the session went over: age, count or size
+	Reason string
+}
+
+// ExpiredSessions returns the sessions over the policy's limits, oldest last
+func (sm *SessionManager) ExpiredSessions(policy RetentionPolicy) ([]ExpiredSession, error) {
+	infos, err := sm.storedSessions(StatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []ExpiredSession
+	kept, keptBytes := 0, int64(0)
+	full := false
+	cutoff := time.Now().Add(-policy.MaxAge)
+	for _, info := range infos {
+		reason := ""
+		switch {
+		case policy.MaxAge > 0 && info.UpdatedAt.Before(cutoff):
+			reason = ExpiredByAge
+		case policy.MaxCount > 0 && kept >= policy.MaxCount:
+			reason = ExpiredByCount
+		case policy.MaxBytes > 0 && (full || keptBytes+info.Size > policy.MaxBytes):
+			// Once the newest sessions fill the size limit, every older one goes too
+			full = true
+			reason = ExpiredBySize
+		}
+
+		if reason != "" {
+			expired = append(expired, ExpiredSession{SessionInfo: info, Reason: reason})
+			continue
+		}
+		kept++
+		keptBytes += info.Size
+	}
+
+	// An interrupted session waits for a resume, and only goes once it is too old for one
+	if policy.MaxAge > 0 {
+		interrupted, err := sm.storedSessions(StatusInterrupted)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range interrupted {
+			if info.UpdatedAt.Before(cutoff) {
+				expired = append(expired, ExpiredSession{SessionInfo: info, Reason: ExpiredByAge})
+			}
+		}
+		sort.SliceStable(expired, func(i, j int) bool {
+			return expired[i].UpdatedAt.After(expired[j].UpdatedAt)
+		})
+	}
+	return expired, nil
+}
+
+// storedSessions describes the stored sessions with a status, most recently updated first
+func (sm *SessionManager) storedSessions(status string) ([]SessionInfo, error) {
+	ids, err := sm.store.List(status)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SessionInfo, 0, len(ids))
+	for _, sessionID := range ids {
+		info, err := sm.store.Stat(sessionID)
+		if err != nil {
+			// The session was removed or reopened since it was listed
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].UpdatedAt.Equal(infos[j].UpdatedAt) {
+			return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos, nil
+}
+
+// CollectSessions archives or removes the sessions over the policy's limits and returns them.
+// With dryRun nothing is changed and the sessions that would be collected are returned.
+func (sm *SessionManager) CollectSessions(policy RetentionPolicy, dryRun bool) ([]ExpiredSession, error) {
+	if !policy.Enabled() {
+		return nil, nil
+	}
+	expired, err := sm.ExpiredSessions(policy)
+	if err != nil || dryRun {
+		return expired, err
+	}
+
+	var collected []ExpiredSession
+	for _, session := range expired {
+		if !sm.claimForCollection(session.ID) {
+			continue
+		}
+
+		// Compressing can take a while, so other sessions stay usable meanwhile
+		if policy.Archive {
+			err = sm.store.Archive(session.ID)
+		} else {
+			err = sm.store.Remove(session.ID)
+		}
+		sm.releaseFromCollection(session.ID)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return collected, fmt.Errorf("failed to collect session %s: %w", session.ID, err)
+		}
+		collected = append(collected, session)
+	}
+	return collected, nil
+}
+
+// claimForCollection takes a session out of use while it is archived or removed. It fails for a
+// session reopened since it was listed, which is in use again.
+func (sm *SessionManager) claimForCollection(sessionID string) bool {
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
+
+	if loaded, exists := sm.sessions[sessionID]; exists && loaded.Status() == StatusActive {
+		return false
+	}
+	delete(sm.sessions, sessionID)
+	sm.collecting[sessionID] = true
+	return true
+}
+
+// releaseFromCollection ends the collection of a session
+func (sm *SessionManager) releaseFromCollection(sessionID string) {
+	sm.sessionMutex.Lock()
+	defer sm.sessionMutex.Unlock()
+	delete(sm.collecting, sessionID)
+}
+
+// StartSweeper collects expired sessions right away and then every interval until ctx is done
+func (sm *SessionManager) StartSweeper(ctx context.Context, policy RetentionPolicy, interval time.Duration) {
+	if !policy.Enabled() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sm.sweep(policy)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// sweep runs one collection and logs what it freed
+func (sm *SessionManager) sweep(policy RetentionPolicy) {
+	collected, err := sm.CollectSessions(policy, false)
+	if err != nil {
+		fmt.Printf("WARNING: Session garbage collection failed: %v\n", err)
+	}
+	if len(collected) == 0 {
+		return
+	}
+
+	var freed int64
+	for _, session := range collected {
+		freed += session.Size
+	}
+	action := "removed"
+	if policy.Archive {
+		action = "archived"
+	}
+	fmt.Printf("DEBUG: Session garbage collection %s %d sessions (%d bytes)\n", action, len(collected), freed)
+}
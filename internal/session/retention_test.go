@@ -0,0 +1,192 @@
+package session
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorka/internal/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// agedSessions stores completed sessions last used 1, 2, 3... days ago, newest first
+func agedSessions(t *testing.T, sm *SessionManager, storageDir string, count int) []string {
+	var ids []string
+	for i := 0; i < count; i++ {
+		session := reviewSession(t, sm, "reviewer")
+		updated := time.Now().Add(-time.Duration(i+1) * 24 * time.Hour)
+		os.Chtimes(filepath.Join(storageDir, StatusCompleted, session.ID+".jsonl"), updated, updated)
+		ids = append(ids, session.ID)
+	}
+	return ids
+}
+
+func TestExpiredSessions(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	ids := agedSessions(t, sm, storageDir, 4)
+	sm.CreateSession("engineer", &types.BehavioralMatrix{AgentID: "engineer"}, "")
+	info, _ := sm.store.Stat(ids[0])
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   string
+	}{
+		{"age", RetentionPolicy{MaxAge: 60 * time.Hour}, ids[2] + ":age " + ids[3] + ":age"},
+		{"count", RetentionPolicy{MaxCount: 1}, ids[1] + ":count " + ids[2] + ":count " + ids[3] + ":count"},
+		{"size", RetentionPolicy{MaxBytes: 2*info.Size + 10}, ids[2] + ":size " + ids[3] + ":size"},
+		{"age before count", RetentionPolicy{MaxAge: 84 * time.Hour, MaxCount: 2}, ids[2] + ":count " + ids[3] + ":age"},
+	}
+	for _, tt := range tests {
+		expired, err := sm.ExpiredSessions(tt.policy)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, session := range expired {
+			got = append(got, session.ID+":"+session.Reason)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: expected %s, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCollectSessions(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	ids := agedSessions(t, sm, storageDir, 3)
+	policy := RetentionPolicy{MaxCount: 1}
+
+	if collected, _ := sm.CollectSessions(policy, true); len(collected) != 2 {
+		t.Fatalf("Expected a dry run to report 2 sessions, got %d", len(collected))
+	}
+	if ids, _ := sm.store.List(StatusCompleted); len(ids) != 3 {
+		t.Fatalf("Expected a dry run to keep every session, got %d", len(ids))
+	}
+
+	// A session reopened after it was listed is left alone
+	if _, err := sm.ReopenSession(ids[2], &types.BehavioralMatrix{AgentID: "reviewer"}); err != nil {
+		t.Fatalf("Failed to reopen session: %v", err)
+	}
+	policy.Archive = true
+	collected, err := sm.CollectSessions(policy, false)
+	if err != nil || len(collected) != 1 || collected[0].ID != ids[1] {
+		t.Fatalf("Expected only the older completed session to be archived, got %+v, %v", collected, err)
+	}
+	if _, exists := sm.GetSession(ids[1]); exists {
+		t.Error("Expected an archived session to be gone from the store")
+	}
+	if _, exists := sm.GetSession(ids[2]); !exists {
+		t.Error("Expected the reopened session to be kept")
+	}
+
+	file, err := os.Open(filepath.Join(storageDir, "archive", ids[1]+".jsonl.gz"))
+	if err != nil {
+		t.Fatalf("Expected an archive file: %v", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Expected a gzip archive: %v", err)
+	}
+	archived, _ := io.ReadAll(reader)
+	if !strings.Contains(string(archived), `"id":"`+ids[1]+`"`) || !strings.Contains(string(archived), "make install") {
+		t.Error("Expected the archive to hold the whole session log")
+	}
+}
+
+func TestSweeperCollectsInBackground(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	ids := agedSessions(t, sm, storageDir, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sm.StartSweeper(ctx, RetentionPolicy{MaxAge: 36 * time.Hour}, time.Hour)
+
+	path := filepath.Join(storageDir, StatusCompleted, ids[1]+".jsonl")
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the sweeper to remove the expired session on start")
+	}
+	if _, exists := sm.GetSession(ids[0]); !exists {
+		t.Error("Expected the newer session to be kept")
+	}
+}
+
+func TestCollectSessionsIncludesOldInterruptedSessions(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	sm := NewSessionManagerWithDir(storageDir)
+	var ids []string
+	for _, age := range []time.Duration{time.Hour, 72 * time.Hour} {
+		session := crashedSession(t, sm)
+		sm.InterruptSession(session.ID, "run failed")
+		updated := time.Now().Add(-age)
+		os.Chtimes(filepath.Join(storageDir, StatusInterrupted, session.ID+".jsonl"), updated, updated)
+		ids = append(ids, session.ID)
+	}
+
+	// Count and size limits leave interrupted sessions for a resume
+	if collected, _ := sm.CollectSessions(RetentionPolicy{MaxCount: 1, MaxBytes: 1}, false); len(collected) != 0 {
+		t.Errorf("Expected no interrupted session over the count or size limits, got %+v", collected)
+	}
+	collected, err := sm.CollectSessions(RetentionPolicy{MaxAge: 36 * time.Hour}, false)
+	if err != nil || len(collected) != 1 || collected[0].ID != ids[1] || collected[0].Reason != ExpiredByAge {
+		t.Fatalf("Expected the old interrupted session to go by age, got %+v, %v", collected, err)
+	}
+	if _, exists := sm.GetSession(ids[0]); !exists {
+		t.Error("Expected the recent interrupted session to be kept")
+	}
+}
+
+// blockingStore holds up Archive until released, to look at the manager while it compresses
+type blockingStore struct {
+	SessionStore
+	archiving chan string
+	release   chan struct{}
+}
+
+func (st *blockingStore) Archive(sessionID string) error {
+	st.archiving <- sessionID
+	<-st.release
+	return st.SessionStore.Archive(sessionID)
+}
+
+func TestCollectSessionsKeepsOtherSessionsUsable(t *testing.T) {
+	storageDir := filepath.Join(t.TempDir(), "sessions")
+	store := &blockingStore{SessionStore: NewJSONLStore(storageDir, SyncInterval), archiving: make(chan string), release: make(chan struct{})}
+	sm := NewSessionManagerWithStore(store)
+	ids := agedSessions(t, sm, storageDir, 2)
+
+	done := make(chan error)
+	go func() {
+		_, err := sm.CollectSessions(RetentionPolicy{MaxCount: 1, Archive: true}, false)
+		done <- err
+	}()
+	if archiving := <-store.archiving; archiving != ids[1] {
+		t.Fatalf("Expected %s to be archived, got %s", ids[1], archiving)
+	}
+
+	// While the archive is written, other sessions can be read and written
+	if err := sm.AddMessage(ids[0], openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: "again"}); err != nil {
+		t.Errorf("Expected the kept session to take a message during collection: %v", err)
+	}
+	if _, exists := sm.GetSession(ids[1]); exists {
+		t.Error("Expected the session being archived to be out of use")
+	}
+	close(store.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Collection failed: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// SessionStore persists sessions. The manager keeps the sessions in use in memory and loads
+// the others from the store on first use.
+type SessionStore interface {
+	// Create stores a new session with its messages
+	Create(session *AgentSession) error
+	// Append stores messages added to the end of a session
+	Append(session *AgentSession, messages ...openai.ChatCompletionMessage) error
+	// Update stores a change to a session's status, stop reason or working memory
+	Update(session *AgentSession) error
+	// Load reads a session; the error wraps os.ErrNotExist when there is no such session
+	Load(sessionID string) (*AgentSession, error)
+	// List returns the IDs of the stored sessions with a status, or of all sessions when status is empty
+	List(status string) ([]string, error)
+	// Remove deletes a session; the error wraps os.ErrNotExist when there is no such session
+	Remove(sessionID string) error
+	// Compact rewrites a session's storage without superseded records, keeping its content
+	Compact(sessionID string) error
+	// Stat describes a stored session without reading its messages
+	Stat(sessionID string) (SessionInfo, error)
+	// Archive moves a session into compressed storage, where it is no longer listed or loaded
+	Archive(sessionID string) error
+	// Truncate drops the messages after the first keep of a stored session
+	Truncate(session *AgentSession, keep int) error
+	// Close flushes any writes still buffered; the store must not be used afterwards
+	Close() error
+}
+
+// SessionInfo describes a stored session
+type SessionInfo struct {
+	ID        string
+	UpdatedAt time.Time
+	// Size is the number of bytes the session takes in the store
+	Size int64
+}
+
+// SyncPolicy controls when session writes are flushed to disk
+type SyncPolicy string
+
+const (
+	// SyncAlways flushes after every write
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval flushes a session at most once per syncInterval, and whenever its status changes.
+	// Writes in between are flushed in the background, so at most syncInterval of them can be lost.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the operating system
+	SyncNever SyncPolicy = "never"
+)
+
+// syncInterval is how often SyncInterval flushes a session that keeps growing
+const syncInterval = time.Second
+
+// sessionMeta is everything about a session except its messages
+type sessionMeta struct {
+	ID        string    `json:"id"`
+	AgentID   string    `json:"agent_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Completed bool      `json:"completed"`
+
+	StopReason        string `json:"stop_reason,omitempty"`
+	WorkingMemory     string `json:"working_memory,omitempty"`
+	CompactedMessages int    `json:"compacted_messages,omitempty"`
+
+	OwnerHost    string        `json:"owner_host,omitempty"`
+	OwnerPID     int           `json:"owner_pid,omitempty"`
+	Interruption *Interruption `json:"interruption,omitempty"`
+}
+
+// metaOf returns the metadata of a session
+func metaOf(session *AgentSession) sessionMeta {
+	return sessionMeta{
+		ID:        session.ID,
+		AgentID:   session.AgentID,
+		CreatedAt: session.CreatedAt,
+		UpdatedAt: session.UpdatedAt,
+		Completed: session.Completed,
+
+		StopReason:        session.StopReason,
+		WorkingMemory:     session.WorkingMemory,
+		CompactedMessages: session.CompactedMessages,
+
+		OwnerHost:    session.OwnerHost,
+		OwnerPID:     session.OwnerPID,
+		Interruption: session.Interruption,
+	}
+}
+
+// apply copies the metadata onto a session, leaving its messages and matrix alone
+func (m sessionMeta) apply(session *AgentSession) {
+	session.ID = m.ID
+	session.AgentID = m.AgentID
+	session.CreatedAt = m.CreatedAt
+	session.UpdatedAt = m.UpdatedAt
+	session.Completed = m.Completed
+	session.StopReason = m.StopReason
+	session.WorkingMemory = m.WorkingMemory
+	session.CompactedMessages = m.CompactedMessages
+	session.OwnerHost = m.OwnerHost
+	session.OwnerPID = m.OwnerPID
+	session.Interruption = m.Interruption
+}
@@ -0,0 +1,145 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BPE is a byte-level byte pair encoder that gives the same tokens as tiktoken when loaded
+// with the same ranks and splitter
+type BPE struct {
+	name  string
+	ranks map[string]int
+	split func(string) []string
+}
+
+// NewBPE creates an encoder from merge ranks, keyed by the token's bytes
+func NewBPE(name string, ranks map[string]int, split func(string) []string) *BPE {
+	return &BPE{name: name, ranks: ranks, split: split}
+}
+
+// Name returns the encoding name, e.g. cl100k_base
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count returns the number of tokens in text
+func (b *BPE) Count(text string) int {
+	count := 0
+	for _, piece := range b.split(text) {
+		if _, ok := b.ranks[piece]; ok {
+			count++
+			continue
+		}
+		count += len(b.merge(piece)) - 1
+	}
+	return count
+}
+
+// Encode returns the token ids for text; bytes missing from the ranks encode as -1
+func (b *BPE) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range b.split(text) {
+		if rank, ok := b.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		bounds := b.merge(piece)
+		for i := 0; i+1 < len(bounds); i++ {
+			rank, ok := b.ranks[piece[bounds[i]:bounds[i+1]]]
+			if !ok {
+				rank = -1
+			}
+			tokens = append(tokens, rank)
+		}
+	}
+	return tokens
+}
+
+// merge repeatedly joins the adjacent pair with the lowest rank, as tiktoken does, and
+// returns the byte offsets where the resulting tokens start plus the piece length
+func (b *BPE) merge(piece string) []int {
+	type part struct {
+		start int
+		rank  int
+	}
+	rankOf := func(from, to int) int {
+		if rank, ok := b.ranks[piece[from:to]]; ok {
+			return rank
+		}
+		return math.MaxInt
+	}
+
+	parts := make([]part, 0, len(piece)+1)
+	for i := 0; i+1 < len(piece); i++ {
+		parts = append(parts, part{start: i, rank: rankOf(i, i+2)})
+	}
+	parts = append(parts, part{start: len(piece) - 1, rank: math.MaxInt}, part{start: len(piece), rank: math.MaxInt})
+
+	// pairRank ranks the token formed by joining the parts starting at i and i+1
+	pairRank := func(i int) int {
+		if i+3 < len(parts) {
+			return rankOf(parts[i].start, parts[i+3].start)
+		}
+		return math.MaxInt
+	}
+
+	for {
+		best := -1
+		for i := 0; i+1 < len(parts); i++ {
+			if parts[i].rank != math.MaxInt && (best < 0 || parts[i].rank < parts[best].rank) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		if best > 0 {
+			parts[best-1].rank = pairRank(best - 1)
+		}
+		parts[best].rank = pairRank(best)
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	bounds := make([]int, len(parts))
+	for i, p := range parts {
+		bounds[i] = p.start
+	}
+	return bounds
+}
+
+// LoadRanks reads a .tiktoken rank file: one base64 token and its rank per line
+func LoadRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a token and a rank", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid token: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
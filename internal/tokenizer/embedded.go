@@ -0,0 +1,72 @@
+package tokenizer
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sync"
+)
+
+//go:generate go run gen_ranks.go
+
+// rankFiles holds the <encoding>.tiktoken rank files built into the binary
+//
+//go:embed ranks
+var rankFiles embed.FS
+
+// splitters pre-tokenizes text for each encoding the registry knows
+var splitters = map[string]func(string) []string{CL100K: splitCL100K, O200K: splitO200K}
+
+// builtinEncodings returns exact encoders for the encodings with a rank file under ranks/ in
+// fsys, and estimators for the others
+func builtinEncodings(fsys fs.FS) map[string]Tokenizer {
+	builtin := make(map[string]Tokenizer, len(splitters))
+	for name, split := range splitters {
+		path := "ranks/" + name + ".tiktoken"
+		if _, err := fs.Stat(fsys, path); err != nil {
+			builtin[name] = &estimator{name: name, split: split}
+			continue
+		}
+		builtin[name] = &lazyBPE{name: name, split: split, fsys: fsys, path: path}
+	}
+	return builtin
+}
+
+// lazyBPE parses its rank file on first use, so processes that never count tokens do not pay
+// for loading a few hundred thousand ranks
+type lazyBPE struct {
+	name  string
+	split func(string) []string
+	fsys  fs.FS
+	path  string
+
+	once    sync.Once
+	encoder Tokenizer
+}
+
+// Name returns the encoding name, e.g. cl100k_base
+func (l *lazyBPE) Name() string {
+	return l.name
+}
+
+// Count returns the number of tokens in text
+func (l *lazyBPE) Count(text string) int {
+	l.once.Do(l.load)
+	return l.encoder.Count(text)
+}
+
+// load reads the ranks, falling back to estimated counts when the file cannot be read
+func (l *lazyBPE) load() {
+	file, err := l.fsys.Open(l.path)
+	if err == nil {
+		var ranks map[string]int
+		ranks, err = LoadRanks(file)
+		file.Close()
+		if err == nil {
+			l.encoder = NewBPE(l.name, ranks, l.split)
+			return
+		}
+	}
+	fmt.Printf("WARNING: Token counts for %s will be estimated: failed to load %s: %v\n", l.name, l.path, err)
+	l.encoder = &estimator{name: l.name, split: l.split}
+}
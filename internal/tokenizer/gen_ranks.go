@@ -0,0 +1,64 @@
+//go:build ignore
+
+// gen_ranks downloads the tiktoken rank files embedded by the tokenizer package and checks
+// them against the hashes tiktoken publishes.
+// Run: go generate ./internal/tokenizer
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const baseURL = "https://openaipublic.blob.core.windows.net/encodings/"
+
+// rankFiles maps each embedded rank file to its expected SHA-256
+var rankFiles = map[string]string{
+	"cl100k_base.tiktoken": "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	"o200k_base.tiktoken":  "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+}
+
+func main() {
+	for name, hash := range rankFiles {
+		if err := fetch(name, hash); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Fetched ranks/%s\n", name)
+	}
+}
+
+// fetch downloads a rank file into ranks/, keeping an existing copy when its hash matches
+func fetch(name, hash string) error {
+	path := filepath.Join("ranks", name)
+	if existing, err := os.ReadFile(path); err == nil && sum(existing) == hash {
+		return nil
+	}
+
+	resp, err := http.Get(baseURL + name)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: HTTP %d", name, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if got := sum(data); got != hash {
+		return fmt.Errorf("%s has SHA-256 %s, want %s", name, got, hash)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func sum(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
@@ -0,0 +1,68 @@
+package tokenizer
+
+import (
+	"encoding/json"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Chat formatting overhead, following OpenAI's published counting recipe
+const (
+	tokensPerMessage  = 3
+	tokensPerName     = 1
+	tokensPerToolCall = 3
+	tokensPerTool     = 8
+	replyPriming      = 3
+	// Tool images are at most 1568px on the longer side, which providers bill at roughly
+	// 800 to 3000 tokens depending on shape and detail
+	tokensPerImage = 1500
+)
+
+// CountMessages returns the prompt tokens a conversation costs, including chat formatting
+func CountMessages(t Tokenizer, messages []openai.ChatCompletionMessage) int {
+	if len(messages) == 0 {
+		return 0
+	}
+	total := replyPriming
+	for _, message := range messages {
+		total += CountMessage(t, message)
+	}
+	return total
+}
+
+// CountMessage returns the tokens one message costs, including its tool calls
+func CountMessage(t Tokenizer, message openai.ChatCompletionMessage) int {
+	total := tokensPerMessage + t.Count(message.Role) + t.Count(message.Content)
+	for _, part := range message.MultiContent {
+		total += t.Count(part.Text)
+		if part.ImageURL != nil {
+			total += tokensPerImage
+		}
+	}
+	if message.Name != "" {
+		total += tokensPerName + t.Count(message.Name)
+	}
+	if message.ToolCallID != "" {
+		total += t.Count(message.ToolCallID)
+	}
+	for _, call := range message.ToolCalls {
+		total += tokensPerToolCall + t.Count(call.Function.Name) + t.Count(call.Function.Arguments)
+	}
+	return total
+}
+
+// CountTools returns the tokens the tool schemas sent with each request cost
+func CountTools(t Tokenizer, tools []openai.Tool) int {
+	total := 0
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema, err := json.Marshal(tool.Function)
+		if err != nil {
+			continue
+		}
+		total += tokensPerTool + t.Count(string(schema))
+	}
+	return total
+}
@@ -0,0 +1,203 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Splitters cut text into the pieces BPE merges never cross. They follow the tiktoken
+// patterns by hand since Go's regexp has no lookahead.
+
+// splitCL100K splits text like the cl100k_base pattern:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitCL100K(text string) []string {
+	var pieces []string
+	for pos := 0; pos < len(text); {
+		end := matchContraction(text, pos)
+		if end == pos {
+			end = matchPrefixed(text, pos, matchLetters)
+		}
+		if end == pos {
+			end = matchNumbers(text, pos)
+		}
+		if end == pos {
+			end = matchPunctuation(text, pos, false)
+		}
+		if end == pos {
+			end = matchWhitespace(text, pos)
+		}
+		pieces = append(pieces, text[pos:end])
+		pos = end
+	}
+	return pieces
+}
+
+// splitO200K splits text like the o200k_base pattern, which also breaks words at case
+// changes and keeps contractions on the word:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func splitO200K(text string) []string {
+	var pieces []string
+	for pos := 0; pos < len(text); {
+		end := matchPrefixed(text, pos, matchLowerWord)
+		if end == pos {
+			end = matchPrefixed(text, pos, matchUpperWord)
+		}
+		if end == pos {
+			end = matchNumbers(text, pos)
+		}
+		if end == pos {
+			end = matchPunctuation(text, pos, true)
+		}
+		if end == pos {
+			end = matchWhitespace(text, pos)
+		}
+		pieces = append(pieces, text[pos:end])
+		pos = end
+	}
+	return pieces
+}
+
+// Every match function returns the end of its match at pos, or pos when it does not match
+
+// matchContraction matches 's, 't, 're, 've, 'm, 'll and 'd in any case
+func matchContraction(text string, pos int) int {
+	if pos >= len(text) || text[pos] != '\'' {
+		return pos
+	}
+	lower := func(i int) byte {
+		if i < len(text) && text[i] >= 'A' && text[i] <= 'Z' {
+			return text[i] + 'a' - 'A'
+		}
+		if i < len(text) {
+			return text[i]
+		}
+		return 0
+	}
+	switch first, second := lower(pos+1), lower(pos+2); {
+	case first == 's' || first == 't' || first == 'm' || first == 'd':
+		return pos + 2
+	case first == 'r' && second == 'e', first == 'v' && second == 'e', first == 'l' && second == 'l':
+		return pos + 3
+	}
+	return pos
+}
+
+// matchPrefixed matches an optional [^\r\n\p{L}\p{N}] followed by body, preferring the prefix
+func matchPrefixed(text string, pos int, body func(string, int) int) int {
+	if r, size := utf8.DecodeRuneInString(text[pos:]); r != '\r' && r != '\n' && !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+		if end := body(text, pos+size); end > pos+size {
+			return end
+		}
+	}
+	return body(text, pos)
+}
+
+// matchLetters matches \p{L}+
+func matchLetters(text string, pos int) int {
+	return matchRun(text, pos, unicode.IsLetter, -1)
+}
+
+// matchLowerWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+ and a contraction
+func matchLowerWord(text string, pos int) int {
+	upperEnd := matchRun(text, pos, isUpperish, -1)
+	// Backtrack through the upper run until a lower run can follow it
+	for split := upperEnd; split >= pos; {
+		if end := matchRun(text, split, isLowerish, -1); end > split {
+			return withContraction(text, end)
+		}
+		if split == pos {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(text[pos:split])
+		split -= size
+	}
+	return pos
+}
+
+// matchUpperWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]* and a contraction
+func matchUpperWord(text string, pos int) int {
+	end := matchRun(text, pos, isUpperish, -1)
+	if end == pos {
+		return pos
+	}
+	return withContraction(text, matchRun(text, end, isLowerish, -1))
+}
+
+// withContraction extends a word ending at end over a following contraction
+func withContraction(text string, end int) int {
+	return matchContraction(text, end)
+}
+
+// matchNumbers matches \p{N}{1,3}
+func matchNumbers(text string, pos int) int {
+	return matchRun(text, pos, unicode.IsNumber, 3)
+}
+
+// matchPunctuation matches ` ?[^\s\p{L}\p{N}]+[\r\n]*`; o200k also lets slashes trail
+func matchPunctuation(text string, pos int, trailingSlash bool) int {
+	start := pos
+	if text[pos] == ' ' {
+		start++
+	}
+	end := matchRun(text, start, isPunctuation, -1)
+	if end == start {
+		return pos
+	}
+	return matchRun(text, end, func(r rune) bool {
+		return r == '\r' || r == '\n' || (trailingSlash && r == '/')
+	}, -1)
+}
+
+// matchWhitespace matches \s*[\r\n]+, then \s+(?!\S), then \s+
+func matchWhitespace(text string, pos int) int {
+	end := matchRun(text, pos, unicode.IsSpace, -1)
+	if end == pos {
+		// Nothing else matched, so take the single rune on its own
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		return pos + size
+	}
+
+	// \s*[\r\n]+ ends just after the last newline in the run
+	for i := end - 1; i >= pos; i-- {
+		if text[i] == '\r' || text[i] == '\n' {
+			return i + 1
+		}
+	}
+
+	// \s+(?!\S) leaves the last space to join the word that follows
+	if end < len(text) {
+		_, size := utf8.DecodeLastRuneInString(text[pos:end])
+		if end-size > pos {
+			return end - size
+		}
+	}
+	return end
+}
+
+// matchRun matches up to max runes (max < 0 for no limit) satisfying in
+func matchRun(text string, pos int, in func(rune) bool, max int) int {
+	for count := 0; pos < len(text) && count != max; count++ {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if !in(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+func isUpperish(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isLowerish(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isPunctuation(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
@@ -0,0 +1,137 @@
+package tokenizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Tokenizer counts the tokens a model family sees in text
+type Tokenizer interface {
+	// Name identifies the encoding, e.g. cl100k_base
+	Name() string
+	// Count returns the number of tokens in text
+	Count(text string) int
+}
+
+// Encoding names understood by the registry
+const (
+	CL100K = "cl100k_base"
+	O200K  = "o200k_base"
+)
+
+// DefaultEncoding counts tokens for models with no known encoding. Families such as Claude,
+// Gemini and Llama use their own vocabularies, which cl100k approximates well.
+const DefaultEncoding = CL100K
+
+var (
+	registryMutex sync.RWMutex
+	// encodings count exactly with the built-in rank files, or estimate when a file was not built in
+	encodings = builtinEncodings(rankFiles)
+	// families maps model names to encodings, matched like the usage price table: without
+	// any "vendor/" prefix, and with a key also matching the variants it is a prefix of
+	families = map[string]string{
+		"gpt-3.5":            CL100K,
+		"gpt-4":              CL100K,
+		"text-embedding-3":   CL100K,
+		"text-embedding-ada": CL100K,
+		"gpt-4o":             O200K,
+		"chatgpt-4o":         O200K,
+		"gpt-4.1":            O200K,
+		"gpt-4.5":            O200K,
+		"gpt-5":              O200K,
+		"gpt-oss":            O200K,
+		"o1":                 O200K,
+		"o3":                 O200K,
+		"o4":                 O200K,
+	}
+	loadedDir string
+)
+
+// Register adds or replaces an encoding under its name
+func Register(t Tokenizer) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	encodings[t.Name()] = t
+}
+
+// RegisterFamily makes models named like family use the named encoding
+func RegisterFamily(family, encoding string) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	families[family] = encoding
+}
+
+// ForModel returns the tokenizer for a model, falling back to DefaultEncoding
+func ForModel(model string) Tokenizer {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	encoding := DefaultEncoding
+	if family := lookup(model, families); family != "" {
+		encoding = families[family]
+	}
+	if t, ok := encodings[encoding]; ok {
+		return t
+	}
+	return encodings[DefaultEncoding]
+}
+
+// LoadDir replaces the built-in encodings with the ones in every <encoding>.tiktoken rank
+// file in dir. Files that are missing are skipped, and loading the same directory
+// again does nothing.
+func LoadDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	registryMutex.RLock()
+	loaded := loadedDir == dir
+	registryMutex.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	for name, split := range splitters {
+		path := filepath.Join(dir, name+".tiktoken")
+		file, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		ranks, err := LoadRanks(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		Register(NewBPE(name, ranks, split))
+	}
+
+	registryMutex.Lock()
+	loadedDir = dir
+	registryMutex.Unlock()
+	return nil
+}
+
+// lookup returns the longest key of table that names model, or "" when none does
+func lookup[V any](model string, table map[string]V) string {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+
+	// Prefer the longest matching key so "gpt-4o" is not counted as "gpt-4"
+	var best string
+	for key := range table {
+		if (name == key || strings.HasPrefix(name, key+"-")) && len(key) > len(best) {
+			best = key
+		}
+	}
+	return best
+}
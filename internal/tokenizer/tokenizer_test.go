@@ -0,0 +1,212 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestSplitCL100K(t *testing.T) {
+	tests := map[string][]string{
+		"Hello world": {"Hello", " world"},
+		"I'm here":    {"I", "'m", " here"},
+		"12345":       {"123", "45"},
+		"foo  bar":    {"foo", " ", " bar"},
+		"a\n\nb":      {"a", "\n\n", "b"},
+		"x = 1;\n":    {"x", " =", " ", "1", ";\n"},
+		"end   ":      {"end", "   "},
+		"HelloWorld":  {"HelloWorld"},
+		"...wait":     {"...", "wait"},
+		"naïve café":  {"naïve", " café"},
+	}
+	for text, want := range tests {
+		if got := splitCL100K(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitCL100K(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestSplitO200K(t *testing.T) {
+	tests := map[string][]string{
+		"Hello world": {"Hello", " world"},
+		"it's":        {"it's"},
+		"camelCase":   {"camel", "Case"},
+		"JSONParser":  {"JSONParser"},
+		"HTTP":        {"HTTP"},
+		"path/to/":    {"path", "/to", "/"},
+		"12345":       {"123", "45"},
+	}
+	for text, want := range tests {
+		if got := splitO200K(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitO200K(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func testRanks() map[string]int {
+	return map[string]int{"a": 0, "b": 1, "c": 2, " ": 3, "ab": 4, "abc": 5, " ab": 6, "d": 7}
+}
+
+func TestBPEMergesByRank(t *testing.T) {
+	bpe := NewBPE("test", testRanks(), splitCL100K)
+
+	if got := bpe.Encode("abc abd"); !reflect.DeepEqual(got, []int{5, 6, 7}) {
+		t.Errorf("Encode = %v, want [5 6 7]", got)
+	}
+	if got := bpe.Count("abc abd"); got != 3 {
+		t.Errorf("Count = %d, want 3", got)
+	}
+	if got := bpe.Encode("e"); !reflect.DeepEqual(got, []int{-1}) {
+		t.Errorf("Encode of an unknown byte = %v, want [-1]", got)
+	}
+}
+
+func writeRanks(t *testing.T, path string, ranks map[string]int) {
+	var lines []string
+	for token, rank := range ranks {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(token)), rank))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadRanksRejectsMalformedLines(t *testing.T) {
+	if _, err := LoadRanks(strings.NewReader("YWI= 4\nYWI=\n")); err == nil {
+		t.Error("Expected an error for a line without a rank")
+	}
+	if _, err := LoadRanks(strings.NewReader("not-base64! 4\n")); err == nil {
+		t.Error("Expected an error for an invalid token")
+	}
+}
+
+func TestLoadDirReplacesEstimates(t *testing.T) {
+	dir := t.TempDir()
+	writeRanks(t, filepath.Join(dir, CL100K+".tiktoken"), testRanks())
+	builtin, o200k := encodings[CL100K], encodings[O200K]
+	t.Cleanup(func() {
+		registryMutex.Lock()
+		encodings[CL100K] = builtin
+		loadedDir = ""
+		registryMutex.Unlock()
+	})
+
+	if err := LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+
+	tk := ForModel("gpt-4-turbo")
+	if tk.Name() != CL100K {
+		t.Fatalf("Expected the loaded %s encoding, got %s", CL100K, tk.Name())
+	}
+	if got := tk.Count("abc abd"); got != 3 {
+		t.Errorf("Count = %d, want 3", got)
+	}
+	// o200k had no rank file and keeps its built-in encoding
+	if ForModel("gpt-4o") != o200k {
+		t.Errorf("Expected the built-in o200k, got %s", ForModel("gpt-4o").Name())
+	}
+}
+
+func TestForModel(t *testing.T) {
+	tests := map[string]string{
+		"openai/gpt-4o-mini":        O200K,
+		"gpt-4.1":                   O200K,
+		"o3-mini":                   O200K,
+		"openai/gpt-4-turbo":        CL100K,
+		"gpt-3.5-turbo-0125":        CL100K,
+		"anthropic/claude-sonnet-4": CL100K,
+		"qwen3:8b":                  CL100K,
+	}
+	for model, encoding := range tests {
+		if name := strings.TrimSuffix(ForModel(model).Name(), " (estimated)"); name != encoding {
+			t.Errorf("ForModel(%q) = %s, want %s", model, name, encoding)
+		}
+	}
+}
+
+func TestBuiltinEncodings(t *testing.T) {
+	var lines []string
+	for token, rank := range testRanks() {
+		lines = append(lines, fmt.Sprintf("%s %d", base64.StdEncoding.EncodeToString([]byte(token)), rank))
+	}
+	fsys := fstest.MapFS{"ranks/" + CL100K + ".tiktoken": {Data: []byte(strings.Join(lines, "\n") + "\n")}}
+
+	builtin := builtinEncodings(fsys)
+	if name := builtin[CL100K].Name(); name != CL100K {
+		t.Fatalf("Expected the embedded %s encoding, got %s", CL100K, name)
+	}
+	if got := builtin[CL100K].Count("abc abd"); got != 3 {
+		t.Errorf("Count = %d, want 3", got)
+	}
+	// Without a rank file the encoding is estimated
+	if name := builtin[O200K].Name(); name != O200K+" (estimated)" {
+		t.Errorf("Expected estimated o200k, got %s", name)
+	}
+}
+
+func TestEmbeddedEncodingsAreExact(t *testing.T) {
+	tk := ForModel("gpt-4o")
+	if _, ok := tk.(*lazyBPE); !ok || tk.Name() != O200K {
+		t.Fatalf("Expected the embedded %s BPE encoding, got %T %s", O200K, tk, tk.Name())
+	}
+	if tk.Count("Hello world") != 2 {
+		t.Errorf("Count = %d, want 2", tk.Count("Hello world"))
+	}
+
+	tk = ForModel("gpt-4-turbo")
+	if _, ok := tk.(*lazyBPE); !ok || tk.Name() != CL100K {
+		t.Fatalf("Expected the embedded %s BPE encoding, got %T %s", CL100K, tk, tk.Name())
+	}
+	if got := tk.Count("tiktoken is great!"); got != 6 {
+		t.Errorf("Count = %d, want 6", got)
+	}
+}
+
+func TestEstimatedCounts(t *testing.T) {
+	tk := &estimator{name: CL100K, split: splitCL100K}
+	tests := map[string]int{
+		"Hello world":                     2,
+		"The quick brown fox jumps over.": 7,
+		"12345":                           2,
+	}
+	for text, want := range tests {
+		if got := tk.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestCountMessagesIncludesToolCalls(t *testing.T) {
+	tk := ForModel("gpt-4o")
+	plain := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleAssistant, Content: "Reading it"}}
+	withCall := []openai.ChatCompletionMessage{{
+		Role:    openai.ChatMessageRoleAssistant,
+		Content: "Reading it",
+		ToolCalls: []openai.ToolCall{{
+			ID:       "call_1",
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: "read_file", Arguments: `{"path":"main.go"}`},
+		}},
+	}}
+
+	base := CountMessages(tk, plain)
+	if want := replyPriming + tokensPerMessage + tk.Count("assistant") + tk.Count("Reading it"); base != want {
+		t.Errorf("CountMessages = %d, want %d", base, want)
+	}
+	if got := CountMessages(tk, withCall); got <= base+tokensPerToolCall {
+		t.Errorf("Expected the tool call to add tokens, got %d vs %d", got, base)
+	}
+
+	tools := []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "read_file", Description: "Read a file"}}}
+	if CountTools(tk, tools) <= tokensPerTool {
+		t.Error("Expected the tool schema to cost tokens")
+	}
+}
@@ -15,6 +15,9 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 )
 
+// waitDelay bounds how long a killed command may take to release its output pipes
+const waitDelay = 5 * time.Second
+
 type ExecTools struct {
 	workspaceRoot string
 }
@@ -111,7 +114,9 @@ func (et *ExecTools) validateWorkDir(workDir string) (string, error) {
 	return absPath, nil
 }
 
-func (et *ExecTools) ExecuteCommand(req ExecRequest) (*ExecResponse, error) {
+// ExecuteCommand runs a command in the workspace. The command is killed, along with any
+// children it started, when ctx is cancelled or the request timeout elapses.
+func (et *ExecTools) ExecuteCommand(ctx context.Context, req ExecRequest) (*ExecResponse, error) {
 	// Validate and resolve work directory
 	workDir, err := et.validateWorkDir(req.WorkDir)
 	if err != nil {
@@ -124,11 +129,14 @@ func (et *ExecTools) ExecuteCommand(req ExecRequest) (*ExecResponse, error) {
 		timeout = 30 * time.Second
 	}
 
-	// Create command with timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	// Create command with a timeout derived from the caller's context
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, req.Command, req.Args...)
+	cmd := exec.CommandContext(cmdCtx, req.Command, req.Args...)
 	cmd.Dir = workDir
+	setProcessGroup(cmd)
+	// Stop waiting for output if a killed command's descendants still hold the pipes open
+	cmd.WaitDelay = waitDelay
 
 	// Set environment variables - start with current environment
 	cmd.Env = os.Environ()
@@ -149,7 +157,11 @@ func (et *ExecTools) ExecuteCommand(req ExecRequest) (*ExecResponse, error) {
 	var timedOut bool
 	
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		if ctx.Err() != nil {
+			// The request itself was cancelled, so there is no one to report output to
+			return nil, ctx.Err()
+		}
+		if cmdCtx.Err() == context.DeadlineExceeded {
 			timedOut = true
 			exitCode = -1
 		} else if exitError, ok := err.(*exec.ExitError); ok {
@@ -184,7 +196,7 @@ func (et *ExecTools) CreateExecHandler() mcp.ToolHandler {
 			return nil, err
 		}
 		
-		result, err := et.ExecuteCommand(req)
+		result, err := et.ExecuteCommand(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -212,14 +224,14 @@ func mapToStruct(m map[string]any, v interface{}) error {
 }
 
 // Executor function for OpenAI tool calling
-func (et *ExecTools) createExecExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (et *ExecTools) createExecExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req ExecRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
 		}
 		
-		result, err := et.ExecuteCommand(req)
+		result, err := et.ExecuteCommand(ctx, req)
 		if err != nil {
 			return "", err
 		}
@@ -0,0 +1,45 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommandCancellation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	et := NewExecTools(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	// The shell's sleep child inherits stdout, so the call only returns promptly if the
+	// whole process group is killed rather than just the shell
+	start := time.Now()
+	_, err := et.ExecuteCommand(ctx, ExecRequest{Command: "sh", Args: []string{"-c", "sleep 30; echo done"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected cancellation to stop the command promptly, took %v", elapsed)
+	}
+}
+
+func TestExecuteCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	et := NewExecTools(t.TempDir())
+	result, err := et.ExecuteCommand(context.Background(), ExecRequest{Command: "sleep", Args: []string{"30"}, Timeout: 1})
+	if err != nil {
+		t.Fatalf("Expected a timed-out result, got error %v", err)
+	}
+	if !result.TimedOut || result.Success {
+		t.Errorf("Expected TimedOut result, got %+v", result)
+	}
+}
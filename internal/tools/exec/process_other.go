@@ -0,0 +1,9 @@
+//go:build !unix
+
+package exec
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups are unavailable; cancellation kills only
+// the command itself
+func setProcessGroup(cmd *exec.Cmd) {}
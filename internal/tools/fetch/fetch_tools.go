@@ -87,7 +87,7 @@ func (ft *FetchTools) Register(registrar interfaces.ToolRegistrar) {
 
 func (ft *FetchTools) CreateFetchHandler() mcp.ToolHandler {
 	return func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
-		result, err := ft.ExecuteFetch(params.Arguments)
+		result, err := ft.ExecuteFetch(ctx, params.Arguments)
 		if err != nil {
 			return nil, err
 		}
@@ -102,7 +102,7 @@ func (ft *FetchTools) CreateFetchHandler() mcp.ToolHandler {
 	}
 }
 
-func (ft *FetchTools) ExecuteFetch(params map[string]interface{}) (string, error) {
+func (ft *FetchTools) ExecuteFetch(ctx context.Context, params map[string]interface{}) (string, error) {
 	var req FetchRequest
 	
 	// Parse URL (required)
@@ -170,7 +170,7 @@ func (ft *FetchTools) ExecuteFetch(params map[string]interface{}) (string, error
 		bodyReader = strings.NewReader(req.Body)
 	}
 	
-	httpReq, err := http.NewRequest(req.Method, req.URL, bodyReader)
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bodyReader)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %v", err)
 	}
@@ -190,6 +190,9 @@ func (ft *FetchTools) ExecuteFetch(params map[string]interface{}) (string, error
 	// Perform request
 	resp, err := ft.client.Do(httpReq)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		response := FetchResponse{
 			URL:     req.URL,
 			Success: false,
@@ -203,6 +206,9 @@ func (ft *FetchTools) ExecuteFetch(params map[string]interface{}) (string, error
 	// Read response body
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		response := FetchResponse{
 			StatusCode: resp.StatusCode,
 			Status:     resp.Status,
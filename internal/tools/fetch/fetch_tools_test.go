@@ -1,10 +1,13 @@
 package fetch
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"net/http"
 	"net/http/httptest"
 	"encoding/json"
+	"time"
 )
 
 func TestFetchTools_ExecuteFetch(t *testing.T) {
@@ -26,7 +29,7 @@ func TestFetchTools_ExecuteFetch(t *testing.T) {
 	}
 
 	// Execute fetch
-	result, err := ft.ExecuteFetch(params)
+	result, err := ft.ExecuteFetch(context.Background(), params)
 	if err != nil {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
@@ -62,7 +65,7 @@ func TestFetchTools_ExecuteFetch_InvalidURL(t *testing.T) {
 		"url": "invalid-url",
 	}
 
-	_, err := ft.ExecuteFetch(params)
+	_, err := ft.ExecuteFetch(context.Background(), params)
 	if err == nil {
 		t.Error("Expected error for invalid URL, got nil")
 	}
@@ -75,8 +78,27 @@ func TestFetchTools_ExecuteFetch_MissingURL(t *testing.T) {
 		"method": "GET",
 	}
 
-	_, err := ft.ExecuteFetch(params)
+	_, err := ft.ExecuteFetch(context.Background(), params)
 	if err == nil {
 		t.Error("Expected error for missing URL, got nil")
 	}
-}
\ No newline at end of file
+}
+func TestFetchTools_ExecuteFetch_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer testServer.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := NewFetchTools().ExecuteFetch(ctx, map[string]interface{}{"url": testServer.URL})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"regexp"
 	"strings"
 
+	"gorka/internal/tools/media"
+
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
@@ -20,7 +22,7 @@ import (
 // ToolRegistrar interface - should match the one in tools package
 type ToolRegistrar interface {
 	RegisterMCPTool(name, description string, handler mcp.ToolHandler, schema *jsonschema.Schema)
-	RegisterOpenAITool(name, description string, schema *jsonschema.Schema, executor func(params map[string]interface{}) (string, error))
+	RegisterOpenAITool(name, description string, schema *jsonschema.Schema, executor func(ctx context.Context, params map[string]interface{}) (string, error))
 }
 
 type FileTools struct {
@@ -39,6 +41,22 @@ type ReadFileResponse struct {
 	FilePath  string `json:"filePath"`
 }
 
+type ViewImageRequest struct {
+	FilePath string `json:"file_path"`
+}
+
+type ViewImageResponse struct {
+	FilePath       string `json:"filePath"`
+	MimeType       string `json:"mimeType"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	OriginalWidth  int    `json:"originalWidth,omitempty"`
+	OriginalHeight int    `json:"originalHeight,omitempty"`
+	SizeBytes      int    `json:"sizeBytes"`
+	Downscaled     bool   `json:"downscaled"`
+	Note           string `json:"note,omitempty"`
+}
+
 type ReplaceStringRequest struct {
 	FilePath  string `json:"file_path"`
 	OldString string `json:"old_string"`
@@ -142,6 +160,17 @@ func (ft *FileTools) Register(registrar ToolRegistrar) {
 		Required: []string{"file_path"},
 	}
 
+	viewImageSchema := &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"file_path": {
+				Type:        "string",
+				Description: "Absolute path to a PNG, JPEG, GIF or WebP image",
+			},
+		},
+		Required: []string{"file_path"},
+	}
+
 	replaceStringSchema := &jsonschema.Schema{
 		Type: "object",
 		Properties: map[string]*jsonschema.Schema{
@@ -229,8 +258,11 @@ func (ft *FileTools) Register(registrar ToolRegistrar) {
 	}
 
 	// Register all tools for both MCP and OpenAI usage
-	registrar.RegisterMCPTool("read_file", "Read file contents with line range support", ft.CreateReadFileHandler(), readFileSchema)
-	registrar.RegisterOpenAITool("read_file", "Read file contents with line range support", readFileSchema, ft.createReadFileExecutor())
+	registrar.RegisterMCPTool("read_file", "Read file contents with line range support; image files are returned as images", ft.CreateReadFileHandler(), readFileSchema)
+	registrar.RegisterOpenAITool("read_file", "Read file contents with line range support; image files are returned as images", readFileSchema, ft.createReadFileExecutor())
+
+	registrar.RegisterMCPTool("view_image", "View an image such as a UI screenshot or diagram", ft.CreateViewImageHandler(), viewImageSchema)
+	registrar.RegisterOpenAITool("view_image", "View an image such as a UI screenshot or diagram", viewImageSchema, ft.createViewImageExecutor())
 
 	registrar.RegisterMCPTool("replace_string_in_file", "Replace string in file with context validation", ft.CreateReplaceStringHandler(), replaceStringSchema)
 	registrar.RegisterOpenAITool("replace_string_in_file", "Replace string in file with context validation", replaceStringSchema, ft.createReplaceStringExecutor())
@@ -343,6 +375,32 @@ func isBinary(file *os.File) (bool, error) {
 	return bytes.Contains(buffer[:n], []byte{0}), nil
 }
 
+// ViewImage loads an image file, downscaled to fit the limits vision models accept
+func (ft *FileTools) ViewImage(req ViewImageRequest) (*ViewImageResponse, *media.Image, error) {
+	validPath, err := ft.validatePath(req.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	img, err := media.Load(validPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response := &ViewImageResponse{
+		FilePath:   req.FilePath,
+		MimeType:   img.MimeType,
+		Width:      img.Width,
+		Height:     img.Height,
+		SizeBytes:  len(img.Data),
+		Downscaled: img.Downscaled,
+	}
+	if img.Downscaled {
+		response.OriginalWidth, response.OriginalHeight = img.OriginalWidth, img.OriginalHeight
+	}
+	return response, img, nil
+}
+
 func (ft *FileTools) ReplaceString(req ReplaceStringRequest) (*ReplaceStringResponse, error) {
 	validPath, err := ft.validatePath(req.FilePath)
 	if err != nil {
@@ -421,7 +479,7 @@ func (ft *FileTools) CreateFile(req CreateFileRequest) (*CreateFileResponse, err
 	}, nil
 }
 
-func (ft *FileTools) GrepSearch(req GrepSearchRequest) (*GrepSearchResponse, error) {
+func (ft *FileTools) GrepSearch(ctx context.Context, req GrepSearchRequest) (*GrepSearchResponse, error) {
 	var pattern *regexp.Regexp
 	var err error
 
@@ -441,6 +499,10 @@ func (ft *FileTools) GrepSearch(req GrepSearchRequest) (*GrepSearchResponse, err
 	totalFound := 0
 
 	err = filepath.WalkDir(ft.workspaceRoot, func(path string, d fs.DirEntry, err error) error {
+		// Stop walking large workspaces once the request is abandoned
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil || d.IsDir() {
 			return nil
 		}
@@ -592,7 +654,7 @@ func (ft *FileTools) shouldSkipFile(relPath string, d fs.DirEntry) bool {
 	return false
 }
 
-func (ft *FileTools) FileSearch(req FileSearchRequest) (*FileSearchResponse, error) {
+func (ft *FileTools) FileSearch(ctx context.Context, req FileSearchRequest) (*FileSearchResponse, error) {
 	maxResults := req.MaxResults
 	if maxResults == 0 {
 		maxResults = 100
@@ -602,6 +664,9 @@ func (ft *FileTools) FileSearch(req FileSearchRequest) (*FileSearchResponse, err
 	totalFound := 0
 
 	err := filepath.WalkDir(ft.workspaceRoot, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		if err != nil || d.IsDir() {
 			return nil
 		}
@@ -691,6 +756,9 @@ func (ft *FileTools) CreateReadFileHandler() mcp.ToolHandler {
 		if err := mapToStruct(params.Arguments, &req); err != nil {
 			return nil, err
 		}
+		if media.IsImagePath(req.FilePath) {
+			return ft.viewImageResult(ViewImageRequest{FilePath: req.FilePath})
+		}
 
 		result, err := ft.ReadFile(req)
 		if err != nil {
@@ -710,6 +778,39 @@ func (ft *FileTools) CreateReadFileHandler() mcp.ToolHandler {
 	}
 }
 
+func (ft *FileTools) CreateViewImageHandler() mcp.ToolHandler {
+	return func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
+		var req ViewImageRequest
+		if err := mapToStruct(params.Arguments, &req); err != nil {
+			return nil, err
+		}
+
+		return ft.viewImageResult(req)
+	}
+}
+
+// viewImageResult returns the image with its details for MCP clients
+func (ft *FileTools) viewImageResult(req ViewImageRequest) (*mcp.CallToolResultFor[any], error) {
+	result, img, err := ft.ViewImage(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return &mcp.CallToolResultFor[any]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(resultJSON),
+			},
+			&mcp.ImageContent{
+				Data:     img.Data,
+				MIMEType: img.MimeType,
+			},
+		},
+		IsError: false,
+	}, nil
+}
+
 func (ft *FileTools) CreateReplaceStringHandler() mcp.ToolHandler {
 	return func(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResultFor[any], error) {
 		var req ReplaceStringRequest
@@ -768,7 +869,7 @@ func (ft *FileTools) CreateGrepSearchHandler() mcp.ToolHandler {
 			return nil, err
 		}
 
-		result, err := ft.GrepSearch(req)
+		result, err := ft.GrepSearch(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -794,7 +895,7 @@ func (ft *FileTools) CreateFileSearchHandler() mcp.ToolHandler {
 			return nil, err
 		}
 
-		result, err := ft.FileSearch(req)
+		result, err := ft.FileSearch(ctx, req)
 		if err != nil {
 			return nil, err
 		}
@@ -848,12 +949,15 @@ func mapToStruct(m map[string]any, v interface{}) error {
 }
 
 // Executor functions for OpenAI tool calling
-func (ft *FileTools) createReadFileExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (ft *FileTools) createReadFileExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req ReadFileRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
 		}
+		if media.IsImagePath(req.FilePath) {
+			return ft.attachImage(ctx, ViewImageRequest{FilePath: req.FilePath})
+		}
 
 		result, err := ft.ReadFile(req)
 		if err != nil {
@@ -864,8 +968,36 @@ func (ft *FileTools) createReadFileExecutor() func(params map[string]interface{}
 	}
 }
 
-func (ft *FileTools) createReplaceStringExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (ft *FileTools) createViewImageExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
+		var req ViewImageRequest
+		if err := mapToStruct(params, &req); err != nil {
+			return "", err
+		}
+
+		return ft.attachImage(ctx, req)
+	}
+}
+
+// attachImage hands the image to the agent running the tool and describes it in the result
+func (ft *FileTools) attachImage(ctx context.Context, req ViewImageRequest) (string, error) {
+	result, img, err := ft.ViewImage(req)
+	if err != nil {
+		return "", err
+	}
+
+	if media.Attach(ctx, img) {
+		result.Note = "The image is attached in the next message"
+	} else {
+		result.Note = "The image cannot be shown: the current model does not accept image input"
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return string(resultJSON), nil
+}
+
+func (ft *FileTools) createReplaceStringExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req ReplaceStringRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
@@ -880,8 +1012,8 @@ func (ft *FileTools) createReplaceStringExecutor() func(params map[string]interf
 	}
 }
 
-func (ft *FileTools) createCreateFileExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (ft *FileTools) createCreateFileExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req CreateFileRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
@@ -901,14 +1033,14 @@ func (ft *FileTools) createCreateFileExecutor() func(params map[string]interface
 	}
 }
 
-func (ft *FileTools) createGrepSearchExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (ft *FileTools) createGrepSearchExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req GrepSearchRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
 		}
 
-		result, err := ft.GrepSearch(req)
+		result, err := ft.GrepSearch(ctx, req)
 		if err != nil {
 			return "", err
 		}
@@ -918,14 +1050,14 @@ func (ft *FileTools) createGrepSearchExecutor() func(params map[string]interface
 	}
 }
 
-func (ft *FileTools) createFileSearchExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (ft *FileTools) createFileSearchExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req FileSearchRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
 		}
 
-		result, err := ft.FileSearch(req)
+		result, err := ft.FileSearch(ctx, req)
 		if err != nil {
 			return "", err
 		}
@@ -935,8 +1067,8 @@ func (ft *FileTools) createFileSearchExecutor() func(params map[string]interface
 	}
 }
 
-func (ft *FileTools) createListDirExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (ft *FileTools) createListDirExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req ListDirRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
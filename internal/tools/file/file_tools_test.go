@@ -1,10 +1,16 @@
 package file
 
 import (
+	"context"
+	"errors"
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"gorka/internal/tools/media"
 )
 
 func TestGrepSearchBinaryProtection(t *testing.T) {
@@ -39,7 +45,7 @@ func TestGrepSearchBinaryProtection(t *testing.T) {
 		MaxResults: 10,
 	}
 
-	result, err := ft.GrepSearch(req)
+	result, err := ft.GrepSearch(context.Background(), req)
 	if err != nil {
 		t.Fatalf("GrepSearch failed: %v", err)
 	}
@@ -120,4 +126,55 @@ func TestReplaceStringBinaryProtection(t *testing.T) {
 	if err != nil && !strings.Contains(err.Error(), "cannot replace string in binary file") {
 		t.Errorf("Expected binary file error, got: %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestSearchStopsWhenCancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("Hello"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	ft := NewFileTools(tmpDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ft.GrepSearch(ctx, GrepSearchRequest{Query: "Hello"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected GrepSearch to return context.Canceled, got %v", err)
+	}
+	if _, err := ft.FileSearch(ctx, FileSearchRequest{Query: "*.txt"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected FileSearch to return context.Canceled, got %v", err)
+	}
+}
+
+func TestReadFileAttachesImages(t *testing.T) {
+	tmpDir := t.TempDir()
+	file, err := os.Create(filepath.Join(tmpDir, "shot.png"))
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	png.Encode(file, image.NewRGBA(image.Rect(0, 0, 4, 2)))
+	file.Close()
+
+	ft := NewFileTools(tmpDir)
+	readFile := ft.createReadFileExecutor()
+	params := map[string]interface{}{"file_path": "shot.png"}
+
+	result, err := readFile(context.Background(), params)
+	if err != nil {
+		t.Fatalf("read_file failed: %v", err)
+	}
+	if !strings.Contains(result, "cannot be shown") {
+		t.Errorf("Expected a note that the image cannot be shown, got %s", result)
+	}
+
+	ctx, collector := media.WithCollector(context.Background())
+	result, err = readFile(ctx, params)
+	if err != nil {
+		t.Fatalf("read_file failed: %v", err)
+	}
+	if !strings.Contains(result, `"width":4`) || !strings.Contains(result, "attached") {
+		t.Errorf("Unexpected result %s", result)
+	}
+	if images := collector.Images(); len(images) != 1 || images[0].MimeType != "image/png" {
+		t.Errorf("Expected one attached PNG, got %+v", images)
+	}
+}
@@ -546,8 +546,8 @@ func mapToStruct(m map[string]any, v interface{}) error {
 }
 
 // Executor functions for OpenAI tool calling
-func (kt *KnowledgeTools) createCreateEntitiesExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (kt *KnowledgeTools) createCreateEntitiesExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req CreateEntitiesRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
@@ -563,8 +563,8 @@ func (kt *KnowledgeTools) createCreateEntitiesExecutor() func(params map[string]
 	}
 }
 
-func (kt *KnowledgeTools) createSearchNodesExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (kt *KnowledgeTools) createSearchNodesExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req SearchNodesRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
@@ -580,8 +580,8 @@ func (kt *KnowledgeTools) createSearchNodesExecutor() func(params map[string]int
 	}
 }
 
-func (kt *KnowledgeTools) createCreateRelationsExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (kt *KnowledgeTools) createCreateRelationsExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req CreateRelationsRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
@@ -597,8 +597,8 @@ func (kt *KnowledgeTools) createCreateRelationsExecutor() func(params map[string
 	}
 }
 
-func (kt *KnowledgeTools) createAddObservationsExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (kt *KnowledgeTools) createAddObservationsExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req AddObservationsRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
@@ -613,8 +613,8 @@ func (kt *KnowledgeTools) createAddObservationsExecutor() func(params map[string
 	}
 }
 
-func (kt *KnowledgeTools) createReadGraphExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (kt *KnowledgeTools) createReadGraphExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		result, err := kt.ReadGraph()
 		if err != nil {
 			return "", err
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 
@@ -29,7 +30,7 @@ type ToolsManager struct {
 	openaiTools    []openai.Tool
 	
 	// Registry for OpenAI tool execution
-	openaiExecutors map[string]func(params map[string]interface{}) (string, error)
+	openaiExecutors map[string]func(ctx context.Context, params map[string]interface{}) (string, error)
 }
 
 // Compile-time check that ToolsManager implements interfaces.ToolRegistrar
@@ -53,7 +54,7 @@ func NewToolsManager(workspaceRoot string, storageDir string) *ToolsManager {
 		fetchTools:     fetch.NewFetchTools(),
 		mcpTools:       []MCPToolEntry{},
 		openaiTools:    []openai.Tool{},
-		openaiExecutors: make(map[string]func(params map[string]interface{}) (string, error)),
+		openaiExecutors: make(map[string]func(ctx context.Context, params map[string]interface{}) (string, error)),
 	}
 
 	// Register all tools
@@ -128,7 +129,7 @@ func (tm *ToolsManager) RegisterMCPTool(name, description string, handler mcp.To
 }
 
 // RegisterOpenAITool registers a tool for OpenAI/OpenRouter usage
-func (tm *ToolsManager) RegisterOpenAITool(name, description string, schema *jsonschema.Schema, executor func(params map[string]interface{}) (string, error)) {
+func (tm *ToolsManager) RegisterOpenAITool(name, description string, schema *jsonschema.Schema, executor func(ctx context.Context, params map[string]interface{}) (string, error)) {
 	// Check if tool is already registered to prevent duplicates
 	for _, tool := range tm.openaiTools {
 		if tool.Function.Name == name {
@@ -157,7 +158,7 @@ func (tm *ToolsManager) GetOpenAITools() []openai.Tool {
 }
 
 // ExecuteOpenAITool executes an OpenAI tool by name using the registered executor
-func (tm *ToolsManager) ExecuteOpenAITool(name string, params map[string]interface{}) (string, error) {
+func (tm *ToolsManager) ExecuteOpenAITool(ctx context.Context, name string, params map[string]interface{}) (string, error) {
 	executor, exists := tm.openaiExecutors[name]
 	if !exists {
 		availableTools := func() []string {
@@ -169,6 +170,11 @@ func (tm *ToolsManager) ExecuteOpenAITool(name string, params map[string]interfa
 		}()
 		return "", fmt.Errorf("unknown tool: %s (available: %v)", name, availableTools)
 	}
-	
-	return executor(params)
+
+	// Don't start new work for a request that has already been cancelled
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return executor(ctx, params)
 }
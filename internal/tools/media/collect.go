@@ -0,0 +1,39 @@
+package media
+
+import (
+	"context"
+	"sync"
+)
+
+type collectorKey struct{}
+
+// Collector gathers the images tools attach while a tool call runs
+type Collector struct {
+	mutex  sync.Mutex
+	images []*Image
+}
+
+// WithCollector returns a context whose tool calls may attach images to the returned collector
+func WithCollector(ctx context.Context) (context.Context, *Collector) {
+	collector := &Collector{}
+	return context.WithValue(ctx, collectorKey{}, collector), collector
+}
+
+// Attach hands an image to the caller; it reports false when the caller cannot show images
+func Attach(ctx context.Context, img *Image) bool {
+	collector, ok := ctx.Value(collectorKey{}).(*Collector)
+	if !ok {
+		return false
+	}
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.images = append(collector.images, img)
+	return true
+}
+
+// Images returns the images attached so far
+func (c *Collector) Images() []*Image {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.images
+}
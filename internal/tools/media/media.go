@@ -0,0 +1,217 @@
+package media
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Limits applied to images before they are sent to a model
+const (
+	// MaxFileSize is the largest image file that will be read
+	MaxFileSize = 20 << 20
+	// MaxDimension caps the longer side; larger images are downscaled to it
+	MaxDimension = 1568
+	// MaxEncodedSize caps the bytes sent per image, leaving room under the 5MB providers accept once base64 encoded
+	MaxEncodedSize = 3 << 20
+)
+
+// imageTypes maps the supported file extensions to their MIME types
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// Image is a picture prepared for a vision model
+type Image struct {
+	Path           string
+	MimeType       string
+	Data           []byte
+	Width          int
+	Height         int
+	OriginalWidth  int
+	OriginalHeight int
+	Downscaled     bool
+}
+
+// DataURL returns the image as a base64 data URL
+func (img *Image) DataURL() string {
+	return "data:" + img.MimeType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// IsImagePath reports whether path has a supported image extension
+func IsImagePath(path string) bool {
+	_, ok := imageTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// Load reads an image file and downscales it to fit the limits
+func Load(path string) (*Image, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > MaxFileSize {
+		return nil, fmt.Errorf("image is too large: %d bytes (limit %d)", info.Size(), MaxFileSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	img := &Image{Path: path, MimeType: http.DetectContentType(data), Data: data}
+	switch img.MimeType {
+	case "image/png", "image/jpeg", "image/gif":
+	case "image/webp":
+		return img, fitWebP(img)
+	default:
+		return nil, fmt.Errorf("unsupported image format %s: %s (PNG, JPEG, GIF and WebP are supported)", img.MimeType, path)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %s: %w", path, err)
+	}
+	img.Width, img.Height = config.Width, config.Height
+	img.OriginalWidth, img.OriginalHeight = config.Width, config.Height
+	if img.Width <= MaxDimension && img.Height <= MaxDimension && len(data) <= MaxEncodedSize {
+		return img, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %w", path, err)
+	}
+	return img, shrink(img, decoded)
+}
+
+// shrink replaces the image data with a downscaled copy. Photos stay JPEG, everything else
+// becomes PNG unless that is still too large.
+func shrink(img *Image, decoded image.Image) error {
+	scaled := downscale(decoded, MaxDimension)
+	img.Width, img.Height = scaled.Bounds().Dx(), scaled.Bounds().Dy()
+	img.Downscaled = true
+
+	var buf bytes.Buffer
+	if img.MimeType != "image/jpeg" {
+		if err := png.Encode(&buf, scaled); err != nil {
+			return fmt.Errorf("failed to encode image: %w", err)
+		}
+		if buf.Len() <= MaxEncodedSize {
+			img.MimeType, img.Data = "image/png", buf.Bytes()
+			return nil
+		}
+		buf.Reset()
+	}
+
+	if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 85}); err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+	if buf.Len() > MaxEncodedSize {
+		return fmt.Errorf("image is still %d bytes after downscaling (limit %d)", buf.Len(), MaxEncodedSize)
+	}
+	img.MimeType, img.Data = "image/jpeg", buf.Bytes()
+	return nil
+}
+
+// downscale shrinks src so its longer side is maxDimension, averaging the pixels each output
+// pixel covers
+func downscale(src image.Image, maxDimension int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := float64(maxDimension) / float64(max(width, height))
+	if scale > 1 {
+		scale = 1
+	}
+	dstWidth := max(1, int(float64(width)*scale+0.5))
+	dstHeight := max(1, int(float64(height)*scale+0.5))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0, y1 := y*height/dstHeight, max((y+1)*height/dstHeight, y*height/dstHeight+1)
+		for x := 0; x < dstWidth; x++ {
+			x0, x1 := x*width/dstWidth, max((x+1)*width/dstWidth, x*width/dstWidth+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			pixel := dst.Pix[y*dst.Stride+x*4:]
+			for c := 0; c < 4; c++ {
+				pixel[c] = uint8(sum[c] / count)
+			}
+		}
+	}
+	return dst
+}
+
+// fitWebP checks a WebP image against the limits. The standard library cannot decode WebP,
+// so images over the limits are rejected instead of downscaled.
+func fitWebP(img *Image) error {
+	width, height, err := webpSize(img.Data)
+	if err != nil {
+		return fmt.Errorf("failed to read image %s: %w", img.Path, err)
+	}
+	img.Width, img.Height = width, height
+	img.OriginalWidth, img.OriginalHeight = width, height
+	if width > MaxDimension || height > MaxDimension || len(img.Data) > MaxEncodedSize {
+		return fmt.Errorf("WebP image %s is %dx%d and %d bytes; WebP images over %dpx or %d bytes cannot be downscaled, convert it to PNG or JPEG",
+			img.Path, width, height, len(img.Data), MaxDimension, MaxEncodedSize)
+	}
+	return nil
+}
+
+// webpSize reads the canvas size from the first chunk of a WebP file
+func webpSize(data []byte) (int, int, error) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, errors.New("not a WebP file")
+	}
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8X":
+		// 24-bit canvas width and height minus one, after four bytes of flags
+		width := 1 + (int(chunk[4]) | int(chunk[5])<<8 | int(chunk[6])<<16)
+		height := 1 + (int(chunk[7]) | int(chunk[8])<<8 | int(chunk[9])<<16)
+		return width, height, nil
+	case "VP8 ":
+		// Lossy: a frame tag and start code precede 14-bit width and height
+		if chunk[3] != 0x9d || chunk[4] != 0x01 || chunk[5] != 0x2a {
+			return 0, 0, errors.New("invalid VP8 start code")
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		// Lossless: a signature byte precedes 14-bit width and height minus one
+		if chunk[0] != 0x2f {
+			return 0, 0, errors.New("invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, nil
+	}
+	return 0, 0, fmt.Errorf("unknown WebP chunk %q", data[12:16])
+}
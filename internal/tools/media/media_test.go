@@ -0,0 +1,107 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePNG(t *testing.T, path string, width, height int) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: uint8(x), A: 255})
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeepsSmallImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.png")
+	writePNG(t, path, 40, 20)
+	original, _ := os.ReadFile(path)
+
+	img, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if img.Downscaled || img.Width != 40 || img.Height != 20 {
+		t.Errorf("Expected an untouched 40x20 image, got %dx%d (downscaled %v)", img.Width, img.Height, img.Downscaled)
+	}
+	if string(img.Data) != string(original) {
+		t.Error("Expected the original bytes to be kept")
+	}
+}
+
+func TestLoadDownscalesLargeImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wide.png")
+	writePNG(t, path, 3000, 100)
+
+	img, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !img.Downscaled || img.Width != MaxDimension || img.Height != 52 {
+		t.Errorf("Expected %dx52, got %dx%d (downscaled %v)", MaxDimension, img.Width, img.Height, img.Downscaled)
+	}
+	if img.OriginalWidth != 3000 || img.OriginalHeight != 100 {
+		t.Errorf("Expected the original size to be kept, got %dx%d", img.OriginalWidth, img.OriginalHeight)
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil || config.Width != img.Width {
+		t.Errorf("Expected the data to be the downscaled PNG, got %+v (%v)", config, err)
+	}
+}
+
+func TestLoadRejectsNonImages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.png")
+	if err := os.WriteFile(path, []byte("just some text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for a file that is not an image")
+	}
+}
+
+func TestWebPSize(t *testing.T) {
+	data := make([]byte, 30)
+	copy(data, "RIFF")
+	copy(data[8:], "WEBPVP8L")
+	data[20] = 0x2f
+	binary.LittleEndian.PutUint32(data[21:], uint32(640-1)|uint32(480-1)<<14)
+
+	width, height, err := webpSize(data)
+	if err != nil || width != 640 || height != 480 {
+		t.Errorf("webpSize = %dx%d (%v), want 640x480", width, height, err)
+	}
+
+	if _, _, err := webpSize([]byte("RIFF")); err == nil {
+		t.Error("Expected an error for a truncated file")
+	}
+}
+
+func TestAttachNeedsCollector(t *testing.T) {
+	img := &Image{Path: "a.png", MimeType: "image/png"}
+	if Attach(context.Background(), img) {
+		t.Error("Expected Attach to fail without a collector")
+	}
+
+	ctx, collector := WithCollector(context.Background())
+	if !Attach(ctx, img) {
+		t.Fatal("Expected Attach to succeed with a collector")
+	}
+	if images := collector.Images(); len(images) != 1 || images[0] != img {
+		t.Errorf("Expected the attached image, got %v", images)
+	}
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
@@ -14,7 +15,7 @@ type ToolDefinition struct {
 	Description string
 	Schema      *jsonschema.Schema
 	MCPHandler  mcp.ToolHandler
-	OpenAIFunc  func(params map[string]interface{}) (string, error)
+	OpenAIFunc  func(ctx context.Context, params map[string]interface{}) (string, error)
 }
 
 // ToolRegistry manages centralized tool definitions
@@ -85,11 +86,11 @@ func (r *ToolRegistry) GetOpenAITools() []openai.Tool {
 }
 
 // ExecuteOpenAITool executes a tool by name with OpenAI-formatted parameters
-func (r *ToolRegistry) ExecuteOpenAITool(name string, params map[string]interface{}) (string, error) {
+func (r *ToolRegistry) ExecuteOpenAITool(ctx context.Context, name string, params map[string]interface{}) (string, error) {
 	tool, exists := r.tools[name]
 	if !exists {
 		return "", fmt.Errorf("unknown tool: %s", name)
 	}
 
-	return tool.OpenAIFunc(params)
+	return tool.OpenAIFunc(ctx, params)
 }
\ No newline at end of file
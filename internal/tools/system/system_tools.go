@@ -88,8 +88,8 @@ func getEnvOrDefault(key, defaultValue string) string {
 }
 
 // Executor function for OpenAI tool calling
-func (st *SystemTools) createGetSystemInfoExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (st *SystemTools) createGetSystemInfoExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		info := SystemInfo{
 			Version:     version.GetInfo(),
 			ActiveModel: st.activeModel,
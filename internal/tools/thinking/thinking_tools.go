@@ -366,8 +366,8 @@ func mapToStruct(m map[string]any, v interface{}) error {
 }
 
 // Executor function for OpenAI tool calling
-func (tt *ThinkingTools) createThinkHardExecutor() func(params map[string]interface{}) (string, error) {
-	return func(params map[string]interface{}) (string, error) {
+func (tt *ThinkingTools) createThinkHardExecutor() func(ctx context.Context, params map[string]interface{}) (string, error) {
+	return func(ctx context.Context, params map[string]interface{}) (string, error) {
 		var req ThinkingRequest
 		if err := mapToStruct(params, &req); err != nil {
 			return "", err
@@ -3,6 +3,7 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/modelcontextprotocol/go-sdk/jsonschema"
@@ -15,6 +16,58 @@ type BehavioralMatrix struct {
 	VSCodeMode string                 `json:"vscode_chatmode"`
 	Keywords   []string               `json:"keywords,omitempty"` // Keywords for content-based agent selection
 	Algorithm  map[string]interface{} `json:"algorithm"`
+	LLM        *LLMSettings           `json:"llm,omitempty"` // Optional per-agent model and sampling overrides
+}
+
+// LLMSettings holds model selection and sampling parameters for an agent
+type LLMSettings struct {
+	Model           string   `json:"model,omitempty"`
+	FallbackModels  []string `json:"fallback_models,omitempty"`
+	Temperature     *float32 `json:"temperature,omitempty"`
+	TopP            *float32 `json:"top_p,omitempty"`
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+	// StructuredOutputs forces response_format json_schema on or off instead of detecting support per model
+	StructuredOutputs *bool `json:"structured_outputs,omitempty"`
+	// Vision forces image input on or off instead of detecting support per model
+	Vision *bool `json:"vision,omitempty"`
+}
+
+// Merge returns a copy of the settings with every field set in override applied on top
+func (s *LLMSettings) Merge(override *LLMSettings) *LLMSettings {
+	merged := &LLMSettings{}
+	if s != nil {
+		*merged = *s
+	}
+	if override == nil {
+		return merged
+	}
+
+	if override.Model != "" {
+		merged.Model = override.Model
+	}
+	if len(override.FallbackModels) > 0 {
+		merged.FallbackModels = override.FallbackModels
+	}
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		merged.MaxTokens = override.MaxTokens
+	}
+	if override.ReasoningEffort != "" {
+		merged.ReasoningEffort = override.ReasoningEffort
+	}
+	if override.StructuredOutputs != nil {
+		merged.StructuredOutputs = override.StructuredOutputs
+	}
+	if override.Vision != nil {
+		merged.Vision = override.Vision
+	}
+	return merged
 }
 
 // TaskContext represents the execution context for behavioral processing
@@ -28,6 +81,8 @@ type BehavioralRequest struct {
 	AgentID          string                 `json:"agent_id"`
 	InputParameters  map[string]interface{} `json:"input_parameters"`
 	ExecutionContext map[string]interface{} `json:"execution_context"`
+	// SessionID continues an earlier session of the same agent instead of starting a new one
+	SessionID string `json:"session_id,omitempty"`
 }
 
 // BehavioralResult represents the result of behavioral matrix execution
@@ -36,6 +91,20 @@ type BehavioralResult struct {
 	OutputData    map[string]interface{} `json:"output_data"`
 	ExecutionMeta map[string]interface{} `json:"execution_metadata"`
 	QualityScore  float64                `json:"quality_score"`
+	// ToolCalls lists every tool call the agent made, in the order they were made
+	ToolCalls []ToolCallRecord `json:"tool_calls,omitempty"`
+}
+
+// ToolCallRecord describes one tool call made during an agent run
+type ToolCallRecord struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	// Mode is how the call was requested: openai_tools, adapter_tools or xml_tools
+	Mode       string `json:"mode"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	// ResultSize is the length of the tool output in bytes
+	ResultSize int `json:"result_size"`
 }
 
 // BuildSystemPrompt builds a system prompt for behavioral execution
@@ -232,6 +301,37 @@ func ExtractInputSchema(matrix *BehavioralMatrix) (*jsonschema.Schema, error) {
 	}, nil
 }
 
+// ExtractOutputSchema converts algorithm.output definition to JSON Schema.
+// It returns a nil schema when the matrix declares no output.
+func ExtractOutputSchema(matrix *BehavioralMatrix) (*jsonschema.Schema, error) {
+	algorithm := matrix.Algorithm
+
+	// Try to get output schema from behavioral_prompt first (project-orchestrator format)
+	if behavioralPrompt, ok := algorithm["behavioral_prompt"].(map[string]interface{}); ok {
+		if outputSchema, ok := behavioralPrompt["output_schema"].(map[string]interface{}); ok {
+			return convertMapToJSONSchema(outputSchema, true)
+		}
+	}
+
+	output, ok := algorithm["output"].(map[string]interface{})
+	if !ok || len(output) == 0 {
+		return nil, nil
+	}
+
+	schema, err := convertMapToJSONSchema(output, false)
+	if err != nil {
+		return nil, err
+	}
+	for fieldName, fieldSchema := range schema.Properties {
+		// Output arrays hold findings and other objects, not just the strings inputs take
+		if fieldSchema.Type == "array" {
+			fieldSchema.Items = nil
+		}
+		fieldSchema.Description = fmt.Sprintf("%s output", strings.ReplaceAll(fieldName, "_", " "))
+	}
+	return schema, nil
+}
+
 // convertMapToJSONSchema converts input definition map to JSON Schema
 func convertMapToJSONSchema(inputDef map[string]interface{}, isDetailed bool) (*jsonschema.Schema, error) {
 	schema := &jsonschema.Schema{
@@ -248,6 +348,8 @@ func convertMapToJSONSchema(inputDef map[string]interface{}, isDetailed bool) (*
 			schema.Required = append(schema.Required, fieldName)
 		}
 	}
+	// Keep the schema stable across runs, since it is sent to the model and hashed by cassettes
+	sort.Strings(schema.Required)
 
 	return schema, nil
 }
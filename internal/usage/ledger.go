@@ -0,0 +1,377 @@
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is matched by every BudgetError
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetError reports which budget cap stopped the run
+type BudgetError struct {
+	Scope string // "run" or "daily"
+	Limit float64
+	Spent float64
+}
+
+// Error implements the error interface
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s budget exceeded: spent $%.4f of $%.4f", e.Scope, e.Spent, e.Limit)
+}
+
+// Is lets errors.Is match ErrBudgetExceeded
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// Tokens counts the tokens consumed by one or more LLM calls
+type Tokens struct {
+	Prompt     int `json:"prompt_tokens"`
+	Completion int `json:"completion_tokens"`
+	Reasoning  int `json:"reasoning_tokens,omitempty"`
+	Total      int `json:"total_tokens"`
+}
+
+// Add accumulates other into t
+func (t *Tokens) Add(other Tokens) {
+	t.Prompt += other.Prompt
+	t.Completion += other.Completion
+	t.Reasoning += other.Reasoning
+	t.Total += other.Total
+}
+
+// Totals aggregates calls, tokens and cost
+type Totals struct {
+	Calls         int     `json:"calls"`
+	Tokens        Tokens  `json:"tokens"`
+	CostUSD       float64 `json:"cost_usd"`
+	UnpricedCalls int     `json:"unpriced_calls,omitempty"`
+}
+
+// Add accumulates one recorded call into t
+func (t *Totals) Add(entry Entry) {
+	t.Calls++
+	t.Tokens.Add(entry.Tokens)
+	t.CostUSD += entry.CostUSD
+	if !entry.Priced {
+		t.UnpricedCalls++
+	}
+}
+
+// Entry is one LLM call as persisted in the ledger
+type Entry struct {
+	Timestamp time.Time `json:"timestamp"`
+	RunID     string    `json:"run_id"`
+	SessionID string    `json:"session_id"`
+	AgentID   string    `json:"agent_id"`
+	Model     string    `json:"model"`
+	Tokens    Tokens    `json:"tokens"`
+	CostUSD   float64   `json:"cost_usd"`
+	Priced    bool      `json:"priced"`
+}
+
+// Run aggregates usage for one orchestration run, including every sub-agent it spawns
+type Run struct {
+	ID        string
+	AgentID   string
+	StartedAt time.Time
+
+	mu        sync.Mutex
+	totals    Totals
+	byAgent   map[string]*Totals
+	bySession map[string]*Totals
+	byModel   map[string]*Totals
+}
+
+// RunSummary is the JSON view of a run's usage
+type RunSummary struct {
+	RunID     string            `json:"run_id"`
+	AgentID   string            `json:"agent_id"`
+	Totals    Totals            `json:"totals"`
+	ByAgent   map[string]Totals `json:"by_agent"`
+	BySession map[string]Totals `json:"by_session"`
+	ByModel   map[string]Totals `json:"by_model"`
+	Budget    *BudgetSummary    `json:"budget,omitempty"`
+}
+
+// BudgetSummary reports configured caps alongside current spend
+type BudgetSummary struct {
+	RunLimitUSD   float64 `json:"run_limit_usd,omitempty"`
+	DailyLimitUSD float64 `json:"daily_limit_usd,omitempty"`
+	DailySpentUSD float64 `json:"daily_spent_usd"`
+}
+
+func (r *Run) record(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.totals.Add(entry)
+	addTo(r.byAgent, entry.AgentID, entry)
+	addTo(r.bySession, entry.SessionID, entry)
+	addTo(r.byModel, entry.Model, entry)
+}
+
+func addTo(buckets map[string]*Totals, key string, entry Entry) {
+	totals, ok := buckets[key]
+	if !ok {
+		totals = &Totals{}
+		buckets[key] = totals
+	}
+	totals.Add(entry)
+}
+
+// Totals returns the run-wide totals so far
+func (r *Run) Totals() Totals {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.totals
+}
+
+// SessionTotals returns the totals recorded for one agent session
+func (r *Run) SessionTotals(sessionID string) Totals {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if totals, ok := r.bySession[sessionID]; ok {
+		return *totals
+	}
+	return Totals{}
+}
+
+// Summary returns a snapshot of the run's usage
+func (r *Run) Summary() RunSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return RunSummary{
+		RunID:     r.ID,
+		AgentID:   r.AgentID,
+		Totals:    r.totals,
+		ByAgent:   snapshot(r.byAgent),
+		BySession: snapshot(r.bySession),
+		ByModel:   snapshot(r.byModel),
+	}
+}
+
+func snapshot(buckets map[string]*Totals) map[string]Totals {
+	copied := make(map[string]Totals, len(buckets))
+	for key, totals := range buckets {
+		copied[key] = *totals
+	}
+	return copied
+}
+
+type runKey struct{}
+
+// WithRun attaches a run to ctx so nested agent executions are charged to it
+func WithRun(ctx context.Context, run *Run) context.Context {
+	return context.WithValue(ctx, runKey{}, run)
+}
+
+// RunFromContext returns the run attached to ctx, if any
+func RunFromContext(ctx context.Context) *Run {
+	run, _ := ctx.Value(runKey{}).(*Run)
+	return run
+}
+
+// Ledger records LLM usage to .gorka/usage and enforces budget caps
+type Ledger struct {
+	dir            string
+	prices         PriceTable
+	runBudgetUSD   float64
+	dailyBudgetUSD float64
+
+	mu         sync.Mutex
+	day        string
+	dailySpent float64
+	runCounter int64
+	now        func() time.Time
+}
+
+// NewLedger creates a ledger that appends to one JSONL file per day under dir; a zero budget
+// disables that cap. Spend already recorded today is loaded so the daily cap survives restarts.
+func NewLedger(dir string, prices PriceTable, runBudgetUSD, dailyBudgetUSD float64) (*Ledger, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	ledger := &Ledger{
+		dir:            dir,
+		prices:         prices,
+		runBudgetUSD:   runBudgetUSD,
+		dailyBudgetUSD: dailyBudgetUSD,
+		now:            time.Now,
+	}
+	ledger.day = ledger.now().Format("2006-01-02")
+
+	spent, err := ledger.loadDailySpend(ledger.day)
+	if err != nil {
+		return nil, err
+	}
+	ledger.dailySpent = spent
+
+	return ledger, nil
+}
+
+// NewRun starts a new orchestration run rooted at agentID
+func (l *Ledger) NewRun(agentID string) *Run {
+	l.mu.Lock()
+	l.runCounter++
+	counter := l.runCounter
+	l.mu.Unlock()
+
+	started := l.now()
+	return &Run{
+		ID:        fmt.Sprintf("run_%d_%d", started.UnixNano(), counter),
+		AgentID:   agentID,
+		StartedAt: started,
+		byAgent:   make(map[string]*Totals),
+		bySession: make(map[string]*Totals),
+		byModel:   make(map[string]*Totals),
+	}
+}
+
+// Record prices and persists one LLM call, charging it to run when one is given
+func (l *Ledger) Record(run *Run, sessionID, agentID, model string, tokens Tokens) (Entry, error) {
+	cost, priced := l.prices.Cost(model, tokens)
+	entry := Entry{
+		Timestamp: l.now(),
+		SessionID: sessionID,
+		AgentID:   agentID,
+		Model:     model,
+		Tokens:    tokens,
+		CostUSD:   cost,
+		Priced:    priced,
+	}
+	if run != nil {
+		entry.RunID = run.ID
+		run.record(entry)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := entry.Timestamp.Format("2006-01-02")
+	if day != l.day {
+		l.day = day
+		l.dailySpent = 0
+	}
+	l.dailySpent += cost
+
+	return entry, l.appendEntry(day, entry)
+}
+
+// CheckBudget returns a BudgetError once the run or today's spend has reached its cap
+func (l *Ledger) CheckBudget(run *Run) error {
+	if run != nil && l.runBudgetUSD > 0 {
+		if spent := run.Totals().CostUSD; spent >= l.runBudgetUSD {
+			return &BudgetError{Scope: "run", Limit: l.runBudgetUSD, Spent: spent}
+		}
+	}
+
+	if l.dailyBudgetUSD > 0 {
+		if spent := l.DailySpent(); spent >= l.dailyBudgetUSD {
+			return &BudgetError{Scope: "daily", Limit: l.dailyBudgetUSD, Spent: spent}
+		}
+	}
+	return nil
+}
+
+// DailySpent returns today's recorded spend in USD
+func (l *Ledger) DailySpent() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if day := l.now().Format("2006-01-02"); day != l.day {
+		l.day = day
+		l.dailySpent = 0
+	}
+	return l.dailySpent
+}
+
+// Summarize returns the run summary together with the configured budgets
+func (l *Ledger) Summarize(run *Run) RunSummary {
+	summary := run.Summary()
+	if l.runBudgetUSD > 0 || l.dailyBudgetUSD > 0 {
+		summary.Budget = &BudgetSummary{
+			RunLimitUSD:   l.runBudgetUSD,
+			DailyLimitUSD: l.dailyBudgetUSD,
+			DailySpentUSD: l.DailySpent(),
+		}
+	}
+	return summary
+}
+
+// Entries returns every call recorded on the given day (YYYY-MM-DD), oldest first
+func (l *Ledger) Entries(day string) ([]Entry, error) {
+	file, err := os.Open(l.dayFile(day))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip a line truncated by a crash rather than losing the whole day
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, nil
+}
+
+func (l *Ledger) loadDailySpend(day string) (float64, error) {
+	entries, err := l.Entries(day)
+	if err != nil {
+		return 0, err
+	}
+	var spent float64
+	for _, entry := range entries {
+		spent += entry.CostUSD
+	}
+	return spent, nil
+}
+
+func (l *Ledger) dayFile(day string) string {
+	return filepath.Join(l.dir, day+".jsonl")
+}
+
+// appendEntry writes one JSON line; callers hold l.mu so lines never interleave
+func (l *Ledger) appendEntry(day string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage entry: %w", err)
+	}
+
+	file, err := os.OpenFile(l.dayFile(day), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage entry: %w", err)
+	}
+	return nil
+}
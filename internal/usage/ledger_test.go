@@ -0,0 +1,192 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorka/internal/models"
+)
+
+func testPrices() PriceTable {
+	return PriceTable{
+		"paid-model":      {Prompt: 2, Completion: 10},
+		"paid-model-mini": {Prompt: 0.5, Completion: 1},
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPriceLookup(t *testing.T) {
+	prices := testPrices()
+
+	tests := []struct {
+		model  string
+		want   Price
+		priced bool
+	}{
+		{"paid-model", Price{Prompt: 2, Completion: 10}, true},
+		{"vendor/paid-model-20250101", Price{Prompt: 2, Completion: 10}, true},
+		{"vendor/paid-model-mini", Price{Prompt: 0.5, Completion: 1}, true},
+		{"qwen/qwen3-32b:free", Price{}, true},
+		{"unknown/model", Price{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := prices.Lookup(tt.model)
+		if ok != tt.priced || got != tt.want {
+			t.Errorf("%s: expected %+v (priced=%v), got %+v (priced=%v)", tt.model, tt.want, tt.priced, got, ok)
+		}
+	}
+}
+
+func TestLoadPriceTableAppliesWorkspaceOverrides(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, ".gorka"), 0755)
+	os.WriteFile(filepath.Join(workspace, PricingFile), []byte(`{"my/local-model": {"prompt": 1, "completion": 1}}`), 0644)
+
+	prices, err := LoadPriceTable(workspace)
+	if err != nil {
+		t.Fatalf("Failed to load price table: %v", err)
+	}
+	if _, ok := prices.Lookup("my/local-model"); !ok {
+		t.Error("Expected workspace price to be loaded")
+	}
+	if _, ok := prices.Lookup("anthropic/claude-sonnet-4"); !ok {
+		t.Error("Expected defaults to be kept alongside overrides")
+	}
+}
+
+func TestLoadPriceTableUsesRegistryPrices(t *testing.T) {
+	models.Register(
+		models.Capabilities{ID: "registry/listed-model", Pricing: &models.Pricing{Prompt: 4, Completion: 8}},
+		models.Capabilities{ID: "registry/pinned-model", Pricing: &models.Pricing{Prompt: 4, Completion: 8}},
+		models.Capabilities{ID: "registry/bare-model", Pricing: &models.Pricing{Prompt: 4, Completion: 8}},
+		models.Capabilities{ID: "registry/bare-model-pro", Pricing: &models.Pricing{Prompt: 4, Completion: 8}},
+	)
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, ".gorka"), 0755)
+	os.WriteFile(filepath.Join(workspace, PricingFile), []byte(`{"registry/pinned-model": {"prompt": 1, "completion": 1}, "bare-model": {"prompt": 2, "completion": 2}}`), 0644)
+
+	prices, err := LoadPriceTable(workspace)
+	if err != nil {
+		t.Fatalf("Failed to load price table: %v", err)
+	}
+	if price, _ := prices.Lookup("registry/listed-model"); price != (Price{Prompt: 4, Completion: 8}) {
+		t.Errorf("Expected the registry price, got %+v", price)
+	}
+	if price, _ := prices.Lookup("registry/pinned-model"); price != (Price{Prompt: 1, Completion: 1}) {
+		t.Errorf("Expected the workspace price to win over the registry, got %+v", price)
+	}
+	// A bare workspace price wins over the registry's vendor-prefixed entry
+	if price, _ := prices.Lookup("registry/bare-model"); price != (Price{Prompt: 2, Completion: 2}) {
+		t.Errorf("Expected the bare workspace price to win over the registry, got %+v", price)
+	}
+	if price, _ := prices.Lookup("registry/bare-model-pro"); price != (Price{Prompt: 4, Completion: 8}) {
+		t.Errorf("Expected a different model to keep its registry price, got %+v", price)
+	}
+}
+
+func TestLedgerAggregatesRun(t *testing.T) {
+	ledger, err := NewLedger(t.TempDir(), testPrices(), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create ledger: %v", err)
+	}
+
+	run := ledger.NewRun("project_orchestrator")
+	ledger.Record(run, "s1", "project_orchestrator", "paid-model", Tokens{Prompt: 1000, Completion: 100, Total: 1100})
+	ledger.Record(run, "s1", "project_orchestrator", "paid-model", Tokens{Prompt: 2000, Completion: 200, Reasoning: 50, Total: 2200})
+	ledger.Record(run, "s2", "software_engineer", "unknown/model", Tokens{Prompt: 500, Completion: 50, Total: 550})
+
+	totals := run.Totals()
+	if totals.Calls != 3 || totals.Tokens.Total != 3850 || totals.Tokens.Reasoning != 50 {
+		t.Errorf("Unexpected run totals: %+v", totals)
+	}
+	// 3000 prompt * $2/M + 300 completion * $10/M
+	if !almostEqual(totals.CostUSD, 0.009) {
+		t.Errorf("Expected cost 0.009, got %f", totals.CostUSD)
+	}
+	if totals.UnpricedCalls != 1 {
+		t.Errorf("Expected 1 unpriced call, got %d", totals.UnpricedCalls)
+	}
+
+	summary := run.Summary()
+	if summary.ByAgent["project_orchestrator"].Calls != 2 || summary.ByAgent["software_engineer"].Calls != 1 {
+		t.Errorf("Unexpected per-agent totals: %+v", summary.ByAgent)
+	}
+	if run.SessionTotals("s2").Tokens.Total != 550 {
+		t.Errorf("Unexpected session totals: %+v", run.SessionTotals("s2"))
+	}
+}
+
+func TestLedgerPersistsAndReloadsDailySpend(t *testing.T) {
+	dir := t.TempDir()
+
+	ledger, err := NewLedger(dir, testPrices(), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create ledger: %v", err)
+	}
+	entry, err := ledger.Record(nil, "s1", "agent", "paid-model", Tokens{Prompt: 1000000, Total: 1000000})
+	if err != nil {
+		t.Fatalf("Failed to record entry: %v", err)
+	}
+
+	entries, err := ledger.Entries(entry.Timestamp.Format("2006-01-02"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected 1 persisted entry, got %d (err=%v)", len(entries), err)
+	}
+
+	reopened, err := NewLedger(dir, testPrices(), 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to reopen ledger: %v", err)
+	}
+	if !almostEqual(reopened.DailySpent(), 2) {
+		t.Errorf("Expected daily spend to survive restart, got %f", reopened.DailySpent())
+	}
+}
+
+func TestLedgerBudgets(t *testing.T) {
+	ledger, err := NewLedger(t.TempDir(), testPrices(), 1, 3)
+	if err != nil {
+		t.Fatalf("Failed to create ledger: %v", err)
+	}
+
+	run := ledger.NewRun("agent")
+	if err := ledger.CheckBudget(run); err != nil {
+		t.Fatalf("Expected fresh run to be within budget, got %v", err)
+	}
+
+	ledger.Record(run, "s1", "agent", "paid-model", Tokens{Prompt: 500000, Total: 500000})
+	err = ledger.CheckBudget(run)
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Scope != "run" {
+		t.Fatalf("Expected run budget error, got %v", err)
+	}
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Error("Expected BudgetError to match ErrBudgetExceeded")
+	}
+
+	// A second run has its own cap but shares the daily total
+	other := ledger.NewRun("agent")
+	ledger.Record(other, "s2", "agent", "paid-model", Tokens{Prompt: 1000000, Total: 1000000})
+	if err := ledger.CheckBudget(ledger.NewRun("agent")); !errors.As(err, &budgetErr) || budgetErr.Scope != "daily" {
+		t.Errorf("Expected daily budget error, got %v", err)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	if RunFromContext(context.Background()) != nil {
+		t.Error("Expected no run on a bare context")
+	}
+
+	ledger, _ := NewLedger(t.TempDir(), testPrices(), 0, 0)
+	run := ledger.NewRun("agent")
+	if RunFromContext(WithRun(context.Background(), run)) != run {
+		t.Error("Expected run to round-trip through context")
+	}
+}
@@ -0,0 +1,125 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gorka/internal/models"
+)
+
+// PricingFile is the workspace-relative path of the optional price table override
+const PricingFile = ".gorka/pricing.json"
+
+// Price is the USD cost per million tokens for a model
+type Price struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// PriceTable maps model names to prices. Keys are matched against the model name with any
+// "vendor/" prefix removed, and a key also matches dated variants it is a prefix of
+// (e.g. "claude-sonnet-4" prices "anthropic/claude-sonnet-4-20250514")
+type PriceTable map[string]Price
+
+// DefaultPriceTable returns list prices for commonly used models
+func DefaultPriceTable() PriceTable {
+	return PriceTable{
+		"claude-opus-4":     {Prompt: 15, Completion: 75},
+		"claude-sonnet-4":   {Prompt: 3, Completion: 15},
+		"claude-3-7-sonnet": {Prompt: 3, Completion: 15},
+		"claude-3-5-haiku":  {Prompt: 0.8, Completion: 4},
+		"gpt-4o":            {Prompt: 2.5, Completion: 10},
+		"gpt-4o-mini":       {Prompt: 0.15, Completion: 0.6},
+		"gpt-4.1":           {Prompt: 2, Completion: 8},
+		"gpt-4.1-mini":      {Prompt: 0.4, Completion: 1.6},
+		"o3":                {Prompt: 2, Completion: 8},
+		"o4-mini":           {Prompt: 1.1, Completion: 4.4},
+		"gemini-2.5-pro":    {Prompt: 1.25, Completion: 10},
+		"gemini-2.5-flash":  {Prompt: 0.3, Completion: 2.5},
+	}
+}
+
+// LoadPriceTable returns the default prices, then the model registry's prices, with entries from
+// .gorka/pricing.json applied on top; a missing file yields the defaults and registry prices
+func LoadPriceTable(workspace string) (PriceTable, error) {
+	table := DefaultPriceTable()
+	for model, pricing := range models.Prices() {
+		table[model] = Price{Prompt: pricing.Prompt, Completion: pricing.Completion}
+	}
+
+	path := filepath.Join(workspace, PricingFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return table, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var overrides PriceTable
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for model, price := range overrides {
+		// A bare workspace price also wins over registry prices listed under a vendor prefix
+		for key := range table {
+			if _, overridden := overrides[key]; !overridden && strings.Contains(key, "/") && models.IsRelease(modelName(key), model) {
+				delete(table, key)
+			}
+		}
+		table[model] = price
+	}
+
+	return table, nil
+}
+
+// Lookup finds the price for a model; free-tier models always cost nothing
+func (t PriceTable) Lookup(model string) (Price, bool) {
+	if strings.HasSuffix(model, ":free") {
+		return Price{}, true
+	}
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	// Prefer the longest matching key so "gpt-4o-mini" is not priced as "gpt-4o"
+	var best string
+	for key := range t {
+		if keyMatches(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+// keyMatches reports whether key prices model once model's vendor prefix and tag are removed
+func keyMatches(model, key string) bool {
+	name := modelName(model)
+	return name == key || strings.HasPrefix(name, key+"-")
+}
+
+// modelName strips the vendor prefix and tag from a model
+func modelName(model string) string {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	if i := strings.Index(model, ":"); i >= 0 {
+		model = model[:i]
+	}
+	return model
+}
+
+// Cost returns the USD cost of the given token counts and whether the model was priced
+func (t PriceTable) Cost(model string, tokens Tokens) (float64, bool) {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(tokens.Prompt)*price.Prompt + float64(tokens.Completion)*price.Completion) / 1e6, true
+}
@@ -4,36 +4,105 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 // Config holds all configuration values
 type Config struct {
-	OpenRouterAPIKey  string
-	OpenAIAPIKey      string
-	Model             string
-	Workspace         string
-	MaxParallelAgents int
-	LogLevel          string
-	RequestTimeout    int
-	MaxContextSize    int
-	OpenRouterBaseURL string
-	UseOpenAI         bool
+	OpenRouterAPIKey     string
+	OpenAIAPIKey         string
+	Model                string
+	Workspace            string
+	MaxParallelAgents    int
+	LogLevel             string
+	RequestTimeout       int
+	MaxContextSize       int
+	OpenRouterBaseURL    string
+	UseOpenAI            bool
+	EnableStreaming      bool
+	Provider             string
+	AnthropicAPIKey      string
+	AnthropicBaseURL     string
+	LocalBaseURL         string
+	LocalAPIKey          string
+	FallbackModels       []string
+	MaxAttemptsPerModel  int
+	MaxParallelTools     int
+	MaxAgentIterations   int
+	MaxAgentToolCalls    int
+	MaxAgentDuration     int
+	MaxRepeatedToolCalls int
+	RunBudgetUSD         float64
+	DailyBudgetUSD       float64
+	CassetteMode         string
+	CassettePath         string
+	TokenizerDir         string
+	ReasoningEffort      string
+	CompactionThreshold  float64
+	CompactionModel      string
+	SessionSync          string
+	SessionRetention     SessionRetention
+}
+
+// SessionRetention limits the completed sessions kept on disk; zero limits are unlimited
+type SessionRetention struct {
+	MaxAgeDays int
+	MaxCount   int
+	MaxSizeMB  int
+	// Archive gzips expired sessions instead of deleting them
+	Archive bool
+	// GCInterval is the number of seconds between sweeps in the server
+	GCInterval int
 }
 
 // LoadConfig loads and validates configuration from environment variables
 func LoadConfig() (*Config, error) {
 	config := &Config{}
 
-	// Required environment variables
-	config.OpenRouterAPIKey = os.Getenv("OPENROUTER_API_KEY")
-	if config.OpenRouterAPIKey == "" {
-		return nil, errors.New("OPENROUTER_API_KEY is required")
+	// Replaying a cassette never reaches the provider, so API keys are optional then
+	config.CassetteMode = strings.ToLower(os.Getenv("SECONDBRAIN_CASSETTE_MODE"))
+	switch config.CassetteMode {
+	case "", "off":
+		config.CassetteMode = ""
+	case "record", "replay":
+	default:
+		return nil, fmt.Errorf("invalid cassette mode: %s (must be off, record, or replay)", config.CassetteMode)
+	}
+	replaying := config.CassetteMode == "replay"
+
+	// Provider selection decides which API key is required
+	config.Provider = strings.ToLower(getEnvWithDefault("SECONDBRAIN_PROVIDER", "openrouter"))
+	switch config.Provider {
+	case "openrouter":
+		config.OpenRouterAPIKey = os.Getenv("OPENROUTER_API_KEY")
+		if config.OpenRouterAPIKey == "" && !replaying {
+			return nil, errors.New("OPENROUTER_API_KEY is required")
+		}
+	case "anthropic":
+		config.AnthropicAPIKey = os.Getenv("ANTHROPIC_API_KEY")
+		if config.AnthropicAPIKey == "" && !replaying {
+			return nil, errors.New("ANTHROPIC_API_KEY is required when SECONDBRAIN_PROVIDER is anthropic")
+		}
+	case "openai":
+		config.OpenAIAPIKey = os.Getenv("OPENAI_API_KEY")
+		if config.OpenAIAPIKey == "" && !replaying {
+			return nil, errors.New("OPENAI_API_KEY is required when SECONDBRAIN_PROVIDER is openai")
+		}
+		config.UseOpenAI = true
+	case "local":
+		// Local servers usually run without authentication
+		config.LocalAPIKey = os.Getenv("SECONDBRAIN_LOCAL_API_KEY")
+		config.LocalBaseURL = getEnvWithDefault("SECONDBRAIN_LOCAL_BASE_URL", "http://localhost:11434/v1")
+	default:
+		return nil, fmt.Errorf("invalid provider: %s (must be openrouter, anthropic, openai, or local)", config.Provider)
 	}
 
+	// Required environment variables
 	config.Model = os.Getenv("SECONDBRAIN_MODEL")
-	if config.Model == "" {
+	if config.Model == "" && config.Provider != "local" {
+		// Local providers fall back to the first model reported by /v1/models
 		return nil, errors.New("SECONDBRAIN_MODEL is required")
 	}
 
@@ -72,15 +141,155 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config.OpenRouterBaseURL = getEnvWithDefault("SECONDBRAIN_OPENROUTER_BASE_URL", "https://openrouter.ai/api/v1")
+	config.AnthropicBaseURL = getEnvWithDefault("SECONDBRAIN_ANTHROPIC_BASE_URL", "https://api.anthropic.com")
+
+	for _, model := range strings.Split(os.Getenv("SECONDBRAIN_FALLBACK_MODELS"), ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			config.FallbackModels = append(config.FallbackModels, model)
+		}
+	}
+
+	attemptsStr := getEnvWithDefault("SECONDBRAIN_MAX_ATTEMPTS", "3")
+	config.MaxAttemptsPerModel, err = strconv.Atoi(attemptsStr)
+	if err != nil || config.MaxAttemptsPerModel <= 0 {
+		return nil, errors.New("SECONDBRAIN_MAX_ATTEMPTS must be a positive integer")
+	}
+
+	// 1 turns parallel tool calls off and runs every call in order
+	parallelStr := getEnvWithDefault("SECONDBRAIN_MAX_PARALLEL_TOOLS", "4")
+	config.MaxParallelTools, err = strconv.Atoi(parallelStr)
+	if err != nil || config.MaxParallelTools <= 0 {
+		return nil, errors.New("SECONDBRAIN_MAX_PARALLEL_TOOLS must be a positive integer")
+	}
+
+	// Agent loop limits; zero leaves a limit disabled
+	iterationsStr := getEnvWithDefault("SECONDBRAIN_MAX_AGENT_ITERATIONS", "25")
+	config.MaxAgentIterations, err = strconv.Atoi(iterationsStr)
+	if err != nil || config.MaxAgentIterations < 0 {
+		return nil, errors.New("SECONDBRAIN_MAX_AGENT_ITERATIONS must be a non-negative integer")
+	}
+
+	toolCallsStr := getEnvWithDefault("SECONDBRAIN_MAX_AGENT_TOOL_CALLS", "100")
+	config.MaxAgentToolCalls, err = strconv.Atoi(toolCallsStr)
+	if err != nil || config.MaxAgentToolCalls < 0 {
+		return nil, errors.New("SECONDBRAIN_MAX_AGENT_TOOL_CALLS must be a non-negative integer")
+	}
+
+	durationStr := getEnvWithDefault("SECONDBRAIN_MAX_AGENT_DURATION", "1800")
+	config.MaxAgentDuration, err = strconv.Atoi(durationStr)
+	if err != nil || config.MaxAgentDuration < 0 {
+		return nil, errors.New("SECONDBRAIN_MAX_AGENT_DURATION must be a non-negative integer")
+	}
+
+	repeatsStr := getEnvWithDefault("SECONDBRAIN_MAX_REPEATED_TOOL_CALLS", "3")
+	config.MaxRepeatedToolCalls, err = strconv.Atoi(repeatsStr)
+	if err != nil || config.MaxRepeatedToolCalls < 0 {
+		return nil, errors.New("SECONDBRAIN_MAX_REPEATED_TOOL_CALLS must be a non-negative integer")
+	}
+
+	// Budgets are in USD; zero leaves the cap disabled
+	runBudgetStr := getEnvWithDefault("SECONDBRAIN_RUN_BUDGET_USD", "0")
+	config.RunBudgetUSD, err = strconv.ParseFloat(runBudgetStr, 64)
+	if err != nil || config.RunBudgetUSD < 0 {
+		return nil, errors.New("SECONDBRAIN_RUN_BUDGET_USD must be a non-negative number")
+	}
+
+	dailyBudgetStr := getEnvWithDefault("SECONDBRAIN_DAILY_BUDGET_USD", "0")
+	config.DailyBudgetUSD, err = strconv.ParseFloat(dailyBudgetStr, 64)
+	if err != nil || config.DailyBudgetUSD < 0 {
+		return nil, errors.New("SECONDBRAIN_DAILY_BUDGET_USD must be a non-negative number")
+	}
+
+	streamingStr := getEnvWithDefault("SECONDBRAIN_STREAMING", "true")
+	config.EnableStreaming, err = strconv.ParseBool(streamingStr)
+	if err != nil {
+		return nil, errors.New("SECONDBRAIN_STREAMING must be true or false")
+	}
+
+	// Default reasoning effort for reasoning models; agent settings override it
+	config.ReasoningEffort = strings.ToLower(os.Getenv("SECONDBRAIN_REASONING_EFFORT"))
+	if config.ReasoningEffort != "" && !IsValidReasoningEffort(config.ReasoningEffort) {
+		return nil, errors.New("SECONDBRAIN_REASONING_EFFORT must be minimal, low, medium or high")
+	}
+
+	// Share of the context window at which older turns are summarized into working memory; zero
+	// leaves only the heuristic filter
+	thresholdStr := getEnvWithDefault("SECONDBRAIN_COMPACTION_THRESHOLD", "0.75")
+	config.CompactionThreshold, err = strconv.ParseFloat(thresholdStr, 64)
+	if err != nil || config.CompactionThreshold < 0 || config.CompactionThreshold >= 1 {
+		return nil, errors.New("SECONDBRAIN_COMPACTION_THRESHOLD must be a number from 0 up to 1")
+	}
+
+	// Model that writes the working memory; the agent's own model when unset
+	config.CompactionModel = os.Getenv("SECONDBRAIN_COMPACTION_MODEL")
+
+	// When session logs are flushed to disk: after every write, at most once a second, or never
+	config.SessionSync = strings.ToLower(getEnvWithDefault("SECONDBRAIN_SESSION_FSYNC", "interval"))
+	switch config.SessionSync {
+	case "always", "interval", "never":
+	default:
+		return nil, errors.New("SECONDBRAIN_SESSION_FSYNC must be always, interval or never")
+	}
+
+	config.SessionRetention, err = LoadSessionRetention()
+	if err != nil {
+		return nil, err
+	}
+
+	// Directory of .tiktoken rank files to use instead of the ones built into the binary
+	config.TokenizerDir = os.Getenv("SECONDBRAIN_TOKENIZER_DIR")
 
 	// Validate workspace directory exists and is writable
 	if err := validateWorkspaceDirectory(config.Workspace); err != nil {
 		return nil, fmt.Errorf("workspace validation failed: %w", err)
 	}
 
+	if config.CassetteMode != "" {
+		config.CassettePath = getEnvWithDefault("SECONDBRAIN_CASSETTE", filepath.Join(config.Workspace, ".gorka", "cassettes", "latest.json"))
+	}
+
 	return config, nil
 }
 
+// LoadSessionRetention reads the session retention settings from environment variables. It is
+// separate from LoadConfig so the sessions CLI can use it without an API key.
+func LoadSessionRetention() (SessionRetention, error) {
+	var retention SessionRetention
+	var err error
+
+	ageStr := getEnvWithDefault("SECONDBRAIN_SESSION_RETENTION_DAYS", "0")
+	retention.MaxAgeDays, err = strconv.Atoi(ageStr)
+	if err != nil || retention.MaxAgeDays < 0 {
+		return retention, errors.New("SECONDBRAIN_SESSION_RETENTION_DAYS must be a non-negative integer")
+	}
+
+	countStr := getEnvWithDefault("SECONDBRAIN_SESSION_RETENTION_COUNT", "0")
+	retention.MaxCount, err = strconv.Atoi(countStr)
+	if err != nil || retention.MaxCount < 0 {
+		return retention, errors.New("SECONDBRAIN_SESSION_RETENTION_COUNT must be a non-negative integer")
+	}
+
+	sizeStr := getEnvWithDefault("SECONDBRAIN_SESSION_RETENTION_MB", "0")
+	retention.MaxSizeMB, err = strconv.Atoi(sizeStr)
+	if err != nil || retention.MaxSizeMB < 0 {
+		return retention, errors.New("SECONDBRAIN_SESSION_RETENTION_MB must be a non-negative integer")
+	}
+
+	archiveStr := getEnvWithDefault("SECONDBRAIN_SESSION_ARCHIVE", "false")
+	retention.Archive, err = strconv.ParseBool(archiveStr)
+	if err != nil {
+		return retention, errors.New("SECONDBRAIN_SESSION_ARCHIVE must be true or false")
+	}
+
+	intervalStr := getEnvWithDefault("SECONDBRAIN_SESSION_GC_INTERVAL", "3600")
+	retention.GCInterval, err = strconv.Atoi(intervalStr)
+	if err != nil || retention.GCInterval <= 0 {
+		return retention, errors.New("SECONDBRAIN_SESSION_GC_INTERVAL must be a positive integer")
+	}
+
+	return retention, nil
+}
+
 // getEnvWithDefault returns environment variable value or default if not set
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -89,6 +298,15 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// IsValidReasoningEffort checks if effort is a reasoning effort models accept
+func IsValidReasoningEffort(effort string) bool {
+	switch effort {
+	case "minimal", "low", "medium", "high":
+		return true
+	}
+	return false
+}
+
 // isValidLogLevel checks if log level is valid
 func isValidLogLevel(level string) bool {
 	validLevels := []string{"debug", "info", "warn", "error"}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gorka/internal/types"
+)
+
+// LLMOverridesFile is the workspace-relative path of the per-agent LLM settings file
+const LLMOverridesFile = ".gorka/llm.json"
+
+// LLMOverrides holds workspace-level LLM settings loaded from .gorka/llm.json
+//
+// Example:
+//
+//	{
+//	  "default": {"temperature": 0.3},
+//	  "agents": {
+//	    "security_engineer": {"model": "anthropic/claude-sonnet-4", "max_tokens": 8192}
+//	  }
+//	}
+type LLMOverrides struct {
+	Default *types.LLMSettings            `json:"default,omitempty"`
+	Agents  map[string]*types.LLMSettings `json:"agents,omitempty"`
+}
+
+// LoadLLMOverrides reads the workspace override file; a missing file yields empty overrides
+func LoadLLMOverrides(workspace string) (*LLMOverrides, error) {
+	overrides := &LLMOverrides{Agents: make(map[string]*types.LLMSettings)}
+
+	path := filepath.Join(workspace, LLMOverridesFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return overrides, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if overrides.Agents == nil {
+		overrides.Agents = make(map[string]*types.LLMSettings)
+	}
+
+	for name, settings := range overrides.Agents {
+		if settings != nil && settings.ReasoningEffort != "" && !IsValidReasoningEffort(settings.ReasoningEffort) {
+			return nil, fmt.Errorf("invalid reasoning_effort %q for agent %s in %s", settings.ReasoningEffort, name, path)
+		}
+	}
+	if overrides.Default != nil && overrides.Default.ReasoningEffort != "" && !IsValidReasoningEffort(overrides.Default.ReasoningEffort) {
+		return nil, fmt.Errorf("invalid reasoning_effort %q for default in %s", overrides.Default.ReasoningEffort, path)
+	}
+
+	return overrides, nil
+}
+
+// ResolveAgentSettings combines the settings for an agent in increasing order of precedence:
+// workspace default, the matrix llm block, then the workspace entry for the agent
+func (o *LLMOverrides) ResolveAgentSettings(matrix *types.BehavioralMatrix) *types.LLMSettings {
+	var settings *types.LLMSettings
+	if o != nil {
+		settings = settings.Merge(o.Default)
+	}
+	if matrix == nil {
+		return settings.Merge(nil)
+	}
+
+	settings = settings.Merge(matrix.LLM)
+	if o != nil {
+		settings = settings.Merge(o.Agents[matrix.AgentID])
+	}
+	return settings
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorka/internal/types"
+)
+
+func TestLoadLLMOverridesMissingFile(t *testing.T) {
+	overrides, err := LoadLLMOverrides(t.TempDir())
+	if err != nil {
+		t.Fatalf("missing file should not be an error: %v", err)
+	}
+
+	settings := overrides.ResolveAgentSettings(&types.BehavioralMatrix{AgentID: "software_engineer"})
+	if settings.Model != "" || settings.Temperature != nil {
+		t.Errorf("expected empty settings, got %+v", settings)
+	}
+}
+
+func TestResolveAgentSettingsPrecedence(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, ".gorka"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{
+		"default": {"temperature": 0.2, "max_tokens": 1000},
+		"agents": {
+			"security_engineer": {"model": "strong/model", "reasoning_effort": "high"}
+		}
+	}`
+	if err := os.WriteFile(filepath.Join(workspace, LLMOverridesFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err := LoadLLMOverrides(workspace)
+	if err != nil {
+		t.Fatalf("failed to load overrides: %v", err)
+	}
+
+	matrixTemperature := float32(0.9)
+	matrix := &types.BehavioralMatrix{
+		AgentID: "security_engineer",
+		LLM: &types.LLMSettings{
+			Model:       "spec/model",
+			Temperature: &matrixTemperature,
+		},
+	}
+
+	settings := overrides.ResolveAgentSettings(matrix)
+	if settings.Model != "strong/model" {
+		t.Errorf("workspace agent entry should override the matrix model, got %s", settings.Model)
+	}
+	if settings.Temperature == nil || *settings.Temperature != 0.9 {
+		t.Errorf("matrix temperature should override the workspace default, got %v", settings.Temperature)
+	}
+	if settings.MaxTokens != 1000 {
+		t.Errorf("workspace default max tokens should apply, got %d", settings.MaxTokens)
+	}
+	if settings.ReasoningEffort != "high" {
+		t.Errorf("expected reasoning effort from agent entry, got %s", settings.ReasoningEffort)
+	}
+
+	other := overrides.ResolveAgentSettings(&types.BehavioralMatrix{AgentID: "prompt_writer"})
+	if other.Model != "" || other.Temperature == nil || *other.Temperature != 0.2 {
+		t.Errorf("unexpected settings for agent without overrides: %+v", other)
+	}
+}
+
+func TestLoadLLMOverridesRejectsUnknownReasoningEffort(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, ".gorka"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"agents": {"software_engineer": {"reasoning_effort": "extreme"}}}`
+	if err := os.WriteFile(filepath.Join(workspace, LLMOverridesFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadLLMOverrides(workspace); err == nil {
+		t.Error("expected an error for an unknown reasoning effort")
+	}
+}